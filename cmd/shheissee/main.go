@@ -5,11 +5,16 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/boboTheFoff/shheissee-go/internal/config"
 	"github.com/boboTheFoff/shheissee-go/internal/detector"
+	"github.com/boboTheFoff/shheissee-go/internal/dot11"
 	"github.com/boboTheFoff/shheissee-go/internal/logging"
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/oui"
+	"github.com/boboTheFoff/shheissee-go/internal/scanners"
+	"github.com/boboTheFoff/shheissee-go/internal/store"
 	"github.com/boboTheFoff/shheissee-go/internal/web"
 )
 
@@ -48,7 +53,9 @@ func main() {
 
 	// Initialize web server
 	webServer := web.NewWebServer(cfg.WebServerPort, "web", startupLogger)
+	webServer.SetHost(cfg.WebServerHost)
 	webServer.SetDetector(attackDetector)
+	attackDetector.StartBlockExpiry()
 
 	// Start web server in background
 	go func() {
@@ -57,17 +64,6 @@ func main() {
 		}
 	}()
 
-	// Start web interface update goroutine
-	go func() {
-		for {
-			attackCount := attackDetector.GetAttackCount()
-			if attackCount > 0 {
-				recentAttacks := attackDetector.GetRecentAttacks(50)
-				webServer.UpdateAttacks(recentAttacks)
-			}
-		}
-	}()
-
 	// Display welcome message
 	fmt.Printf("%sGo-Shheissee Security Monitor initialized successfully!%s\n", models.ColorGreen, models.ColorReset)
 	fmt.Printf("%sWeb interface available at: http://localhost:%d%s\n", models.ColorBlue, cfg.WebServerPort, models.ColorReset)
@@ -81,7 +77,7 @@ func handleCommand(args []string) {
 
 	switch command {
 	case "monitor", "start":
-		runMonitoring()
+		runMonitoring(args[1:])
 	case "scan":
 		runQuickScan()
 	case "bluetooth":
@@ -92,7 +88,7 @@ func handleCommand(args []string) {
 		runWebServer()
 	case "block":
 		if len(args) < 3 {
-			fmt.Printf("%sUsage: go-shheissee block <ip|mac|bt> <address> [reason]%s\n", models.ColorRed, models.ColorReset)
+			fmt.Printf("%sUsage: go-shheissee block <ip|mac|bt> <address> [reason] [--ttl <duration>] [--override]%s\n", models.ColorRed, models.ColorReset)
 			os.Exit(1)
 		}
 		runBlock(args[1:])
@@ -116,6 +112,34 @@ func handleCommand(args []string) {
 		runSetAutoBlock(args[1:])
 	case "blocked":
 		runShowBlocked()
+	case "simulated":
+		runShowSimulated(args[1:])
+	case "nft-check":
+		runNftablesCheck()
+	case "devices":
+		runShowDevices(args[1:])
+	case "oui-update":
+		runOUIUpdate(args[1:])
+	case "wifi-pcap":
+		if len(args) != 2 {
+			fmt.Printf("%sUsage: go-shheissee wifi-pcap <file.pcap>%s\n", models.ColorRed, models.ColorReset)
+			os.Exit(1)
+		}
+		runWiFiPcap(args[1])
+	case "trusted-aps":
+		runShowTrustedAPs()
+	case "trust-ap":
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Printf("%sUsage: go-shheissee trust-ap <ssid|bssid> [--oui]%s\n", models.ColorRed, models.ColorReset)
+			os.Exit(1)
+		}
+		runTrustAP(args[1:])
+	case "untrust-ap":
+		if len(args) != 2 {
+			fmt.Printf("%sUsage: go-shheissee untrust-ap <ssid|bssid>%s\n", models.ColorRed, models.ColorReset)
+			os.Exit(1)
+		}
+		runUntrustAP(args[1])
 	case "help", "-h", "--help":
 		showHelp()
 	default:
@@ -125,10 +149,16 @@ func handleCommand(args []string) {
 	}
 }
 
-func runMonitoring() {
+func runMonitoring(args []string) {
 	cfg := models.DefaultConfig()
 	config.EnsureDirectories(cfg)
 
+	for _, arg := range args {
+		if arg == "--dry-run" {
+			cfg.BlockerDryRun = true
+		}
+	}
+
 	attackDetector, err := detector.NewAttackDetector(cfg)
 	if err != nil {
 		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
@@ -139,12 +169,17 @@ func runMonitoring() {
 	// Initialize web server
 	logger, _ := logging.NewLogger(cfg.LogFile)
 	webServer := web.NewWebServer(cfg.WebServerPort, "web", logger)
+	webServer.SetHost(cfg.WebServerHost)
+	webServer.SetDetector(attackDetector)
 
 	go func() {
 		webServer.Start()
 	}()
 
 	fmt.Printf("%sStarting continuous security monitoring...%s\n", models.ColorGreen, models.ColorReset)
+	if cfg.BlockerDryRun {
+		fmt.Printf("%sDry-run: blocking actions are simulated, see 'shheissee simulated'%s\n", models.ColorYellow, models.ColorReset)
+	}
 	fmt.Printf("%sWeb interface: http://localhost:%d%s\n", models.ColorBlue, cfg.WebServerPort, models.ColorReset)
 
 	attackDetector.StartMonitoring()
@@ -215,8 +250,18 @@ func runWebServer() {
 	cfg := models.DefaultConfig()
 	config.EnsureDirectories(cfg)
 
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
 	logger, _ := logging.NewLogger(cfg.LogFile)
 	webServer := web.NewWebServer(cfg.WebServerPort, "web", logger)
+	webServer.SetHost(cfg.WebServerHost)
+	webServer.SetDetector(attackDetector)
+	attackDetector.StartBlockExpiry()
 
 	fmt.Printf("%sStarting web server on port %d...%s\n", models.ColorGreen, cfg.WebServerPort, models.ColorReset)
 	fmt.Printf("%sWeb interface: http://localhost:%d%s\n", models.ColorBlue, cfg.WebServerPort, models.ColorReset)
@@ -249,8 +294,8 @@ func runMainMenu(ad *detector.AttackDetector, consoleLogger *logging.ConsoleLogg
 			} else {
 				fmt.Printf("\n\033[32mFound %d Bluetooth device(s):\033[0m\n", len(devices))
 				if len(devices) > 0 {
-					fmt.Println("\033[1mMAC Address         Device Name                    RSSI   Status\033[0m")
-					fmt.Println("-" + strings.Repeat("-", 69))
+					fmt.Println("\033[1mMAC Address         Device Name                    Vendor                   RSSI   Status\033[0m")
+					fmt.Println("-" + strings.Repeat("-", 94))
 					for _, device := range devices {
 						rssi := "N/A"
 						if device.RSSI != 0 {
@@ -260,8 +305,8 @@ func runMainMenu(ad *detector.AttackDetector, consoleLogger *logging.ConsoleLogg
 						if status == "" {
 							status = "Unknown"
 						}
-						fmt.Printf("%-18s %-30s %-6s %-10s\n",
-							device.Address, device.Name, rssi, status)
+						fmt.Printf("%-18s %-30s %-24.24s %-6s %-10s\n",
+							device.Address, device.Name, oui.Label(device.Vendor, device.LocallyAdministered), rssi, status)
 					}
 				}
 				fmt.Println()
@@ -296,15 +341,19 @@ func runMainMenu(ad *detector.AttackDetector, consoleLogger *logging.ConsoleLogg
 			} else {
 				fmt.Printf("\n\033[32mFound %d WiFi device(s)/network(s):\033[0m\n", len(devices))
 				if len(devices) > 0 {
-					fmt.Println("\033[1mMAC Address         SSID                           Signal Ch Status\033[0m")
-					fmt.Println("-" + strings.Repeat("-", 83))
+					fmt.Println("\033[1mMAC Address         SSID                           Vendor                   Signal Ch Security                     Status\033[0m")
+					fmt.Println("-" + strings.Repeat("-", 138))
 					for _, device := range devices {
 						status := device.Status
 						if status == "" {
 							status = "Unknown"
 						}
-						fmt.Printf("%-18s %-30s %-6s %-2s %-10s\n",
-							device.Address, device.SSID, device.Signal, device.Channel, status)
+						security := "Unknown"
+						if device.Security != nil {
+							security = device.Security.String()
+						}
+						fmt.Printf("%-18s %-30s %-24.24s %-6s %-2s %-28.28s %-10s\n",
+							device.Address, device.SSID, oui.Label(device.Vendor, device.LocallyAdministered), device.Signal, device.Channel, security, status)
 					}
 				}
 				fmt.Println()
@@ -329,6 +378,12 @@ func waitForEnter() {
 }
 
 func runBlock(args []string) {
+	args, opts, err := parseBlockFlags(args)
+	if err != nil {
+		fmt.Printf("%s%v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
 	blockType := strings.ToLower(args[0])
 	address := args[1]
 	reason := "Manual block via command line"
@@ -348,11 +403,11 @@ func runBlock(args []string) {
 
 	switch blockType {
 	case "ip":
-		err = attackDetector.BlockIP(address, reason)
+		err = attackDetector.BlockIP(address, reason, opts)
 	case "mac":
-		err = attackDetector.BlockMAC(address, reason)
+		err = attackDetector.BlockMAC(address, reason, opts)
 	case "bt":
-		err = attackDetector.BlockBluetoothDevice(address, reason)
+		err = attackDetector.BlockBluetoothDevice(address, reason, opts)
 	default:
 		fmt.Printf("%sInvalid block type. Use: ip, mac, or bt%s\n", models.ColorRed, models.ColorReset)
 		os.Exit(1)
@@ -363,9 +418,53 @@ func runBlock(args []string) {
 		os.Exit(1)
 	}
 
+	if opts.TTL > 0 {
+		fmt.Printf("%s✅ Successfully blocked %s %s for %s%s\n", models.ColorGreen, blockType, address, opts.TTL, models.ColorReset)
+		return
+	}
 	fmt.Printf("%s✅ Successfully blocked %s %s%s\n", models.ColorGreen, blockType, address, models.ColorReset)
 }
 
+// parseBlockFlags removes the "--ttl <duration>" (or "--ttl=<duration>") and
+// "--override" flags from args
+func parseBlockFlags(args []string) ([]string, detector.BlockOptions, error) {
+	var rest []string
+	var opts detector.BlockOptions
+
+	for i := 0; i < len(args); i++ {
+		value, isFlag := "", false
+		switch {
+		case args[i] == "--override":
+			opts.Override = true
+			continue
+		case args[i] == "--ttl":
+			if i+1 >= len(args) {
+				return nil, opts, fmt.Errorf("--ttl requires a duration such as 30m or 24h")
+			}
+			value, isFlag = args[i+1], true
+			i++
+		case strings.HasPrefix(args[i], "--ttl="):
+			value, isFlag = strings.TrimPrefix(args[i], "--ttl="), true
+		}
+
+		if !isFlag {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, opts, fmt.Errorf("invalid --ttl %q: use a duration such as 30m or 24h", value)
+		}
+		opts.TTL = parsed
+	}
+
+	if len(rest) < 2 {
+		return nil, opts, fmt.Errorf("Usage: go-shheissee block <ip|mac|bt> <address> [reason] [--ttl <duration>] [--override]")
+	}
+	return rest, opts, nil
+}
+
 func runUnblock(args []string) {
 	blockType := strings.ToLower(args[0])
 	address := args[1]
@@ -464,27 +563,383 @@ func runShowBlocked() {
 
 	fmt.Printf("%sCurrently Blocked Items:%s\n", models.ColorBlue, models.ColorReset)
 	fmt.Printf("IPs: %d\n", len(blocked.BlockedIPs))
-	for ip, timestamp := range blocked.BlockedIPs {
-		fmt.Printf("  %s (blocked at %s)\n", ip, timestamp.Format("2006-01-02 15:04:05"))
+	for ip, record := range blocked.BlockedIPs {
+		printBlockRecord(ip, record)
 	}
 
 	fmt.Printf("MACs: %d\n", len(blocked.BlockedMACs))
-	for mac, timestamp := range blocked.BlockedMACs {
-		fmt.Printf("  %s (blocked at %s)\n", mac, timestamp.Format("2006-01-02 15:04:05"))
+	for mac, record := range blocked.BlockedMACs {
+		printBlockRecord(mac, record)
 	}
 
 	fmt.Printf("Bluetooth: %d\n", len(blocked.BlockedBTAddrs))
-	for bt, timestamp := range blocked.BlockedBTAddrs {
-		fmt.Printf("  %s (blocked at %s)\n", bt, timestamp.Format("2006-01-02 15:04:05"))
+	for bt, record := range blocked.BlockedBTAddrs {
+		printBlockRecord(bt, record)
+	}
+
+	drift, err := attackDetector.ReconcileBlocks()
+	if err != nil {
+		fmt.Printf("%sCould not compare with installed firewall rules: %v%s\n", models.ColorYellow, err, models.ColorReset)
+		return
+	}
+	if len(drift) > 0 {
+		fmt.Printf("%sDrift from installed firewall rules:%s\n", models.ColorYellow, models.ColorReset)
+		for _, d := range drift {
+			fmt.Printf("  %s %s: %s\n", d.Kind, d.Target, d.Issue)
+		}
 	}
 }
 
+func runShowSimulated(args []string) {
+	limit := 20
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 0 {
+			fmt.Printf("%sUsage: go-shheissee simulated [limit]%s\n", models.ColorRed, models.ColorReset)
+			os.Exit(1)
+		}
+		limit = parsed
+	}
+
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	actions, err := attackDetector.GetSimulatedActions(limit)
+	if err != nil {
+		fmt.Printf("%sError reading simulated actions: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%sSimulated Actions: %d%s\n", models.ColorBlue, len(actions), models.ColorReset)
+	for _, action := range actions {
+		fmt.Printf("  [%s] %s %s\n", action.Timestamp.Format("2006-01-02 15:04:05"), action.Action, action.Target)
+		if action.Attack != nil {
+			fmt.Printf("    triggered by %s %s: %s\n", action.Attack.Severity, action.Attack.Type, action.Attack.Description)
+		}
+		for _, command := range action.Commands {
+			fmt.Printf("    $ %s\n", command)
+		}
+	}
+}
+
+func runNftablesCheck() {
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	ruleset, err := attackDetector.CheckNftablesRuleset()
+	fmt.Print(ruleset)
+	if err != nil {
+		fmt.Printf("%sError checking nftables ruleset: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s✅ nftables accepted the ruleset (%s); nothing was applied%s\n", models.ColorGreen, cfg.NftablesRulesetFile, models.ColorReset)
+}
+
+func runShowDevices(args []string) {
+	if len(args) > 1 {
+		fmt.Printf("%sUsage: go-shheissee devices [address|name]%s\n", models.ColorRed, models.ColorReset)
+		os.Exit(1)
+	}
+
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	var query store.DeviceQuery
+	if len(args) == 1 {
+		query.Match = args[0]
+	}
+	devices, err := attackDetector.QueryDevices(query)
+	if err != nil {
+		fmt.Printf("%sError reading device inventory: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	// Without a filter list everything; with one, show each device's history
+	if query.Match == "" {
+		fmt.Printf("%sKnown Devices: %d%s\n", models.ColorBlue, len(devices), models.ColorReset)
+		fmt.Printf("%-10s %-18s %-16s %-24s %-8s %-20s %-20s\n", "KIND", "ADDRESS", "IP", "NAME", "STATUS", "FIRST SEEN", "LAST SEEN")
+		for _, device := range devices {
+			fmt.Printf("%-10s %-18s %-16s %-24s %-8s %-20s %-20s\n",
+				device.Kind, deviceAddress(device), device.IP, deviceName(device), device.Status,
+				device.FirstSeen.Format("2006-01-02 15:04:05"), device.LastSeen.Format("2006-01-02 15:04:05"))
+		}
+		return
+	}
+
+	if len(devices) == 0 {
+		fmt.Printf("%sNo device matches %s%s\n", models.ColorYellow, query.Match, models.ColorReset)
+		return
+	}
+	for _, device := range devices {
+		printDevice(device)
+	}
+}
+
+func runOUIUpdate(args []string) {
+	if len(args) > 1 {
+		fmt.Printf("%sUsage: go-shheissee oui-update [file|url]%s\n", models.ColorRed, models.ColorReset)
+		os.Exit(1)
+	}
+
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	source := oui.DefaultSource
+	if len(args) == 1 {
+		source = args[0]
+	}
+
+	fmt.Printf("%sFetching vendor registry from %s...%s\n", models.ColorBlue, source, models.ColorReset)
+	data, err := oui.Fetch(source)
+	if err != nil {
+		fmt.Printf("%sError fetching vendor registry: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	count, err := oui.Install(cfg.OUIFile, data)
+	if err != nil {
+		fmt.Printf("%sError installing vendor registry: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s✅ Installed %d vendor assignments to %s%s\n", models.ColorGreen, count, cfg.OUIFile, models.ColorReset)
+}
+
+func runWiFiPcap(path string) {
+	cfg := models.DefaultConfig()
+
+	frames, err := dot11.ReadPcapFile(path)
+	if err != nil {
+		fmt.Printf("%sError reading capture: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	counts := make(map[dot11.Subtype]int)
+	for _, frame := range frames {
+		counts[frame.Subtype]++
+	}
+	fmt.Printf("%sRead %d management frames from %s%s\n", models.ColorBlue, len(frames), path, models.ColorReset)
+	for _, subtype := range []dot11.Subtype{dot11.Beacon, dot11.ProbeRequest, dot11.ProbeResponse, dot11.Deauthentication, dot11.Disassociation} {
+		fmt.Printf("  %-18s %d\n", subtype.String()+":", counts[subtype])
+	}
+
+	monitor := scanners.NewDeauthMonitor(cfg.DeauthWindow, cfg.DeauthMaxPerBSSID, cfg.DeauthMaxPerClient)
+	attacks := monitor.Observe(frames)
+	if len(attacks) == 0 {
+		fmt.Printf("%s✅ No deauthentication floods found%s\n", models.ColorGreen, models.ColorReset)
+		return
+	}
+	for _, attack := range attacks {
+		fmt.Printf("%s[%s] %s: %s%s\n", models.ColorRed, attack.Timestamp.Format("2006-01-02 15:04:05"), attack.Type, attack.Description, models.ColorReset)
+	}
+}
+
+func runShowTrustedAPs() {
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	networks, err := attackDetector.TrustedNetworks()
+	if err != nil {
+		fmt.Printf("%sError reading trusted access points: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%sTrusted Networks: %d%s\n", models.ColorBlue, len(networks), models.ColorReset)
+	for _, network := range networks {
+		printTrustedNetwork(network)
+	}
+}
+
+func runTrustAP(args []string) {
+	match := args[0]
+	includeOUI := false
+	if len(args) == 2 {
+		if args[1] != "--oui" {
+			fmt.Printf("%sUsage: go-shheissee trust-ap <ssid|bssid> [--oui]%s\n", models.ColorRed, models.ColorReset)
+			os.Exit(1)
+		}
+		includeOUI = true
+	}
+
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	fmt.Printf("%sScanning for access points of %s...%s\n", models.ColorBlue, match, models.ColorReset)
+	networks, err := attackDetector.TrustAccessPoints(match, includeOUI)
+	if err != nil {
+		fmt.Printf("%sError trusting %s: %v%s\n", models.ColorRed, match, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s✅ Trusted access points of %s%s\n", models.ColorGreen, match, models.ColorReset)
+	for _, network := range networks {
+		printTrustedNetwork(network)
+	}
+}
+
+func runUntrustAP(match string) {
+	cfg := models.DefaultConfig()
+	config.EnsureDirectories(cfg)
+
+	attackDetector, err := detector.NewAttackDetector(cfg)
+	if err != nil {
+		fmt.Printf("%sError initializing detector: %v%s\n", models.ColorRed, err, models.ColorReset)
+		os.Exit(1)
+	}
+	defer attackDetector.Close()
+
+	if err := attackDetector.UntrustAccessPoint(match); err != nil {
+		fmt.Printf("%sError untrusting %s: %v%s\n", models.ColorRed, match, err, models.ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s✅ %s is no longer trusted%s\n", models.ColorGreen, match, models.ColorReset)
+}
+
+// printTrustedNetwork prints a trusted network with its approved access points
+func printTrustedNetwork(network models.TrustedNetwork) {
+	security := "any"
+	if network.Security != nil {
+		security = network.Security.String()
+	}
+	fmt.Printf("\n%s%s%s (trusted %s)\n", models.ColorBold, network.SSID, models.ColorReset, network.TrustedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Security: %s\n", security)
+	if len(network.Channels) > 0 {
+		fmt.Printf("  Channels: %s\n", strings.Join(network.Channels, ", "))
+	}
+	if len(network.OUIs) > 0 {
+		fmt.Printf("  Vendor prefixes: %s\n", strings.Join(network.OUIs, ", "))
+	}
+	for _, bssid := range network.BSSIDs {
+		if signal, ok := network.Signals[bssid]; ok {
+			fmt.Printf("  %s  %d dBm\n", bssid, signal)
+		} else {
+			fmt.Printf("  %s\n", bssid)
+		}
+	}
+}
+
+// deviceAddress returns the MAC, Bluetooth address or BSSID of an inventory
+// device, or its IP address if none is known
+func deviceAddress(device models.InventoryDevice) string {
+	if device.MAC != "" {
+		return device.MAC
+	}
+	return device.IP
+}
+
+func deviceName(device models.InventoryDevice) string {
+	for _, name := range []string{device.Hostname, device.BTName, device.SSID, device.Vendor} {
+		if name != "" {
+			return name
+		}
+	}
+	return "-"
+}
+
+func printDevice(device models.InventoryDevice) {
+	fmt.Printf("%s%s (%s)%s\n", models.ColorBlue, device.Key, device.Status, models.ColorReset)
+	for _, field := range []struct{ name, value string }{
+		{"IP", device.IP},
+		{"MAC", device.MAC},
+		{"Hostname", device.Hostname},
+		{"Vendor", device.Vendor},
+		{"Bluetooth name", device.BTName},
+		{"SSID", device.SSID},
+	} {
+		if field.value != "" {
+			fmt.Printf("  %-15s %s\n", field.name+":", field.value)
+		}
+	}
+	if device.LocallyAdministered {
+		fmt.Printf("  %-15s %s\n", "Address:", "locally administered (randomized)")
+	}
+	fmt.Printf("  %-15s %s\n", "First seen:", device.FirstSeen.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  %-15s %s\n", "Last seen:", device.LastSeen.Format("2006-01-02 15:04:05"))
+
+	if len(device.Ports) > 0 {
+		fmt.Println("  Ports:")
+		for _, port := range device.Ports {
+			state := "closed"
+			if port.Open {
+				state = "open"
+			}
+			service := port.Service
+			if port.Version != "" {
+				service += " (" + port.Version + ")"
+			}
+			fmt.Printf("    %d/%s %-6s %s, first %s, last %s\n", port.Number, port.Protocol, state, service,
+				port.FirstSeen.Format("2006-01-02 15:04:05"), port.LastSeen.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	if len(device.Changes) > 0 {
+		fmt.Println("  Changes:")
+		for _, change := range device.Changes {
+			from := change.From
+			if from == "" {
+				from = "-"
+			}
+			to := change.To
+			if to == "" {
+				to = "-"
+			}
+			fmt.Printf("    [%s] %s: %s -> %s\n", change.Time.Format("2006-01-02 15:04:05"), change.Field, from, to)
+		}
+	}
+	fmt.Println()
+}
+
+func printBlockRecord(target string, record models.BlockRecord) {
+	expiry := "permanent"
+	if record.ExpiresAt != nil {
+		expiry = "expires " + record.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	fmt.Printf("  %s (%s, blocked at %s, %s): %s\n", target, record.Origin, record.BlockedAt.Format("2006-01-02 15:04:05"), expiry, record.Reason)
+}
+
 func showHelp() {
 	fmt.Println("Go-Shheissee Security Monitor")
 	fmt.Println("Usage: go-shheissee [command]")
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  monitor, start    Start continuous security monitoring")
+	fmt.Println("    --dry-run       Simulate blocking actions instead of running them")
 	fmt.Println("  scan              Perform quick security scan")
 	fmt.Println("  bluetooth         Start Bluetooth device monitor")
 	fmt.Println("  demo              Set up demo attack scenario")
@@ -492,10 +947,27 @@ func showHelp() {
 	fmt.Println()
 	fmt.Println("Blocking Commands:")
 	fmt.Println("  block <ip|mac|bt> <address> [reason]    Block IP, MAC, or Bluetooth device")
+	fmt.Println("        [--ttl <duration>]                Remove the block after e.g. 30m or 24h")
+	fmt.Println("        [--override]                      Block even if the target is protected")
 	fmt.Println("  unblock <ip|mac|bt> <address>           Unblock IP, MAC, or Bluetooth device")
 	fmt.Println("  deauth <client_mac> <ap_mac> [reason]   Deauthenticate WiFi client")
 	fmt.Println("  autoblock <on|off>                      Enable/disable automatic blocking")
 	fmt.Println("  blocked                                 Show currently blocked items")
+	fmt.Println("  nft-check                               Print and validate the nftables ruleset")
+	fmt.Println("  simulated [limit]                       Show actions simulated in dry-run mode")
+	fmt.Println()
+	fmt.Println("Inventory Commands:")
+	fmt.Println("  devices                                 List every device seen so far")
+	fmt.Println("  devices <address|name>                  Show when a device appeared and what changed")
+	fmt.Println("  oui-update [file|url]                   Install the IEEE vendor registry (default: download it)")
+	fmt.Println()
+	fmt.Println("Analysis Commands:")
+	fmt.Println("  wifi-pcap <file.pcap>                   Check an 802.11 capture for deauthentication floods")
+	fmt.Println()
+	fmt.Println("Evil Twin Commands:")
+	fmt.Println("  trusted-aps                             List the trusted WiFi networks and access points")
+	fmt.Println("  trust-ap <ssid|bssid> [--oui]           Trust the access points seen now (--oui: and their vendor prefixes)")
+	fmt.Println("  untrust-ap <ssid|bssid>                 Remove a trusted network or access point")
 	fmt.Println()
 	fmt.Println("Other Commands:")
 	fmt.Println("  help, -h, --help  Show this help message")
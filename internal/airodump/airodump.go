@@ -0,0 +1,246 @@
+// Package airodump reads the CSV files airodump-ng writes with
+// --output-format csv. A file holds two sections: the access points, then
+// the client stations, each introduced by its own header row.
+package airodump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayout is the format of the first and last time seen columns
+const timeLayout = "2006-01-02 15:04:05"
+
+// notAssociated is the BSSID column of a station that is only probing
+const notAssociated = "(not associated)"
+
+// Column counts of the two sections. An AP's ESSID and a station's probed
+// ESSIDs may contain commas, so these are minimums.
+const (
+	apColumns      = 15 // BSSID ... ESSID, Key
+	stationColumns = 7  // Station MAC ... Probed ESSIDs
+)
+
+// AccessPoint is a row of the access point section
+type AccessPoint struct {
+	BSSID     string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Channel   int
+	Speed     int    // maximum rate in Mbit/s
+	Privacy   string // e.g. "WPA2", "WPA3 WPA2", "WEP" or "OPN"
+	Cipher    string // e.g. "CCMP", "CCMP TKIP" or "WEP40"
+	Auth      string // e.g. "PSK", "SAE", "MGT"
+	Power     int    // dBm; -1 when airodump could not measure it
+	Beacons   int
+	IVs       int
+	LANIP     string // address learned from the AP's traffic, if any
+	ESSID     string // empty for a hidden network
+}
+
+// Station is a row of the client station section
+type Station struct {
+	MAC       string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Power     int // dBm; -1 when airodump could not measure it
+	Packets   int
+	BSSID     string // empty while the station is not associated
+	Probes    []string
+}
+
+// Capture is the content of one airodump-ng CSV file
+type Capture struct {
+	AccessPoints []AccessPoint
+	Stations     []Station
+}
+
+// Clients returns the stations associated with an access point
+func (c *Capture) Clients(bssid string) []Station {
+	var clients []Station
+	for _, station := range c.Stations {
+		if station.BSSID != "" && strings.EqualFold(station.BSSID, bssid) {
+			clients = append(clients, station)
+		}
+	}
+	return clients
+}
+
+// ParseFile reads the CSV file at path
+func ParseFile(path string) (*Capture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	capture, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return capture, nil
+}
+
+// Parse reads an airodump-ng CSV file. Rows before the first header are an
+// error; blank lines separate the sections and are skipped.
+func Parse(r io.Reader) (*Capture, error) {
+	capture := &Capture{}
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		switch strings.TrimSpace(fields[0]) {
+		case "BSSID":
+			section = "ap"
+			continue
+		case "Station MAC":
+			section = "station"
+			continue
+		}
+
+		switch section {
+		case "ap":
+			ap, err := parseAccessPoint(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			capture.AccessPoints = append(capture.AccessPoints, ap)
+		case "station":
+			station, err := parseStation(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			capture.Stations = append(capture.Stations, station)
+		default:
+			return nil, fmt.Errorf("line %d: row outside of a section", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return capture, nil
+}
+
+func parseAccessPoint(fields []string) (AccessPoint, error) {
+	if len(fields) < apColumns {
+		return AccessPoint{}, fmt.Errorf("access point row has %d columns, want %d", len(fields), apColumns)
+	}
+
+	// The ESSID sits between the ID length and the key and may itself
+	// contain commas or spaces. The ID length says how long it really is, so
+	// a hidden network's "\x00" padding or blank name yields "".
+	essid := strings.TrimPrefix(strings.Join(fields[13:len(fields)-1], ","), " ")
+	if length, err := strconv.Atoi(strings.TrimSpace(fields[12])); err == nil && length >= 0 && length <= len(essid) {
+		essid = essid[:length]
+	}
+	if strings.Trim(essid, "\x00 ") == "" {
+		essid = ""
+	}
+
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	ap := AccessPoint{
+		BSSID:   strings.ToUpper(fields[0]),
+		Privacy: fields[5],
+		Cipher:  fields[6],
+		Auth:    fields[7],
+		ESSID:   essid,
+	}
+	// airodump pads the octets of the LAN IP and writes 0.0.0.0 for none
+	if ip := strings.ReplaceAll(fields[11], " ", ""); ip != "0.0.0.0" {
+		ap.LANIP = ip
+	}
+
+	var err error
+	if ap.FirstSeen, err = parseTime(fields[1]); err != nil {
+		return ap, err
+	}
+	if ap.LastSeen, err = parseTime(fields[2]); err != nil {
+		return ap, err
+	}
+	numbers := []struct {
+		name  string
+		value string
+		dest  *int
+	}{
+		{"channel", fields[3], &ap.Channel},
+		{"speed", strings.TrimRight(fields[4], "e."), &ap.Speed}, // "54e" marks QoS
+		{"power", fields[8], &ap.Power},
+		{"beacons", fields[9], &ap.Beacons},
+		{"IVs", fields[10], &ap.IVs},
+	}
+	for _, number := range numbers {
+		if *number.dest, err = parseNumber(number.value); err != nil {
+			return ap, fmt.Errorf("invalid %s %q", number.name, number.value)
+		}
+	}
+
+	return ap, nil
+}
+
+func parseStation(fields []string) (Station, error) {
+	if len(fields) < stationColumns {
+		return Station{}, fmt.Errorf("station row has %d columns, want %d", len(fields), stationColumns)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	station := Station{MAC: strings.ToUpper(fields[0])}
+	if fields[5] != notAssociated {
+		station.BSSID = strings.ToUpper(fields[5])
+	}
+
+	var err error
+	if station.FirstSeen, err = parseTime(fields[1]); err != nil {
+		return station, err
+	}
+	if station.LastSeen, err = parseTime(fields[2]); err != nil {
+		return station, err
+	}
+	if station.Power, err = parseNumber(fields[3]); err != nil {
+		return station, fmt.Errorf("invalid power %q", fields[3])
+	}
+	if station.Packets, err = parseNumber(fields[4]); err != nil {
+		return station, fmt.Errorf("invalid packet count %q", fields[4])
+	}
+
+	for _, probe := range fields[6:] {
+		if probe != "" {
+			station.Probes = append(station.Probes, probe)
+		}
+	}
+
+	return station, nil
+}
+
+// parseTime reads a timestamp written in the capturing host's time zone
+func parseTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation(timeLayout, value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q", value)
+	}
+	return t, nil
+}
+
+// parseNumber reads a numeric column; airodump leaves some of them blank
+func parseNumber(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
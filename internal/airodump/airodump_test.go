@@ -0,0 +1,58 @@
+package airodump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFile(t *testing.T) {
+	capture, err := ParseFile("testdata/airodump.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(second int) time.Time {
+		return time.Date(2024, 5, 1, 12, 0, second, 0, time.Local)
+	}
+	wantAPs := []AccessPoint{
+		{BSSID: "3C:84:6A:12:34:56", FirstSeen: at(1), LastSeen: at(9), Channel: 6, Speed: 130, Privacy: "WPA2", Cipher: "CCMP", Auth: "PSK", Power: -48, Beacons: 42, IVs: 10, ESSID: "SmithHome"},
+		{BSSID: "F4:F2:6D:44:55:66", FirstSeen: at(2), LastSeen: at(9), Channel: 36, Speed: 866, Privacy: "WPA3 WPA2", Cipher: "CCMP", Auth: "SAE PSK", Power: -71, Beacons: 17, ESSID: "Cafe, Bar"},
+		{BSSID: "00:14:6C:AB:CD:EF", FirstSeen: at(3), LastSeen: at(8), Channel: 11, Speed: 54, Privacy: "WEP", Cipher: "WEP", Power: -80, Beacons: 5, IVs: 312, LANIP: "192.168.0.1", ESSID: "Legacy"},
+		{BSSID: "9A:DE:D0:11:22:33", FirstSeen: at(4), LastSeen: at(4), Channel: 1, Speed: -1, Privacy: "OPN", Power: -1, Beacons: 1},
+	}
+	if !reflect.DeepEqual(capture.AccessPoints, wantAPs) {
+		t.Errorf("AccessPoints = %+v\nwant %+v", capture.AccessPoints, wantAPs)
+	}
+
+	wantStations := []Station{
+		{MAC: "AA:BB:CC:00:00:01", FirstSeen: at(2), LastSeen: at(9), Power: -55, Packets: 30, BSSID: "3C:84:6A:12:34:56", Probes: []string{"SmithHome"}},
+		{MAC: "DA:A1:19:00:00:02", FirstSeen: at(5), LastSeen: at(7), Power: -70, Packets: 4, Probes: []string{"HomeNet", "Office"}},
+		{MAC: "B8:27:EB:00:00:03", FirstSeen: at(6), LastSeen: at(9), Power: -62, Packets: 12, BSSID: "3C:84:6A:12:34:56"},
+	}
+	if !reflect.DeepEqual(capture.Stations, wantStations) {
+		t.Errorf("Stations = %+v\nwant %+v", capture.Stations, wantStations)
+	}
+
+	clients := capture.Clients("3c:84:6a:12:34:56")
+	if len(clients) != 2 || clients[0].MAC != "AA:BB:CC:00:00:01" || clients[1].MAC != "B8:27:EB:00:00:03" {
+		t.Errorf("Clients() = %+v", clients)
+	}
+}
+
+func TestParseRejectsMalformedRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"row before header", "3C:84:6A:12:34:56, 2024-05-01 12:00:01\n"},
+		{"short access point row", "BSSID, First time seen\n3C:84:6A:12:34:56, 2024-05-01 12:00:01, 2024-05-01 12:00:09\n"},
+		{"bad station time", "Station MAC, First time seen, Last time seen, Power, # packets, BSSID, Probed ESSIDs\nAA:BB:CC:00:00:01, yesterday, 2024-05-01 12:00:09, -55, 30, (not associated) ,\n"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: Parse() succeeded", tt.name)
+		}
+	}
+}
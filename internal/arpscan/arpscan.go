@@ -0,0 +1,141 @@
+// Package arpscan discovers hosts on a local IPv4 subnet by sending ARP
+// requests on a raw socket, without external tools.
+package arpscan
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// MaxHosts is the largest number of addresses a sweep will probe (a /20)
+const MaxHosts = 4096
+
+// Defaults for Options
+const (
+	DefaultInterval = time.Millisecond
+	DefaultTimeout  = time.Second
+)
+
+// ErrUnsupported is returned on platforms without raw packet sockets
+var ErrUnsupported = errors.New("ARP sweep is not supported on this platform")
+
+// Ethernet and ARP constants
+const (
+	etherTypeARP = 0x0806
+	frameLength  = 42 // 14 byte Ethernet header + 28 byte ARP packet
+	opRequest    = 1
+	opReply      = 2
+)
+
+// Options controls the pace of a sweep
+type Options struct {
+	Interval time.Duration // delay between requests
+	Timeout  time.Duration // how long to wait for replies after the last request
+}
+
+func (o Options) withDefaults() Options {
+	if o.Interval <= 0 {
+		o.Interval = DefaultInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	return o
+}
+
+// Reply is a host that answered an ARP request
+type Reply struct {
+	IP           net.IP
+	MAC          net.HardwareAddr
+	ResponseTime time.Duration
+}
+
+// Hosts lists the addresses of an IPv4 network that can be probed, leaving
+// out the network and broadcast addresses of prefixes shorter than /31
+func Hosts(network *net.IPNet) ([]net.IP, error) {
+	base := network.IP.To4()
+	ones, bits := network.Mask.Size()
+	if base == nil || bits != 32 {
+		return nil, fmt.Errorf("%s is not an IPv4 network", network)
+	}
+
+	size := uint32(1) << uint(bits-ones)
+	if size > MaxHosts {
+		return nil, fmt.Errorf("%s has %d addresses, more than the %d an ARP sweep probes", network, size, MaxHosts)
+	}
+
+	first, last := uint32(0), size-1
+	if size > 2 {
+		first, last = 1, size-2
+	}
+
+	start := binary.BigEndian.Uint32(base.Mask(network.Mask))
+	hosts := make([]net.IP, 0, last-first+1)
+	for offset := first; offset <= last; offset++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, start+offset)
+		hosts = append(hosts, ip)
+	}
+	return hosts, nil
+}
+
+// Request builds a broadcast Ethernet frame asking who has target
+func Request(srcMAC net.HardwareAddr, srcIP net.IP, target net.IP) []byte {
+	frame := make([]byte, frameLength)
+
+	// Ethernet header: broadcast destination, our source, ARP payload
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+
+	// ARP packet for IPv4 over Ethernet
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // hardware type: Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // protocol type: IPv4
+	arp[4] = 6                                   // hardware address length
+	arp[5] = 4                                   // protocol address length
+	binary.BigEndian.PutUint16(arp[6:8], opRequest)
+	copy(arp[8:14], srcMAC)
+	copy(arp[14:18], srcIP.To4())
+	// target hardware address stays zero
+	copy(arp[24:28], target.To4())
+	return frame
+}
+
+// ParseReply extracts the sender of an ARP reply frame
+func ParseReply(frame []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(frame) < frameLength || binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return nil, nil, false
+	}
+
+	arp := frame[14:]
+	if binary.BigEndian.Uint16(arp[0:2]) != 1 || binary.BigEndian.Uint16(arp[2:4]) != 0x0800 ||
+		arp[4] != 6 || arp[5] != 4 || binary.BigEndian.Uint16(arp[6:8]) != opReply {
+		return nil, nil, false
+	}
+
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, arp[8:14])
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, arp[14:18])
+	return ip, mac, true
+}
+
+// sourceAddress finds the interface's IPv4 address inside network
+func sourceAddress(iface *net.Interface, network *net.IPNet) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read addresses of %s: %v", iface.Name, err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			if ip := ipNet.IP.To4(); ip != nil && network.Contains(ip) {
+				return ip, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s has no address in %s", iface.Name, network)
+}
@@ -0,0 +1,76 @@
+package arpscan
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestHosts(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		wantFirst string
+		wantLast  string
+		wantCount int
+		wantErr   bool
+	}{
+		{cidr: "192.168.1.0/24", wantFirst: "192.168.1.1", wantLast: "192.168.1.254", wantCount: 254},
+		{cidr: "10.0.0.8/30", wantFirst: "10.0.0.9", wantLast: "10.0.0.10", wantCount: 2},
+		{cidr: "10.0.0.8/31", wantFirst: "10.0.0.8", wantLast: "10.0.0.9", wantCount: 2},
+		{cidr: "10.0.0.0/16", wantErr: true},
+		{cidr: "2001:db8::/120", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, network, err := net.ParseCIDR(tt.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hosts, err := Hosts(network)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Hosts(%s) succeeded, want error", tt.cidr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Hosts(%s) error = %v", tt.cidr, err)
+		}
+		if len(hosts) != tt.wantCount || hosts[0].String() != tt.wantFirst || hosts[len(hosts)-1].String() != tt.wantLast {
+			t.Errorf("Hosts(%s) = %d hosts from %s to %s", tt.cidr, len(hosts), hosts[0], hosts[len(hosts)-1])
+		}
+	}
+}
+
+func TestRequestAndParseReply(t *testing.T) {
+	ourMAC, _ := net.ParseMAC("02:42:ac:11:00:02")
+	request := Request(ourMAC, net.ParseIP("192.168.1.23"), net.ParseIP("192.168.1.1"))
+
+	want := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02, 0x42, 0xac, 0x11, 0x00, 0x02, 0x08, 0x06,
+		0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x01,
+		0x02, 0x42, 0xac, 0x11, 0x00, 0x02, 192, 168, 1, 23,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 192, 168, 1, 1,
+	}
+	if !bytes.Equal(request, want) {
+		t.Fatalf("Request() = % x, want % x", request, want)
+	}
+
+	// A request is not a reply
+	if _, _, ok := ParseReply(request); ok {
+		t.Error("ParseReply() accepted a request")
+	}
+
+	// The router's answer, padded to the Ethernet minimum as on the wire
+	reply := append([]byte{
+		0x02, 0x42, 0xac, 0x11, 0x00, 0x02, 0xa4, 0x2b, 0xb0, 0x11, 0x22, 0x33, 0x08, 0x06,
+		0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x02,
+		0xa4, 0x2b, 0xb0, 0x11, 0x22, 0x33, 192, 168, 1, 1,
+		0x02, 0x42, 0xac, 0x11, 0x00, 0x02, 192, 168, 1, 23,
+	}, make([]byte, 18)...)
+	ip, mac, ok := ParseReply(reply)
+	if !ok || ip.String() != "192.168.1.1" || mac.String() != "a4:2b:b0:11:22:33" {
+		t.Errorf("ParseReply() = %v, %v, %v", ip, mac, ok)
+	}
+}
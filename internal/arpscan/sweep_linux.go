@@ -0,0 +1,143 @@
+//go:build linux
+
+package arpscan
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/rawsock"
+)
+
+// readTimeout bounds each receive so the sweep notices its deadline
+const readTimeout = 50 * time.Millisecond
+
+// Check reports whether a sweep can open its raw socket, which needs
+// CAP_NET_RAW
+func Check() error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(rawsock.Htons(etherTypeARP)))
+	if err != nil {
+		return fmt.Errorf("failed to open raw socket: %v", err)
+	}
+	return syscall.Close(fd)
+}
+
+// Sweep sends an ARP request to every host of an IPv4 network through the
+// named interface and returns the hosts that replied, in reply order. It
+// needs CAP_NET_RAW.
+func Sweep(ifaceName string, network *net.IPNet, opts Options) ([]Reply, error) {
+	opts = opts.withDefaults()
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("%s is not an Ethernet interface", ifaceName)
+	}
+	srcIP, err := sourceAddress(iface, network)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := Hosts(network)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(rawsock.Htons(etherTypeARP)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	link := &syscall.SockaddrLinklayer{Protocol: rawsock.Htons(etherTypeARP), Ifindex: iface.Index}
+	if err := syscall.Bind(fd, link); err != nil {
+		return nil, fmt.Errorf("failed to bind raw socket to %s: %v", ifaceName, err)
+	}
+	timeout := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return nil, err
+	}
+
+	var (
+		sentAt  = make(map[string]time.Time)
+		mu      sync.Mutex
+		sendErr error
+		done    = make(chan struct{})
+	)
+
+	broadcast := &syscall.SockaddrLinklayer{
+		Protocol: rawsock.Htons(etherTypeARP),
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	go func() {
+		defer close(done)
+		for _, host := range hosts {
+			if host.Equal(srcIP) {
+				continue
+			}
+			mu.Lock()
+			sentAt[host.String()] = time.Now()
+			mu.Unlock()
+
+			if err := syscall.Sendto(fd, Request(iface.HardwareAddr, srcIP, host), 0, broadcast); err != nil {
+				mu.Lock()
+				sendErr = fmt.Errorf("failed to send ARP request: %v", err)
+				mu.Unlock()
+				return
+			}
+			time.Sleep(opts.Interval)
+		}
+	}()
+
+	var replies []Reply
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+	var deadline time.Time
+
+	for {
+		if deadline.IsZero() {
+			select {
+			case <-done:
+				deadline = time.Now().Add(opts.Timeout)
+			default:
+			}
+		} else if time.Now().After(deadline) {
+			break
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return replies, fmt.Errorf("failed to read ARP reply: %v", err)
+		}
+		received := time.Now()
+
+		ip, mac, ok := ParseReply(buf[:n])
+		if !ok || seen[ip.String()] {
+			continue
+		}
+		mu.Lock()
+		sent, asked := sentAt[ip.String()]
+		mu.Unlock()
+		if !asked {
+			continue
+		}
+
+		seen[ip.String()] = true
+		replies = append(replies, Reply{IP: ip, MAC: mac, ResponseTime: received.Sub(sent)})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return replies, sendErr
+}
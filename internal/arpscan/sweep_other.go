@@ -0,0 +1,15 @@
+//go:build !linux
+
+package arpscan
+
+import "net"
+
+// Check is only implemented on Linux
+func Check() error {
+	return ErrUnsupported
+}
+
+// Sweep is only implemented on Linux
+func Sweep(ifaceName string, network *net.IPNet, opts Options) ([]Reply, error) {
+	return nil, ErrUnsupported
+}
@@ -58,8 +58,20 @@ func SaveConfig(config *models.AttackDetectorConfig, configPath string) error {
 func EnsureDirectories(config *models.AttackDetectorConfig) error {
 	dirs := []string{
 		filepath.Dir(config.KnownDevicesFile),
+		filepath.Dir(config.DHCPServersFile),
+		filepath.Dir(config.PortProfilesFile),
+		filepath.Dir(config.PortBaselineFile),
 		filepath.Dir(config.BluetoothDevicesFile),
 		filepath.Dir(config.LogFile),
+		filepath.Dir(config.AttackStoreFile),
+		filepath.Dir(config.DeviceInventoryFile),
+		filepath.Dir(config.OUIFile),
+		filepath.Dir(config.TrustedAPsFile),
+		filepath.Dir(config.BlockStateFile),
+		filepath.Dir(config.NftablesRulesetFile),
+		filepath.Dir(config.SimulationLogFile),
+		filepath.Dir(config.ProtectedTargetsFile),
+		filepath.Dir(config.RulesFile),
 		"web/templates",
 		"web/static",
 		"scripts",
@@ -1,22 +1,51 @@
 package detector
 
 import (
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/boboTheFoff/shheissee-go/internal/logging"
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+	"github.com/boboTheFoff/shheissee-go/internal/store"
 )
 
+// Errors returned by the blocker, wrapped with the affected target
+var (
+	ErrAlreadyBlocked     = errors.New("already blocked")
+	ErrNotBlocked         = errors.New("not blocked")
+	ErrNoBlockingTool     = errors.New("no supported blocking tool found")
+	ErrBlockerUnavailable = errors.New("blocker not initialized")
+	ErrProtectedTarget    = errors.New("protected target")
+	ErrInvalidTarget      = errors.New("invalid target")
+)
+
+// BlockOptions are the optional settings of a manual block
+type BlockOptions struct {
+	TTL      time.Duration // 0 blocks permanently
+	Override bool          // block even if the target is protected
+}
+
 // Blocker handles active blocking of detected threats
 type Blocker struct {
 	logger         *logging.Logger
-	blockedIPs     map[string]time.Time
-	blockedMACs    map[string]time.Time
-	blockedBTAddrs map[string]time.Time
+	runner         runner.CommandRunner
+	dryRun         *runner.DryRunner // set while commands are only simulated
+	simulations    *store.SimulationLog
+	simulated      map[string]bool // targets blocked in dry-run mode, by kind and target
+	store          *store.BlockStore
+	blockedIPs     map[string]models.BlockRecord
+	blockedMACs    map[string]models.BlockRecord
+	blockedBTAddrs map[string]models.BlockRecord
+	ttlRules       []models.BlockTTLRule
+	expiryErrors   map[string]string // expired blocks that could not be removed, by the error logged
+	protection     *ProtectionPolicy
+	backend        string
+	nftRulesetPath string
+	nftReady       bool
 	autoBlock      bool
 	mu             sync.RWMutex
 }
@@ -25,225 +54,421 @@ type Blocker struct {
 func NewBlocker(logger *logging.Logger, autoBlock bool) *Blocker {
 	return &Blocker{
 		logger:         logger,
-		blockedIPs:     make(map[string]time.Time),
-		blockedMACs:    make(map[string]time.Time),
-		blockedBTAddrs: make(map[string]time.Time),
+		runner:         runner.NewExecRunner(),
+		blockedIPs:     make(map[string]models.BlockRecord),
+		blockedMACs:    make(map[string]models.BlockRecord),
+		blockedBTAddrs: make(map[string]models.BlockRecord),
+		expiryErrors:   make(map[string]string),
+		backend:        BackendAuto,
 		autoBlock:      autoBlock,
 	}
 }
 
-// BlockIP blocks an IP address using iptables
-func (b *Blocker) BlockIP(ip string, reason string) error {
+// SetRunner replaces the runner used to execute firewall and radio tools
+func (b *Blocker) SetRunner(r runner.CommandRunner) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dryRun != nil {
+		b.dryRun = runner.NewDryRunner(r)
+		b.runner = b.dryRun
+		return
+	}
+	b.runner = r
+}
+
+// SetDryRun enables or disables dry-run mode. In dry-run mode the firewall,
+// rfkill and aireplay-ng commands are recorded as simulated actions instead of
+// being run, and the block list is left unchanged.
+func (b *Blocker) SetDryRun(enabled bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	if enabled == (b.dryRun != nil) {
+		return
+	}
+	if enabled {
+		b.dryRun = runner.NewDryRunner(b.runner)
+		b.runner = b.dryRun
+		b.simulated = make(map[string]bool)
+	} else {
+		b.runner = b.dryRun.Wrapped()
+		b.dryRun = nil
+		b.simulated = nil
+	}
+
+	// A simulated nft -f did not create the table
+	b.nftReady = false
+	b.logger.LogInfo(fmt.Sprintf("Blocker dry-run %s", map[bool]string{true: "enabled", false: "disabled"}[enabled]))
+}
+
+// IsDryRun reports whether the blocker only simulates its actions
+func (b *Blocker) IsDryRun() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.dryRun != nil
+}
+
+// SetSimulationLog sets where actions simulated in dry-run mode are kept
+func (b *Blocker) SetSimulationLog(log *store.SimulationLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.simulations = log
+}
+
+// GetSimulatedActions returns the most recent simulated actions, oldest first
+func (b *Blocker) GetSimulatedActions(limit int) ([]models.SimulatedAction, error) {
+	b.mu.RLock()
+	simulations := b.simulations
+	b.mu.RUnlock()
+
+	if simulations == nil {
+		return nil, nil
+	}
+	return simulations.Recent(limit)
+}
+
+// SetStore loads the block list from s and persists every later change to it
+func (b *Blocker) SetStore(s *store.BlockStore) error {
+	items, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.store = s
+	b.setItems(items)
+	return nil
+}
+
+// SetBackend selects the firewall backend used for IP and MAC blocks. The
+// nftables backend writes its generated ruleset to rulesetPath.
+func (b *Blocker) SetBackend(backend string, rulesetPath string) error {
+	switch backend {
+	case "", BackendAuto:
+		backend = BackendAuto
+	case BackendNftables:
+	default:
+		return fmt.Errorf("unknown firewall backend %q (use %s or %s)", backend, BackendAuto, BackendNftables)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.backend = backend
+	b.nftRulesetPath = rulesetPath
+	b.nftReady = false
+	return nil
+}
+
+// SetProtection sets the policy of targets that must not be blocked
+func (b *Blocker) SetProtection(policy *ProtectionPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.protection = policy
+}
+
+// SetTTLRules sets the rules that decide how long auto-blocks last
+func (b *Blocker) SetTTLRules(rules []models.BlockTTLRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ttlRules = append([]models.BlockTTLRule(nil), rules...)
+}
+
+// BlockIP blocks an IP address using the configured firewall backend
+func (b *Blocker) BlockIP(ip string, reason string, opts BlockOptions) error {
+	return b.blockIP(ip, reason, nil, opts)
+}
+
+func (b *Blocker) blockIP(ip string, reason string, attack *models.Attack, opts BlockOptions) error {
+	ip, err := canonicalTarget(blockKindIP, ip)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.syncState()
+
+	if err := b.checkProtected(blockKindIP, ip, attack, opts); err != nil {
+		return err
+	}
+
 	// Check if already blocked
-	if _, exists := b.blockedIPs[ip]; exists {
-		return fmt.Errorf("IP %s is already blocked", ip)
+	if b.isBlocked(blockKindIP, ip) {
+		return fmt.Errorf("IP %s is %w", ip, ErrAlreadyBlocked)
 	}
 
 	// Try different firewall tools in order of preference
-	var cmd *exec.Cmd
-	var err error
-
-	// Try ufw first (Ubuntu/Debian)
-	if b.isCommandAvailable("ufw") {
-		cmd = exec.Command("sudo", "ufw", "deny", "from", ip)
-	} else if b.isCommandAvailable("firewall-cmd") {
+	if b.backend == BackendNftables {
+		err = b.nftUpdate("add", nftSetForIP(ip), ip)
+	} else if b.runner.Available("ufw") {
+		// Try ufw first (Ubuntu/Debian)
+		err = b.runner.Run("sudo", "ufw", "deny", "from", ip)
+	} else if b.runner.Available("firewall-cmd") {
 		// Try firewalld (RHEL/CentOS/Fedora)
-		cmd = exec.Command("sudo", "firewall-cmd", "--permanent", "--add-rich-rule", fmt.Sprintf("rule family='ipv4' source address='%s' reject", ip))
-		err = cmd.Run()
+		err = b.runner.Run("sudo", "firewall-cmd", "--permanent", "--add-rich-rule", richRule(ip))
 		if err == nil {
-			cmd = exec.Command("sudo", "firewall-cmd", "--reload")
+			err = b.runner.Run("sudo", "firewall-cmd", "--reload")
 		}
-	} else if b.isCommandAvailable("iptables") {
-		// Try iptables directly
-		cmd = exec.Command("sudo", "iptables", "-I", "INPUT", "-s", ip, "-j", "DROP")
+	} else if tool := iptablesFor(ip); b.runner.Available(tool) {
+		// Try iptables, or ip6tables for IPv6, directly
+		err = b.runner.Run("sudo", tool, "-I", "INPUT", "-s", ip, "-j", "DROP")
 	} else {
-		return fmt.Errorf("no supported firewall tool found (ufw, firewalld, iptables)")
+		return fmt.Errorf("%w (ufw, firewalld, %s)", ErrNoBlockingTool, tool)
 	}
 
-	if cmd != nil {
-		err = cmd.Run()
-		if err != nil {
-			return fmt.Errorf("failed to block IP %s: %v", ip, err)
-		}
+	if err != nil {
+		return fmt.Errorf("failed to block IP %s: %w", ip, err)
 	}
 
 	// Record the block
-	b.blockedIPs[ip] = time.Now()
-
-	b.logger.LogInfo(fmt.Sprintf("Blocked IP %s: %s", ip, reason))
+	b.record(blockKindIP, ip, reason, attack, opts.TTL)
 	return nil
 }
 
 // UnblockIP removes IP block
 func (b *Blocker) UnblockIP(ip string) error {
+	ip, err := canonicalTarget(blockKindIP, ip)
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, exists := b.blockedIPs[ip]; !exists {
-		return fmt.Errorf("IP %s is not blocked", ip)
-	}
+	b.syncState()
 
-	var cmd *exec.Cmd
-	var err error
+	if !b.isBlocked(blockKindIP, ip) {
+		return fmt.Errorf("IP %s is %w", ip, ErrNotBlocked)
+	}
 
 	// Try different firewall tools
-	if b.isCommandAvailable("ufw") {
-		cmd = exec.Command("sudo", "ufw", "delete", "deny", "from", ip)
-	} else if b.isCommandAvailable("firewall-cmd") {
-		cmd = exec.Command("sudo", "firewall-cmd", "--permanent", "--remove-rich-rule", fmt.Sprintf("rule family='ipv4' source address='%s' reject", ip))
-		err = cmd.Run()
+	if b.backend == BackendNftables {
+		err = b.nftUpdate("delete", nftSetForIP(ip), ip)
+	} else if b.runner.Available("ufw") {
+		err = b.runner.Run("sudo", "ufw", "delete", "deny", "from", ip)
+	} else if b.runner.Available("firewall-cmd") {
+		err = b.runner.Run("sudo", "firewall-cmd", "--permanent", "--remove-rich-rule", richRule(ip))
 		if err == nil {
-			cmd = exec.Command("sudo", "firewall-cmd", "--reload")
+			err = b.runner.Run("sudo", "firewall-cmd", "--reload")
 		}
-	} else if b.isCommandAvailable("iptables") {
-		cmd = exec.Command("sudo", "iptables", "-D", "INPUT", "-s", ip, "-j", "DROP")
+	} else if tool := iptablesFor(ip); b.runner.Available(tool) {
+		err = b.runner.Run("sudo", tool, "-D", "INPUT", "-s", ip, "-j", "DROP")
 	} else {
-		return fmt.Errorf("no supported firewall tool found")
+		return fmt.Errorf("%w (ufw, firewalld, %s)", ErrNoBlockingTool, tool)
 	}
 
-	if cmd != nil {
-		err = cmd.Run()
-		if err != nil {
-			return fmt.Errorf("failed to unblock IP %s: %v", ip, err)
-		}
+	if err != nil {
+		return fmt.Errorf("failed to unblock IP %s: %w", ip, err)
 	}
 
-	delete(b.blockedIPs, ip)
-	b.logger.LogInfo(fmt.Sprintf("Unblocked IP %s", ip))
+	b.forget(blockKindIP, ip)
 	return nil
 }
 
-// BlockMAC blocks a MAC address using ebtables or iptables
-func (b *Blocker) BlockMAC(mac string, reason string) error {
+// richRule returns the firewalld rich rule rejecting a canonical IP address
+func richRule(ip string) string {
+	family := "ipv4"
+	if strings.Contains(ip, ":") {
+		family = "ipv6"
+	}
+	return fmt.Sprintf("rule family='%s' source address='%s' reject", family, ip)
+}
+
+// iptablesFor returns the iptables command for the family of a canonical IP address
+func iptablesFor(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+// BlockMAC blocks a MAC address using nftables, ebtables or iptables
+func (b *Blocker) BlockMAC(mac string, reason string, opts BlockOptions) error {
+	return b.blockMAC(mac, reason, nil, opts)
+}
+
+func (b *Blocker) blockMAC(mac string, reason string, attack *models.Attack, opts BlockOptions) error {
+	mac, err := canonicalTarget(blockKindMAC, mac)
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, exists := b.blockedMACs[mac]; exists {
-		return fmt.Errorf("MAC %s is already blocked", mac)
+	b.syncState()
+
+	if err := b.checkProtected(blockKindMAC, mac, attack, opts); err != nil {
+		return err
+	}
+
+	if b.isBlocked(blockKindMAC, mac) {
+		return fmt.Errorf("MAC %s is %w", mac, ErrAlreadyBlocked)
 	}
 
-	var cmd *exec.Cmd
-	var err error
+	if b.backend == BackendNftables {
+		if err := b.nftUpdate("add", nftSetMACs, mac); err != nil {
+			return fmt.Errorf("failed to block MAC %s: %w", mac, err)
+		}
+		b.record(blockKindMAC, mac, reason, attack, opts.TTL)
+		return nil
+	}
 
 	// Try ebtables for layer 2 blocking
-	if b.isCommandAvailable("ebtables") {
-		cmd = exec.Command("sudo", "ebtables", "-A", "INPUT", "-s", mac, "-j", "DROP")
-		err = cmd.Run()
+	if b.runner.Available("ebtables") {
+		err := b.runner.Run("sudo", "ebtables", "-A", "INPUT", "-s", mac, "-j", "DROP")
 		if err == nil {
-			b.blockedMACs[mac] = time.Now()
-			b.logger.LogInfo(fmt.Sprintf("Blocked MAC %s: %s", mac, reason))
+			b.record(blockKindMAC, mac, reason, attack, opts.TTL)
 			return nil
 		}
 	}
 
 	// Fallback to iptables with MAC matching
-	if b.isCommandAvailable("iptables") {
-		cmd = exec.Command("sudo", "iptables", "-I", "INPUT", "-m", "mac", "--mac-source", mac, "-j", "DROP")
-		err = cmd.Run()
+	if b.runner.Available("iptables") {
+		err := b.runner.Run("sudo", "iptables", "-I", "INPUT", "-m", "mac", "--mac-source", mac, "-j", "DROP")
 		if err != nil {
 			return fmt.Errorf("failed to block MAC %s: %v", mac, err)
 		}
 	} else {
-		return fmt.Errorf("no supported tool for MAC blocking (ebtables or iptables)")
+		return fmt.Errorf("%w for MAC blocking (ebtables or iptables)", ErrNoBlockingTool)
 	}
 
-	b.blockedMACs[mac] = time.Now()
-	b.logger.LogInfo(fmt.Sprintf("Blocked MAC %s: %s", mac, reason))
+	b.record(blockKindMAC, mac, reason, attack, opts.TTL)
 	return nil
 }
 
 // UnblockMAC removes MAC block
 func (b *Blocker) UnblockMAC(mac string) error {
+	mac, err := canonicalTarget(blockKindMAC, mac)
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, exists := b.blockedMACs[mac]; !exists {
-		return fmt.Errorf("MAC %s is not blocked", mac)
+	b.syncState()
+
+	if !b.isBlocked(blockKindMAC, mac) {
+		return fmt.Errorf("MAC %s is %w", mac, ErrNotBlocked)
 	}
 
-	var cmd *exec.Cmd
-	var err error
+	if b.backend == BackendNftables {
+		if err := b.nftUpdate("delete", nftSetMACs, mac); err != nil {
+			return fmt.Errorf("failed to unblock MAC %s: %w", mac, err)
+		}
+		b.forget(blockKindMAC, mac)
+		return nil
+	}
 
-	if b.isCommandAvailable("ebtables") {
-		cmd = exec.Command("sudo", "ebtables", "-D", "INPUT", "-s", mac, "-j", "DROP")
-		err = cmd.Run()
+	if b.runner.Available("ebtables") {
+		err := b.runner.Run("sudo", "ebtables", "-D", "INPUT", "-s", mac, "-j", "DROP")
 		if err == nil {
-			delete(b.blockedMACs, mac)
-			b.logger.LogInfo(fmt.Sprintf("Unblocked MAC %s", mac))
+			b.forget(blockKindMAC, mac)
 			return nil
 		}
 	}
 
-	if b.isCommandAvailable("iptables") {
-		cmd = exec.Command("sudo", "iptables", "-D", "INPUT", "-m", "mac", "--mac-source", mac, "-j", "DROP")
-		err = cmd.Run()
+	if b.runner.Available("iptables") {
+		err := b.runner.Run("sudo", "iptables", "-D", "INPUT", "-m", "mac", "--mac-source", mac, "-j", "DROP")
 		if err != nil {
 			return fmt.Errorf("failed to unblock MAC %s: %v", mac, err)
 		}
 	} else {
-		return fmt.Errorf("no supported tool for MAC unblocking")
+		return fmt.Errorf("%w for MAC unblocking (ebtables or iptables)", ErrNoBlockingTool)
 	}
 
-	delete(b.blockedMACs, mac)
-	b.logger.LogInfo(fmt.Sprintf("Unblocked MAC %s", mac))
+	b.forget(blockKindMAC, mac)
 	return nil
 }
 
 // BlockBluetoothDevice blocks a Bluetooth device by blocking its MAC
-func (b *Blocker) BlockBluetoothDevice(btAddr string, reason string) error {
+func (b *Blocker) BlockBluetoothDevice(btAddr string, reason string, opts BlockOptions) error {
+	return b.blockBluetoothDevice(btAddr, reason, nil, opts)
+}
+
+func (b *Blocker) blockBluetoothDevice(btAddr string, reason string, attack *models.Attack, opts BlockOptions) error {
+	btAddr, err := canonicalTarget(blockKindBluetooth, btAddr)
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, exists := b.blockedBTAddrs[btAddr]; exists {
-		return fmt.Errorf("Bluetooth device %s is already blocked", btAddr)
+	b.syncState()
+
+	if err := b.checkProtected(blockKindBluetooth, btAddr, attack, opts); err != nil {
+		return err
+	}
+
+	if b.isBlocked(blockKindBluetooth, btAddr) {
+		return fmt.Errorf("Bluetooth device %s is %w", btAddr, ErrAlreadyBlocked)
 	}
 
 	// Use rfkill to block Bluetooth devices if available
-	if b.isCommandAvailable("rfkill") {
+	if b.runner.Available("rfkill") {
 		// This is a simplified approach - in practice, Bluetooth blocking
 		// might require more sophisticated tools like btmgmt
-		cmd := exec.Command("sudo", "rfkill", "block", "bluetooth")
-		err := cmd.Run()
+		err := b.runner.Run("sudo", "rfkill", "block", "bluetooth")
 		if err != nil {
 			b.logger.LogError(fmt.Sprintf("Failed to block Bluetooth device %s", btAddr), err)
 			return err
 		}
 	}
 
-	b.blockedBTAddrs[btAddr] = time.Now()
-	b.logger.LogInfo(fmt.Sprintf("Blocked Bluetooth device %s: %s", btAddr, reason))
+	b.record(blockKindBluetooth, btAddr, reason, attack, opts.TTL)
 	return nil
 }
 
 // UnblockBluetoothDevice removes Bluetooth device block
 func (b *Blocker) UnblockBluetoothDevice(btAddr string) error {
+	btAddr, err := canonicalTarget(blockKindBluetooth, btAddr)
+	if err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if _, exists := b.blockedBTAddrs[btAddr]; !exists {
-		return fmt.Errorf("Bluetooth device %s is not blocked", btAddr)
+	b.syncState()
+
+	if !b.isBlocked(blockKindBluetooth, btAddr) {
+		return fmt.Errorf("Bluetooth device %s is %w", btAddr, ErrNotBlocked)
 	}
 
-	if b.isCommandAvailable("rfkill") {
-		cmd := exec.Command("sudo", "rfkill", "unblock", "bluetooth")
-		err := cmd.Run()
+	if b.runner.Available("rfkill") {
+		err := b.runner.Run("sudo", "rfkill", "unblock", "bluetooth")
 		if err != nil {
 			return fmt.Errorf("failed to unblock Bluetooth device %s: %v", btAddr, err)
 		}
 	}
 
-	delete(b.blockedBTAddrs, btAddr)
-	b.logger.LogInfo(fmt.Sprintf("Unblocked Bluetooth device %s", btAddr))
+	b.forget(blockKindBluetooth, btAddr)
 	return nil
 }
 
 // DeauthWiFiClient sends deauthentication packets to disconnect a WiFi client
 func (b *Blocker) DeauthWiFiClient(clientMAC string, apMAC string, reason string) error {
-	if !b.isCommandAvailable("aireplay-ng") {
-		return fmt.Errorf("aireplay-ng not available for WiFi deauthentication")
+	clientMAC, err := canonicalTarget(blockKindMAC, clientMAC)
+	if err != nil {
+		return err
+	}
+	apMAC, err = canonicalTarget(blockKindMAC, apMAC)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.runner.Available("aireplay-ng") {
+		return fmt.Errorf("%w: aireplay-ng not available for WiFi deauthentication", ErrNoBlockingTool)
 	}
 
 	// Find monitor interface
@@ -253,12 +478,16 @@ func (b *Blocker) DeauthWiFiClient(clientMAC string, apMAC string, reason string
 	}
 
 	// Send deauth packets
-	cmd := exec.Command("sudo", "aireplay-ng", "--deauth", "10", "-a", apMAC, "-c", clientMAC, monitorInterface)
-	err = cmd.Run()
+	err = b.runner.Run("sudo", "aireplay-ng", "--deauth", "10", "-a", apMAC, "-c", clientMAC, monitorInterface)
 	if err != nil {
 		return fmt.Errorf("failed to deauth WiFi client %s: %v", clientMAC, err)
 	}
 
+	if b.dryRun != nil {
+		b.simulate("deauth wifi client", clientMAC, reason, nil)
+		return nil
+	}
+
 	b.logger.LogInfo(fmt.Sprintf("Deauthenticated WiFi client %s from AP %s: %s", clientMAC, apMAC, reason))
 	return nil
 }
@@ -273,19 +502,47 @@ func (b *Blocker) BlockWiFiAccessPoint(apMAC string, reason string) error {
 
 // AutoBlockAttack automatically blocks an attack based on its type and severity
 func (b *Blocker) AutoBlockAttack(attack models.Attack) error {
-	if !b.autoBlock {
+	// In dry-run mode attacks are always simulated, so the effect of enabling
+	// auto-blocking can be reviewed before turning it on
+	b.mu.RLock()
+	autoBlock := b.autoBlock || b.dryRun != nil
+	ttl := b.autoBlockTTL(attack)
+	b.mu.RUnlock()
+
+	if !autoBlock {
 		return nil
 	}
 
+	// An attack seen again in every scan is blocked, or simulated, only once
+	err := b.blockAttack(attack, ttl)
+	if errors.Is(err, ErrAlreadyBlocked) {
+		return nil
+	}
+	return err
+}
+
+// blockAttack blocks the target of an attack by the means its type calls for
+func (b *Blocker) blockAttack(attack models.Attack, ttl time.Duration) error {
 	switch attack.Type {
 	case "UNKNOWN_DEVICE", "SUSPICIOUS_PORT", "AI_CONNECTION_ANOMALY":
 		// Block by IP if it's an IP address
 		if strings.Contains(attack.Target, ".") {
-			return b.BlockIP(attack.Target, fmt.Sprintf("Auto-blocked: %s", attack.Description))
+			return b.blockIP(attack.Target, fmt.Sprintf("Auto-blocked: %s", attack.Description), &attack, BlockOptions{TTL: ttl})
+		}
+	case "ARP_SPOOFING":
+		// Only a flipped binding or changed gateway MAC is severe. A MAC that
+		// merely answers for many addresses is usually a VM or Docker host, a
+		// Wi-Fi extender or a proxy-ARP router, so it is not blocked.
+		if attack.Severity != models.SeverityHigh {
+			return nil
 		}
+		// Block the MAC answering for addresses it does not own. The address
+		// itself belongs to the victim, so it is not blocked. The protection
+		// policy keeps the gateway's current MAC from being blocked.
+		return b.blockMAC(attack.Target, fmt.Sprintf("Auto-blocked: %s", attack.Description), &attack, BlockOptions{TTL: ttl})
 	case "BLUETOOTH_SPOOFING", "BLUETOOTH_MITM":
 		// Block Bluetooth device
-		return b.BlockBluetoothDevice(attack.Target, fmt.Sprintf("Auto-blocked: %s", attack.Description))
+		return b.blockBluetoothDevice(attack.Target, fmt.Sprintf("Auto-blocked: %s", attack.Description), &attack, BlockOptions{TTL: ttl})
 	case "EVIL_TWIN", "ROGUE_AP":
 		// Deauth WiFi clients from rogue APs
 		// This is simplified - in practice would need more context
@@ -295,10 +552,128 @@ func (b *Blocker) AutoBlockAttack(attack models.Attack) error {
 	return nil
 }
 
+// checkProtected refuses to block protected targets. Manual blocks may
+// override the protection; auto-blocks never do.
+func (b *Blocker) checkProtected(kind string, target string, attack *models.Attack, opts BlockOptions) error {
+	why, protected := b.protection.Protects(kind, target)
+	if !protected {
+		return nil
+	}
+
+	if attack == nil && opts.Override {
+		b.logger.LogWarning(fmt.Sprintf("Blocking protected %s %s (%s) on explicit override", blockKindLabels[kind], target, why))
+		return nil
+	}
+
+	b.logger.LogWarning(fmt.Sprintf("Refusing to block protected %s %s (%s)", blockKindLabels[kind], target, why))
+	return fmt.Errorf("%s %s is a %w (%s)", blockKindLabels[kind], target, ErrProtectedTarget, why)
+}
+
+// autoBlockTTL returns the block duration for an attack from the first matching TTL rule
+func (b *Blocker) autoBlockTTL(attack models.Attack) time.Duration {
+	for _, rule := range b.ttlRules {
+		if rule.Matches(attack) {
+			return rule.TTL
+		}
+	}
+	return 0
+}
+
+// ExpireBlocks removes every time-limited block that has run out at now,
+// returning the number of blocks removed
+func (b *Blocker) ExpireBlocks(now time.Time) int {
+	type expiredBlock struct {
+		kind   string
+		target string
+	}
+
+	b.mu.Lock()
+	if b.dryRun != nil {
+		// Removing a block runs commands too, so blocks wait for dry-run to end
+		b.mu.Unlock()
+		return 0
+	}
+	b.syncState()
+	var expired []expiredBlock
+	for _, kind := range []string{blockKindIP, blockKindMAC, blockKindBluetooth} {
+		for target, record := range b.blockedMap(kind) {
+			if record.Expired(now) {
+				expired = append(expired, expiredBlock{kind: kind, target: target})
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	removed := 0
+	for _, block := range expired {
+		var err error
+		switch block.kind {
+		case blockKindIP:
+			err = b.UnblockIP(block.target)
+		case blockKindMAC:
+			err = b.UnblockMAC(block.target)
+		default:
+			err = b.UnblockBluetoothDevice(block.target)
+		}
+
+		// Another process may have removed the block already
+		if err != nil && !errors.Is(err, ErrNotBlocked) {
+			// The block stays recorded and is retried every interval, for
+			// example until a blocking tool is installed; the same error is
+			// logged only once
+			if b.noteExpiryError(block.kind+" "+block.target, err.Error()) {
+				b.logger.LogError(fmt.Sprintf("Failed to expire block of %s, retrying quietly", block.target), err)
+			}
+			continue
+		}
+		b.noteExpiryError(block.kind+" "+block.target, "")
+		if err == nil {
+			b.logger.LogInfo(fmt.Sprintf("Block of %s expired", block.target))
+			removed++
+		}
+	}
+	return removed
+}
+
+// noteExpiryError remembers the error of the last attempt to expire a block
+// ("" after a success), reporting whether it differs from the one before
+func (b *Blocker) noteExpiryError(key string, message string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.expiryErrors[key] == message {
+		return false
+	}
+	if message == "" {
+		delete(b.expiryErrors, key)
+	} else {
+		b.expiryErrors[key] = message
+	}
+	return true
+}
+
+// RunExpiry expires blocks every interval until stop is closed
+func (b *Blocker) RunExpiry(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.ExpireBlocks(time.Now())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // GetBlockedItems returns all currently blocked items
 func (b *Blocker) GetBlockedItems() models.BlockedItems {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.syncState()
 
 	return models.BlockedItems{
 		BlockedIPs:     b.copyBlockedMap(b.blockedIPs),
@@ -317,14 +692,8 @@ func (b *Blocker) SetAutoBlock(enabled bool) {
 
 // Helper methods
 
-func (b *Blocker) isCommandAvailable(cmd string) bool {
-	_, err := exec.LookPath(cmd)
-	return err == nil
-}
-
 func (b *Blocker) findMonitorInterface() (string, error) {
-	cmd := exec.Command("iwconfig")
-	output, err := cmd.Output()
+	output, err := b.runner.Output("iwconfig")
 	if err != nil {
 		return "", err
 	}
@@ -342,10 +711,192 @@ func (b *Blocker) findMonitorInterface() (string, error) {
 	return "", fmt.Errorf("no monitor interface found")
 }
 
-func (b *Blocker) copyBlockedMap(original map[string]time.Time) map[string]time.Time {
-	copy := make(map[string]time.Time)
+func (b *Blocker) copyBlockedMap(original map[string]models.BlockRecord) map[string]models.BlockRecord {
+	copy := make(map[string]models.BlockRecord)
 	for k, v := range original {
 		copy[k] = v
 	}
 	return copy
 }
+
+// Block kinds, as used in drift reports and the block CLI
+const (
+	blockKindIP        = "ip"
+	blockKindMAC       = "mac"
+	blockKindBluetooth = "bt"
+)
+
+// blockKindLabels names block kinds in log messages
+var blockKindLabels = map[string]string{
+	blockKindIP:        "IP",
+	blockKindMAC:       "MAC",
+	blockKindBluetooth: "Bluetooth device",
+}
+
+func (b *Blocker) blockedMap(kind string) map[string]models.BlockRecord {
+	switch kind {
+	case blockKindIP:
+		return b.blockedIPs
+	case blockKindMAC:
+		return b.blockedMACs
+	default:
+		return b.blockedBTAddrs
+	}
+}
+
+func blockedMapOf(items *models.BlockedItems, kind string) map[string]models.BlockRecord {
+	switch kind {
+	case blockKindIP:
+		return items.BlockedIPs
+	case blockKindMAC:
+		return items.BlockedMACs
+	default:
+		return items.BlockedBTAddrs
+	}
+}
+
+// canonicalTarget writes an IP address or MAC the way the block list is
+// keyed, so a target blocked as "aa:bb:cc:dd:ee:ff" can be unblocked as
+// "AA:BB:CC:DD:EE:FF". Anything else is rejected before it reaches a
+// firewall command, where nft would parse it as ruleset syntax.
+func canonicalTarget(kind string, target string) (string, error) {
+	var canonical string
+	if kind == blockKindIP {
+		canonical = normalizeIP(strings.TrimSpace(target))
+	} else {
+		canonical = normalizeMAC(strings.TrimSpace(target))
+	}
+	if canonical == "" {
+		return "", fmt.Errorf("%w: %q is not a valid %s", ErrInvalidTarget, target, blockKindLabels[kind])
+	}
+	return canonical, nil
+}
+
+// canonicalizeItems rewrites the keys of a block list written before targets
+// were normalized
+func canonicalizeItems(items *models.BlockedItems) {
+	for _, kind := range []string{blockKindIP, blockKindMAC, blockKindBluetooth} {
+		blocked := blockedMapOf(items, kind)
+		for target, record := range blocked {
+			if canonical, err := canonicalTarget(kind, target); err == nil && canonical != target {
+				delete(blocked, target)
+				blocked[canonical] = record
+			}
+		}
+	}
+}
+
+// isBlocked reports whether a target is on the block list or, in dry-run
+// mode, was already blocked in simulation
+func (b *Blocker) isBlocked(kind string, target string) bool {
+	if _, exists := b.blockedMap(kind)[target]; exists {
+		return true
+	}
+	return b.simulated[kind+" "+target]
+}
+
+// syncState reloads the block list so changes made by other processes are seen
+func (b *Blocker) syncState() {
+	if b.store == nil {
+		return
+	}
+
+	items, err := b.store.Load()
+	if err != nil {
+		b.logger.LogError("Failed to load block list", err)
+		return
+	}
+	b.setItems(items)
+}
+
+func (b *Blocker) setItems(items models.BlockedItems) {
+	canonicalizeItems(&items)
+	b.blockedIPs = items.BlockedIPs
+	b.blockedMACs = items.BlockedMACs
+	b.blockedBTAddrs = items.BlockedBTAddrs
+}
+
+// record stores a new block. The rule is already installed at this point, so
+// a failure to persist it is logged rather than returned.
+func (b *Blocker) record(kind string, target string, reason string, attack *models.Attack, ttl time.Duration) {
+	if b.dryRun != nil {
+		b.simulated[kind+" "+target] = true
+		b.simulate("block "+kind, target, reason, attack)
+		return
+	}
+
+	b.logger.LogInfo(fmt.Sprintf("Blocked %s %s: %s", blockKindLabels[kind], target, reason))
+
+	record := models.BlockRecord{
+		Reason:    reason,
+		Origin:    models.BlockOriginManual,
+		BlockedAt: time.Now(),
+	}
+	if attack != nil {
+		record.Origin = models.BlockOriginAuto
+	}
+	if ttl > 0 {
+		expiresAt := record.BlockedAt.Add(ttl)
+		record.ExpiresAt = &expiresAt
+	}
+	b.blockedMap(kind)[target] = record
+
+	if b.store == nil {
+		return
+	}
+	_, err := b.store.Update(func(items *models.BlockedItems) {
+		canonicalizeItems(items)
+		blockedMapOf(items, kind)[target] = record
+	})
+	if err != nil {
+		b.logger.LogError(fmt.Sprintf("Failed to persist block of %s", target), err)
+	}
+}
+
+// forget removes a block after its rule was removed
+func (b *Blocker) forget(kind string, target string) {
+	if b.dryRun != nil {
+		delete(b.simulated, kind+" "+target)
+		b.simulate("unblock "+kind, target, "", nil)
+		return
+	}
+
+	b.logger.LogInfo(fmt.Sprintf("Unblocked %s %s", blockKindLabels[kind], target))
+	delete(b.blockedMap(kind), target)
+
+	if b.store == nil {
+		return
+	}
+	_, err := b.store.Update(func(items *models.BlockedItems) {
+		canonicalizeItems(items)
+		delete(blockedMapOf(items, kind), target)
+	})
+	if err != nil {
+		b.logger.LogError(fmt.Sprintf("Failed to persist unblock of %s", target), err)
+	}
+}
+
+// simulate records the commands collected by the dry runner as one action
+func (b *Blocker) simulate(action string, target string, reason string, attack *models.Attack) {
+	simulated := models.SimulatedAction{
+		Timestamp: time.Now(),
+		Action:    action,
+		Target:    target,
+		Reason:    reason,
+		Commands:  b.dryRun.Take(),
+		Attack:    attack,
+	}
+
+	message := fmt.Sprintf("[DRY RUN] Would %s %s: %s", action, target, strings.Join(simulated.Commands, "; "))
+	if attack != nil {
+		message += fmt.Sprintf(" (triggered by %s %s attack on %s: %s)", attack.Severity, attack.Type, attack.Target, attack.Description)
+	}
+	b.logger.LogInfo(message)
+
+	if b.simulations == nil {
+		return
+	}
+	if err := b.simulations.Add(simulated); err != nil {
+		b.logger.LogError("Failed to record simulated action", err)
+	}
+}
@@ -0,0 +1,520 @@
+package detector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/logging"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+	"github.com/boboTheFoff/shheissee-go/internal/store"
+)
+
+func newTestBlocker(t *testing.T, available ...string) (*Blocker, *runner.ReplayRunner) {
+	t.Helper()
+
+	logger, err := logging.NewLogger(filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable(available...)
+
+	blocker := NewBlocker(logger, false)
+	blocker.SetRunner(replay)
+	return blocker, replay
+}
+
+func TestBlockIPCommandSequence(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []string
+		want      []string
+		wantErr   error
+	}{
+		{
+			name:      "ufw preferred",
+			available: []string{"ufw", "firewall-cmd", "iptables"},
+			want:      []string{"sudo ufw deny from 192.168.1.50"},
+		},
+		{
+			name:      "firewalld adds rule and reloads",
+			available: []string{"firewall-cmd", "iptables"},
+			want: []string{
+				"sudo firewall-cmd --permanent --add-rich-rule rule family='ipv4' source address='192.168.1.50' reject",
+				"sudo firewall-cmd --reload",
+			},
+		},
+		{
+			name:      "iptables fallback",
+			available: []string{"iptables"},
+			want:      []string{"sudo iptables -I INPUT -s 192.168.1.50 -j DROP"},
+		},
+		{
+			name:    "no firewall tool",
+			wantErr: ErrNoBlockingTool,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocker, replay := newTestBlocker(t, tt.available...)
+
+			err := blocker.BlockIP("192.168.1.50", "test", BlockOptions{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BlockIP() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := replay.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockIPv6UsesIPv6Rules(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []string
+		want      []string
+		wantErr   error
+	}{
+		{
+			name:      "firewalld ipv6 rule",
+			available: []string{"firewall-cmd"},
+			want: []string{
+				"sudo firewall-cmd --permanent --add-rich-rule rule family='ipv6' source address='2001:db8::1' reject",
+				"sudo firewall-cmd --reload",
+			},
+		},
+		{
+			name:      "ip6tables",
+			available: []string{"iptables", "ip6tables"},
+			want:      []string{"sudo ip6tables -I INPUT -s 2001:db8::1 -j DROP"},
+		},
+		{
+			name:      "iptables cannot block IPv6",
+			available: []string{"iptables"},
+			wantErr:   ErrNoBlockingTool,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocker, replay := newTestBlocker(t, tt.available...)
+
+			err := blocker.BlockIP("2001:db8::1", "test", BlockOptions{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BlockIP() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := replay.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnblockIPCommandSequence(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "iptables")
+
+	if err := blocker.UnblockIP("192.168.1.50"); !errors.Is(err, ErrNotBlocked) {
+		t.Fatalf("UnblockIP() of unblocked IP error = %v, want %v", err, ErrNotBlocked)
+	}
+	if err := blocker.BlockIP("192.168.1.50", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockIP("192.168.1.50", "test", BlockOptions{}); !errors.Is(err, ErrAlreadyBlocked) {
+		t.Fatalf("second BlockIP() error = %v, want %v", err, ErrAlreadyBlocked)
+	}
+	if err := blocker.UnblockIP("192.168.1.50"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo iptables -I INPUT -s 192.168.1.50 -j DROP",
+		"sudo iptables -D INPUT -s 192.168.1.50 -j DROP",
+	}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestBlockMACFallsBackToIptables(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "ebtables", "iptables")
+	replay.AddError("sudo ebtables -A INPUT -s AA:BB:CC:DD:EE:FF -j DROP", errors.New("exit status 1"))
+
+	if err := blocker.BlockMAC("AA:BB:CC:DD:EE:FF", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo ebtables -A INPUT -s AA:BB:CC:DD:EE:FF -j DROP",
+		"sudo iptables -I INPUT -m mac --mac-source AA:BB:CC:DD:EE:FF -j DROP",
+	}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if _, blocked := blocker.GetBlockedItems().BlockedMACs["AA:BB:CC:DD:EE:FF"]; !blocked {
+		t.Error("MAC not recorded as blocked")
+	}
+}
+
+func TestDeauthWiFiClientUsesMonitorInterface(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "aireplay-ng")
+	if err := replay.AddFile("iwconfig", "testdata/iwconfig_monitor.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := blocker.DeauthWiFiClient("AA:BB:CC:DD:EE:FF", "00:11:22:33:44:55", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"iwconfig",
+		"sudo aireplay-ng --deauth 10 -a 00:11:22:33:44:55 -c AA:BB:CC:DD:EE:FF wlan0mon",
+	}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestAutoBlockAttack(t *testing.T) {
+	tests := []struct {
+		name   string
+		attack models.Attack
+		want   []string
+	}{
+		{
+			name:   "unknown device blocked by IP",
+			attack: models.Attack{Type: "UNKNOWN_DEVICE", Target: "192.168.1.50"},
+			want:   []string{"sudo iptables -I INPUT -s 192.168.1.50 -j DROP"},
+		},
+		{
+			name:   "bluetooth spoofing uses rfkill",
+			attack: models.Attack{Type: "BLUETOOTH_SPOOFING", Target: "11:22:33:44:55:66"},
+			want:   []string{"sudo rfkill block bluetooth"},
+		},
+		{
+			name:   "flipped ARP binding blocked by MAC",
+			attack: models.Attack{Type: "ARP_SPOOFING", Severity: models.SeverityHigh, Target: "AA:BB:CC:DD:EE:FF"},
+			want:   []string{"sudo iptables -I INPUT -m mac --mac-source AA:BB:CC:DD:EE:FF -j DROP"},
+		},
+		{
+			name:   "shared ARP MAC is not blocked",
+			attack: models.Attack{Type: "ARP_SPOOFING", Severity: models.SeverityMedium, Target: "AA:BB:CC:DD:EE:FF"},
+			want:   nil,
+		},
+		{
+			name:   "informational attack is ignored",
+			attack: models.Attack{Type: "WIFI_MONITORING", Target: "wifi"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocker, replay := newTestBlocker(t, "iptables", "rfkill")
+			blocker.SetAutoBlock(true)
+
+			if err := blocker.AutoBlockAttack(tt.attack); err != nil {
+				t.Fatal(err)
+			}
+			if got := replay.Calls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockStatePersistsAcrossBlockers(t *testing.T) {
+	blockStore, err := store.OpenBlockStore(filepath.Join(t.TempDir(), "blocked_items.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := newTestBlocker(t, "iptables")
+	if err := first.SetStore(blockStore); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.BlockIP("192.168.1.50", "manual test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	first.SetAutoBlock(true)
+	attack := models.Attack{Type: "UNKNOWN_DEVICE", Target: "192.168.1.77", Description: "Unknown device"}
+	if err := first.AutoBlockAttack(attack); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second blocker, as built by a separate CLI invocation, sees both blocks
+	second, replay := newTestBlocker(t, "iptables")
+	if err := second.SetStore(blockStore); err != nil {
+		t.Fatal(err)
+	}
+
+	items := second.GetBlockedItems()
+	if got := items.BlockedIPs["192.168.1.50"]; got.Origin != models.BlockOriginManual || got.Reason != "manual test" {
+		t.Errorf("manual block = %+v", got)
+	}
+	if got := items.BlockedIPs["192.168.1.77"]; got.Origin != models.BlockOriginAuto {
+		t.Errorf("auto block = %+v", got)
+	}
+
+	if err := second.UnblockIP("192.168.1.50"); err != nil {
+		t.Fatalf("UnblockIP() error = %v", err)
+	}
+	want := []string{"sudo iptables -D INPUT -s 192.168.1.50 -j DROP"}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+
+	// The first blocker picks up the unblock from the store
+	if err := first.UnblockIP("192.168.1.50"); !errors.Is(err, ErrNotBlocked) {
+		t.Errorf("UnblockIP() on stale blocker error = %v, want %v", err, ErrNotBlocked)
+	}
+}
+
+func TestBlockerNormalizesTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked_items.json")
+	// A block list written before targets were normalized
+	legacy := `{"blocked_ips": {}, "blocked_macs": {"00:11:22:aa:bb:cc": {"reason": "old", "origin": "manual"}}, "blocked_bt_addrs": {}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	blockStore, err := store.OpenBlockStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocker, replay := newTestBlocker(t, "iptables", "ip6tables")
+	if err := blocker.SetStore(blockStore); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockMAC("aa:bb:cc:dd:ee:ff", "cli", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockMAC("AA:BB:CC:DD:EE:FF", "web", BlockOptions{}); !errors.Is(err, ErrAlreadyBlocked) {
+		t.Errorf("BlockMAC() of the same MAC in upper case error = %v, want %v", err, ErrAlreadyBlocked)
+	}
+	if err := blocker.UnblockMAC("AA:BB:CC:DD:EE:FF"); err != nil {
+		t.Fatalf("UnblockMAC() error = %v", err)
+	}
+	if err := blocker.UnblockMAC("00:11:22:AA:BB:CC"); err != nil {
+		t.Fatalf("UnblockMAC() of a legacy record error = %v", err)
+	}
+	if err := blocker.BlockIP(" 2001:DB8:0::0001 ", "cli", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo iptables -I INPUT -m mac --mac-source AA:BB:CC:DD:EE:FF -j DROP",
+		"sudo iptables -D INPUT -m mac --mac-source AA:BB:CC:DD:EE:FF -j DROP",
+		"sudo iptables -D INPUT -m mac --mac-source 00:11:22:AA:BB:CC -j DROP",
+		"sudo ip6tables -I INPUT -s 2001:db8::1 -j DROP",
+	}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	items, err := blockStore.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items.BlockedMACs) != 0 || len(items.BlockedIPs) != 1 {
+		t.Errorf("stored blocks = %+v", items)
+	}
+	if _, ok := items.BlockedIPs["2001:db8::1"]; !ok {
+		t.Errorf("stored IPs = %v, want 2001:db8::1", items.BlockedIPs)
+	}
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "iptables", "ebtables")
+	if err := replay.AddFile("sudo iptables -S INPUT", "testdata/iptables_input.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := replay.AddFile("sudo ebtables -L INPUT", "testdata/ebtables_input.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	blocker.blockedIPs["192.168.1.50"] = models.BlockRecord{BlockedAt: now}
+	blocker.blockedIPs["192.168.1.60"] = models.BlockRecord{BlockedAt: now}
+	blocker.blockedMACs["00:11:22:33:44:55"] = models.BlockRecord{BlockedAt: now}
+
+	drift, err := blocker.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.BlockDrift{
+		{Kind: "ip", Target: "192.168.1.60", Issue: models.DriftMissingRule},
+		{Kind: "ip", Target: "10.0.0.99", Issue: models.DriftUnmanagedRule},
+		{Kind: "mac", Target: "AA:BB:CC:DD:EE:01", Issue: models.DriftUnmanagedRule},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("Reconcile() = %+v, want %+v", drift, want)
+	}
+}
+
+func TestAutoBlockTTLAndExpiry(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "iptables")
+	blocker.SetAutoBlock(true)
+	blocker.SetTTLRules([]models.BlockTTLRule{
+		{Type: "UNKNOWN_DEVICE", Severity: "LOW", TTL: time.Minute},
+		{Type: "UNKNOWN_DEVICE", TTL: time.Hour},
+		{Severity: "HIGH", TTL: 0},
+	})
+
+	attacks := []models.Attack{
+		{Type: "UNKNOWN_DEVICE", Severity: models.SeverityLow, Target: "192.168.1.10"},
+		{Type: "UNKNOWN_DEVICE", Severity: models.SeverityHigh, Target: "192.168.1.11"},
+		{Type: "SUSPICIOUS_PORT", Severity: models.SeverityHigh, Target: "192.168.1.12"},
+	}
+	for _, attack := range attacks {
+		if err := blocker.AutoBlockAttack(attack); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := blocker.GetBlockedItems()
+	wantTTLs := map[string]time.Duration{
+		"192.168.1.10": time.Minute,
+		"192.168.1.11": time.Hour,
+		"192.168.1.12": 0,
+	}
+	for ip, want := range wantTTLs {
+		record := items.BlockedIPs[ip]
+		var got time.Duration
+		if record.ExpiresAt != nil {
+			got = record.ExpiresAt.Sub(record.BlockedAt)
+		}
+		if got != want {
+			t.Errorf("%s TTL = %s, want %s", ip, got, want)
+		}
+	}
+
+	replay.Reset()
+	if removed := blocker.ExpireBlocks(time.Now().Add(2 * time.Minute)); removed != 1 {
+		t.Errorf("ExpireBlocks() removed %d blocks, want 1", removed)
+	}
+	want := []string{"sudo iptables -D INPUT -s 192.168.1.10 -j DROP"}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+
+	if removed := blocker.ExpireBlocks(time.Now().Add(48 * time.Hour)); removed != 1 {
+		t.Errorf("ExpireBlocks() removed %d blocks, want 1", removed)
+	}
+	if items := blocker.GetBlockedItems(); len(items.BlockedIPs) != 1 {
+		t.Errorf("permanent block should remain, got %v", items.BlockedIPs)
+	}
+}
+
+func TestExpireBlocksLogsMissingToolOnce(t *testing.T) {
+	dir := t.TempDir()
+	expired := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	state := `{"blocked_ips": {"192.168.1.10": {"reason": "test", "origin": "auto", "expires_at": "` + expired + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "blocked_items.json"), []byte(state), 0644); err != nil {
+		t.Fatal(err)
+	}
+	blockStore, err := store.OpenBlockStore(filepath.Join(dir, "blocked_items.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "test.log")
+	logger, err := logging.NewLogger(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	// No blocking tool is installed
+	replay := runner.NewReplayRunner()
+	blocker := NewBlocker(logger, false)
+	blocker.SetRunner(replay)
+	if err := blocker.SetStore(blockStore); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if removed := blocker.ExpireBlocks(time.Now()); removed != 0 {
+			t.Fatalf("ExpireBlocks() removed %d blocks without a tool", removed)
+		}
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "Failed to expire block of 192.168.1.10"); n != 1 {
+		t.Errorf("expiry failure logged %d times, want 1:\n%s", n, data)
+	}
+
+	// The block is removed once a tool appears
+	replay.SetAvailable("iptables")
+	if removed := blocker.ExpireBlocks(time.Now()); removed != 1 {
+		t.Errorf("ExpireBlocks() removed %d blocks, want 1", removed)
+	}
+}
+
+func TestDryRunRecordsSimulatedActions(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "iptables", "rfkill")
+	simulations, err := store.OpenSimulationLog(filepath.Join(t.TempDir(), "simulated.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocker.SetSimulationLog(simulations)
+	blocker.SetDryRun(true)
+
+	// Auto-blocking is off, but dry-run still shows what it would do
+	attack := models.Attack{Type: "UNKNOWN_DEVICE", Severity: models.SeverityHigh, Target: "192.168.1.77", Description: "Unknown device"}
+	if err := blocker.AutoBlockAttack(attack); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockBluetoothDevice("11:22:33:44:55:66", "manual", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// The next scan cycle reports the same attack; it is simulated only once
+	if err := blocker.AutoBlockAttack(attack); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := replay.Calls(); len(calls) != 0 {
+		t.Errorf("dry-run executed commands: %q", calls)
+	}
+	if items := blocker.GetBlockedItems(); len(items.BlockedIPs) != 0 || len(items.BlockedBTAddrs) != 0 {
+		t.Errorf("dry-run changed the block list: %+v", items)
+	}
+
+	actions, err := blocker.GetSimulatedActions(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("got %d simulated actions, want 2", len(actions))
+	}
+
+	if actions[0].Action != "block ip" || actions[0].Attack == nil || actions[0].Attack.Type != "UNKNOWN_DEVICE" {
+		t.Errorf("first action = %+v", actions[0])
+	}
+	if want := []string{"sudo iptables -I INPUT -s 192.168.1.77 -j DROP"}; !reflect.DeepEqual(actions[0].Commands, want) {
+		t.Errorf("first action commands = %q, want %q", actions[0].Commands, want)
+	}
+	if actions[1].Attack != nil {
+		t.Errorf("manual action has an attack: %+v", actions[1].Attack)
+	}
+	if want := []string{"sudo rfkill block bluetooth"}; !reflect.DeepEqual(actions[1].Commands, want) {
+		t.Errorf("second action commands = %q, want %q", actions[1].Commands, want)
+	}
+
+	blocker.SetDryRun(false)
+	if err := blocker.BlockIP("192.168.1.77", "real", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sudo iptables -I INPUT -s 192.168.1.77 -j DROP"}; !reflect.DeepEqual(replay.Calls(), want) {
+		t.Errorf("calls after disabling dry-run = %q, want %q", replay.Calls(), want)
+	}
+}
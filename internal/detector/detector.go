@@ -1,14 +1,20 @@
 package detector
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/boboTheFoff/shheissee-go/internal/logging"
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/oui"
+	"github.com/boboTheFoff/shheissee-go/internal/portscan"
+	"github.com/boboTheFoff/shheissee-go/internal/rules"
 	"github.com/boboTheFoff/shheissee-go/internal/scanners"
+	"github.com/boboTheFoff/shheissee-go/internal/store"
 )
 
 // AttackDetector coordinates all security scanning and attack detection systems
@@ -19,14 +25,32 @@ type AttackDetector struct {
 	networkScanner   *scanners.NetworkScanner
 	bluetoothScanner *scanners.BluetoothScanner
 	wifiScanner      *scanners.WiFiScanner
+	registry         *scanners.Registry
 	anomalyDetector  *models.AnomalyDetector
 	blocker          *Blocker
 	knownDevices     []string
 	knownBtDevices   []models.BluetoothDevice
-	attackLog        []models.Attack
+	attackStore      *store.AttackStore
+	inventory        *store.DeviceInventory
+	trustedAPs       *store.TrustedAPStore
+	resources        []resource
+	expiryOnce       sync.Once
+	stopExpiry       chan struct{}
 	mu               sync.RWMutex
 }
 
+// resource is a store or watcher the detector releases when it is closed
+type resource struct {
+	name   string
+	closer io.Closer
+}
+
+// ErrUnknownAccessPoint is returned when no access point matches an SSID or BSSID
+var ErrUnknownAccessPoint = errors.New("no matching access point")
+
+// blockExpiryInterval is how often time-limited blocks are checked
+const blockExpiryInterval = 30 * time.Second
+
 // NewAttackDetector creates a new attack detector instance
 func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, error) {
 	// Load known devices
@@ -35,6 +59,17 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 		return nil, fmt.Errorf("failed to load known devices: %v", err)
 	}
 
+	// Authorized DHCP servers use the known devices format: IP or MAC addresses
+	dhcpServers, err := scanners.LoadKnownDevices(config.DHCPServersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load authorized DHCP servers: %v", err)
+	}
+
+	portProfiles, err := scanners.LoadPortProfiles(config.PortProfilesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load port profiles: %v", err)
+	}
+
 	knownBtDevices, err := scanners.LoadKnownBluetoothDevices(config.BluetoothDevicesFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load known Bluetooth devices: %v", err)
@@ -48,10 +83,119 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 
 	consoleLogger := logging.NewConsoleLogger()
 
+	// Everything opened below is closed again, newest first, if a later
+	// step fails
+	var resources []resource
+	fail := func(err error) (*AttackDetector, error) {
+		closeResources(resources, nil)
+		logger.Close()
+		return nil, err
+	}
+
+	// Open persistent attack history
+	attackStore, err := store.OpenAttackStore(config.AttackStoreFile, store.Retention{
+		MaxAge:     config.AttackRetention,
+		MaxEntries: config.MaxStoredAttacks,
+	})
+	if err != nil {
+		return fail(fmt.Errorf("failed to open attack store: %v", err))
+	}
+	resources = append(resources, resource{"attack store", attackStore})
+
+	// Open the inventory of every device seen so far
+	inventory, err := store.OpenDeviceInventory(config.DeviceInventoryFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open device inventory: %v", err))
+	}
+	resources = append(resources, resource{"device inventory", inventory})
+
+	// Open the registry of access points trusted against evil twins
+	trustedAPs, err := store.OpenTrustedAPStore(config.TrustedAPsFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open trusted access points: %v", err))
+	}
+	resources = append(resources, resource{"trusted access points", trustedAPs})
+
+	// Load the detection rules; the scanners pick up later edits of the file
+	detectionRules, err := rules.Load(config.RulesFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load detection rules: %v", err))
+	}
+	resources = append(resources, resource{"detection rules", detectionRules})
+	logger.LogInfo(fmt.Sprintf("Loaded %d detection rules from %s", len(detectionRules.Rules()), config.RulesFile))
+	detectionRules.SetReloadHandler(func(count int, err error) {
+		if err != nil {
+			logger.LogError("Keeping previous detection rules", err)
+			return
+		}
+		logger.LogInfo(fmt.Sprintf("Reloaded %d detection rules from %s", count, config.RulesFile))
+	})
+
+	// The built-in vendor registry, extended by an installed IEEE registry
+	vendors, err := oui.Open(config.OUIFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load vendor registry: %v", err))
+	}
+
 	// Create scanners
 	networkScanner := scanners.NewNetworkScanner(knownDevices)
 	bluetoothScanner := scanners.NewBluetoothScanner(knownBtDevices)
 	wifiScanner := scanners.NewWiFiScanner()
+	networkScanner.SetRules(detectionRules)
+	networkScanner.SetVendors(vendors)
+	subnetFilter, err := scanners.NewSubnetFilter(config.NetworkIncludeCIDRs, config.NetworkExcludeCIDRs)
+	if err != nil {
+		return fail(err)
+	}
+	networkScanner.SetSubnetFilter(subnetFilter)
+	if err := configurePortScan(networkScanner, config); err != nil {
+		return fail(err)
+	}
+	portBaseline, err := scanners.NewPortBaseline(config.PortBaselineFile, portProfiles)
+	if err != nil {
+		return fail(err)
+	}
+	portBaseline.SetErrorHandler(func(err error) {
+		logger.LogError("Failed to save port baseline", err)
+	})
+	networkScanner.SetPortBaseline(portBaseline)
+	if subnets, err := networkScanner.Subnets(); err != nil {
+		logger.LogWarning(err.Error())
+	} else if len(subnets) == 0 {
+		logger.LogWarning("No local subnets selected for network discovery")
+	} else {
+		var names []string
+		for _, subnet := range subnets {
+			names = append(names, fmt.Sprintf("%s (%s)", subnet.Network, subnet.Interface))
+		}
+		logger.LogInfo("Scanning subnets: " + strings.Join(names, ", "))
+	}
+	arpScanner := scanners.NewARPScanner(networkScanner)
+	arpScanner.SetMaxIPsPerMAC(config.ARPMaxIPsPerMAC)
+	dhcpScanner := scanners.NewDHCPScanner(networkScanner, dhcpServers)
+	dnsScanner, err := scanners.NewDNSScanner(config.DNSCanaryHosts, config.DNSPinnedResolvers)
+	if err != nil {
+		return fail(err)
+	}
+	bluetoothScanner.SetRules(detectionRules)
+	bluetoothScanner.SetVendors(vendors)
+	wifiScanner.SetRules(detectionRules)
+	wifiScanner.SetVendors(vendors)
+	wifiScanner.SetMonitorInterface(config.WiFiMonitorInterface)
+	wifiScanner.SetDeauthMonitor(scanners.NewDeauthMonitor(config.DeauthWindow, config.DeauthMaxPerBSSID, config.DeauthMaxPerClient))
+	evilTwin := scanners.NewEvilTwinDetector(trustedAPs.Networks, config.EvilTwinSignalDeviation)
+	evilTwin.SetErrorHandler(func(err error) {
+		logger.LogError("Failed to read trusted access points", err)
+	})
+	wifiScanner.SetEvilTwinDetector(evilTwin)
+
+	// Register the built-in scanners in scan order
+	registry := scanners.NewRegistry()
+	for _, scanner := range []scanners.Scanner{networkScanner, arpScanner, dhcpScanner, dnsScanner, bluetoothScanner, wifiScanner} {
+		if err := registry.Register(scanner); err != nil {
+			return fail(err)
+		}
+	}
 
 	// Create anomaly detector
 	anomalyDetector := &models.AnomalyDetector{
@@ -61,8 +205,42 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 		AnomalyThreshold:  config.AnomalyThreshold,
 	}
 
-	// Create blocker (auto-block disabled by default for safety)
+	// Create blocker (auto-block disabled by default for safety) and restore
+	// the blocks recorded by earlier runs
 	blocker := NewBlocker(logger, false)
+	blockStore, err := store.OpenBlockStore(config.BlockStateFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open block store: %v", err))
+	}
+	resources = append(resources, resource{"block store", blockStore})
+	if err := blocker.SetStore(blockStore); err != nil {
+		return fail(fmt.Errorf("failed to load block list: %v", err))
+	}
+	blocker.SetTTLRules(config.AutoBlockTTLs)
+	if err := blocker.SetBackend(config.FirewallBackend, config.NftablesRulesetFile); err != nil {
+		return fail(err)
+	}
+	simulationLog, err := store.OpenSimulationLog(config.SimulationLogFile, config.MaxStoredAttacks)
+	if err != nil {
+		return fail(fmt.Errorf("failed to open simulation log: %v", err))
+	}
+	resources = append(resources, resource{"simulation log", simulationLog})
+	blocker.SetSimulationLog(simulationLog)
+
+	// Never block the gateway, name servers or this host
+	protectedTargets, err := LoadProtectedTargets(config.ProtectedTargetsFile)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load protected targets: %v", err))
+	}
+	protection, err := NewProtectionPolicy(protectedTargets)
+	if err != nil {
+		return fail(err)
+	}
+	if err := protection.AddDetected(protectedTargets); err != nil {
+		logger.LogWarning(err.Error())
+	}
+	blocker.SetProtection(protection)
+	blocker.SetDryRun(config.BlockerDryRun)
 
 	detector := &AttackDetector{
 		config:           config,
@@ -71,11 +249,16 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 		networkScanner:   networkScanner,
 		bluetoothScanner: bluetoothScanner,
 		wifiScanner:      wifiScanner,
+		registry:         registry,
 		anomalyDetector:  anomalyDetector,
 		blocker:          blocker,
 		knownDevices:     knownDevices,
 		knownBtDevices:   knownBtDevices,
-		attackLog:        []models.Attack{},
+		attackStore:      attackStore,
+		inventory:        inventory,
+		trustedAPs:       trustedAPs,
+		resources:        resources,
+		stopExpiry:       make(chan struct{}),
 	}
 
 	return detector, nil
@@ -83,7 +266,14 @@ func NewAttackDetector(config *models.AttackDetectorConfig) (*AttackDetector, er
 
 // StartMonitoring begins continuous security monitoring
 func (ad *AttackDetector) StartMonitoring() error {
-	ad.consoleLogger.DisplayStatus(len(ad.knownDevices), len(ad.knownBtDevices), len(ad.attackLog))
+	ad.consoleLogger.DisplayStatus(len(ad.knownDevices), len(ad.knownBtDevices), ad.attackStore.Count())
+
+	ad.StartBlockExpiry()
+
+	// Drift is logged by the blocker; monitoring continues either way
+	if _, err := ad.ReconcileBlocks(); err != nil {
+		ad.logger.LogError("Failed to reconcile blocked items", err)
+	}
 
 	for {
 		ad.mu.Lock()
@@ -98,83 +288,48 @@ func (ad *AttackDetector) StartMonitoring() error {
 func (ad *AttackDetector) performSecurityScan() {
 	fmt.Print("\n\033[34mScanning for threats...\033[0m\r")
 
-	// Network scan
-	networkDevices, networkAttacks, err := ad.networkScanner.ScanNetwork()
-	if err != nil {
-		ad.logger.LogError("Network scan failed", err)
-	} else {
-		// Update anomaly detector with network data
-		ad.updateAnomalyDetector(networkDevices)
-
-		// Detect AI anomalies
-		aiAttacks := ad.detectAIAnomalies()
-		networkAttacks = append(networkAttacks, aiAttacks...)
+	for _, scanner := range ad.registry.Scanners() {
+		name := scanner.Name()
 
-		// Log network attacks
-		for _, attack := range networkAttacks {
-			ad.logAttack(attack)
+		if health := scanner.Health(); !health.Available {
+			ad.logger.LogWarning(fmt.Sprintf("Skipping %s scan: %s", name, health.Message))
+			continue
 		}
 
-		ad.logger.LogScanResult("network", &models.ScanResult{
-			Type:      "network",
-			Timestamp: time.Now(),
-			Devices:   []interface{}{networkDevices},
-			Attacks:   networkAttacks,
-		})
-	}
-
-	// Port scan
-	_, portAttacks, err := ad.networkScanner.ScanPorts(networkDevices)
-	if err != nil {
-		ad.logger.LogError("Port scan failed", err)
-	} else {
-		for _, attack := range portAttacks {
-			ad.logAttack(attack)
+		devices, err := scanner.Scan()
+		if err != nil {
+			ad.logger.LogError(fmt.Sprintf("%s scan failed", name), err)
+			ad.logger.LogScanResult(name, &models.ScanResult{
+				Type:      name,
+				Timestamp: time.Now(),
+				Error:     err.Error(),
+			})
+			continue
 		}
-	}
 
-	// Bluetooth scan
-	bluetoothDevices, err := ad.bluetoothScanner.ScanBluetoothDevices()
-	if err != nil {
-		ad.logger.LogError("Bluetooth scan failed", err)
-	} else {
-		// Update anomaly detector with Bluetooth data
-		ad.updateBluetoothAnomalyDetector(bluetoothDevices)
+		// Update anomaly detector with the sensor data
+		ad.updateAnomalyHistory(devices)
+		ad.recordInventory(name, devices)
 
-		// Detect Bluetooth attacks
-		bluetoothAttacks := ad.bluetoothScanner.DetectBluetoothAttacks(bluetoothDevices)
-		for _, attack := range bluetoothAttacks {
+		attacks := scanner.Detect(devices)
+		for _, attack := range attacks {
 			ad.logAttack(attack)
 		}
 
-		ad.logger.LogScanResult("bluetooth", &models.ScanResult{
-			Type:      "bluetooth",
+		ad.logger.LogScanResult(name, &models.ScanResult{
+			Type:      name,
 			Timestamp: time.Now(),
-			Devices:   []interface{}{bluetoothDevices},
-			Attacks:   bluetoothAttacks,
+			Devices:   devices,
+			Attacks:   attacks,
 		})
 	}
 
-	// WiFi scan
-	wifiDevices, err := ad.wifiScanner.ScanWiFiNetworks()
-	if err != nil {
-		ad.logger.LogError("WiFi scan failed", err)
-	} else {
-		// Detect WiFi attacks
-		wifiAttacks := ad.wifiScanner.DetectWiFiAttacks(wifiDevices)
-		for _, attack := range wifiAttacks {
-			ad.logAttack(attack)
-		}
-
-		ad.logger.LogScanResult("wifi", &models.ScanResult{
-			Type:      "wifi",
-			Timestamp: time.Now(),
-			Devices:   []interface{}{wifiDevices},
-			Attacks:   wifiAttacks,
-		})
+	// Detect AI anomalies across everything seen so far
+	for _, attack := range ad.detectAIAnomalies() {
+		ad.logAttack(attack)
 	}
 
-	fmt.Print("\033[32mScan complete. Next scan in 60 seconds...\033[0m\r")
+	fmt.Printf("\033[32mScan complete. Next scan in %s...\033[0m\r", ad.config.ScanInterval)
 }
 
 // PerformQuickScan performs a quick security assessment
@@ -184,31 +339,32 @@ func (ad *AttackDetector) PerformQuickScan() []models.Attack {
 
 	var allAttacks []models.Attack
 
-	// Network scan
-	_, networkAttacks, err := ad.networkScanner.ScanNetwork()
-	if err == nil {
-		allAttacks = append(allAttacks, networkAttacks...)
-	}
+	for _, scanner := range ad.registry.Scanners() {
+		if health := scanner.Health(); !health.Available {
+			ad.logger.LogWarning(fmt.Sprintf("Skipping %s scan: %s", scanner.Name(), health.Message))
+			continue
+		}
 
-	// Port scan
-	networkDevices, portAttacks, err := ad.networkScanner.ScanNetwork()
-	ad.networkScanner.ScanPorts(networkDevices)
-	if err == nil {
-		allAttacks = append(allAttacks, portAttacks...)
-	}
+		devices, err := scanner.Scan()
+		if err != nil {
+			ad.logger.LogScanResult(scanner.Name(), &models.ScanResult{
+				Type:      scanner.Name(),
+				Timestamp: time.Now(),
+				Error:     err.Error(),
+			})
+			continue
+		}
+		ad.recordInventory(scanner.Name(), devices)
 
-	// Bluetooth scan
-	bluetoothDevices, err := ad.bluetoothScanner.ScanBluetoothDevices()
-	if err == nil {
-		bluetoothAttacks := ad.bluetoothScanner.DetectBluetoothAttacks(bluetoothDevices)
-		allAttacks = append(allAttacks, bluetoothAttacks...)
-	}
+		attacks := scanner.Detect(devices)
+		allAttacks = append(allAttacks, attacks...)
 
-	// WiFi scan
-	wifiDevices, err := ad.wifiScanner.ScanWiFiNetworks()
-	if err == nil {
-		wifiAttacks := ad.wifiScanner.DetectWiFiAttacks(wifiDevices)
-		allAttacks = append(allAttacks, wifiAttacks...)
+		ad.logger.LogScanResult(scanner.Name(), &models.ScanResult{
+			Type:      scanner.Name(),
+			Timestamp: time.Now(),
+			Devices:   devices,
+			Attacks:   attacks,
+		})
 	}
 
 	// Log all detected attacks
@@ -219,6 +375,37 @@ func (ad *AttackDetector) PerformQuickScan() []models.Attack {
 	return allAttacks
 }
 
+// configurePortScan applies the port scan settings to the network scanner
+func configurePortScan(ns *scanners.NetworkScanner, config *models.AttackDetectorConfig) error {
+	tcpPorts, err := portscan.ParsePorts(config.PortScanTCPPorts)
+	if err != nil {
+		return fmt.Errorf("invalid TCP port list: %v", err)
+	}
+	udpPorts, err := portscan.ParsePorts(config.PortScanUDPPorts)
+	if err != nil {
+		return fmt.Errorf("invalid UDP port list: %v", err)
+	}
+
+	return ns.SetPortScan(config.PortScanBackend, portscan.Options{
+		TCPPorts:    tcpPorts,
+		UDPPorts:    udpPorts,
+		Timeout:     config.PortScanTimeout,
+		Concurrency: config.PortScanConcurrency,
+		Rate:        config.PortScanRate,
+	})
+}
+
+// RegisterScanner adds a scanner to the detection pipeline. It is run after
+// the built-in scanners.
+func (ad *AttackDetector) RegisterScanner(scanner scanners.Scanner) error {
+	return ad.registry.Register(scanner)
+}
+
+// ScannerHealth returns the health of every registered scanner
+func (ad *AttackDetector) ScannerHealth() []models.ScannerHealth {
+	return ad.registry.Health()
+}
+
 // ListBluetoothDevices returns a list of nearby Bluetooth devices
 func (ad *AttackDetector) ListBluetoothDevices() ([]models.BluetoothDevice, error) {
 	return ad.bluetoothScanner.ScanBluetoothDevices()
@@ -265,6 +452,57 @@ func (ad *AttackDetector) ListWiFiDevices() ([]models.WiFiDevice, error) {
 	return ad.wifiScanner.ScanWiFiNetworks()
 }
 
+// TrustedNetworks returns the networks trusted against evil twins
+func (ad *AttackDetector) TrustedNetworks() ([]models.TrustedNetwork, error) {
+	return ad.trustedAPs.Networks()
+}
+
+// TrustAccessPoints scans for WiFi networks and trusts the access points
+// whose SSID or BSSID is match, as they are seen now. With includeOUI every
+// BSSID of their vendor prefixes is trusted as well.
+func (ad *AttackDetector) TrustAccessPoints(match string, includeOUI bool) ([]models.TrustedNetwork, error) {
+	// The scan loop drives the same scanner, so the scans take turns
+	ad.mu.Lock()
+	devices, err := ad.wifiScanner.ScanWiFiNetworks()
+	ad.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var aps []models.WiFiDevice
+	for _, device := range devices {
+		if device.SSID != "" && (device.SSID == match || strings.EqualFold(device.Address, match)) {
+			aps = append(aps, device)
+		}
+	}
+	if len(aps) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccessPoint, match)
+	}
+
+	networks, err := ad.trustedAPs.Trust(aps, includeOUI, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, ap := range aps {
+		ad.logger.LogInfo(fmt.Sprintf("Trusted access point %s of '%s'", ap.Address, ap.SSID))
+	}
+	return networks, nil
+}
+
+// UntrustAccessPoint removes a trusted network by SSID, or a single access
+// point by BSSID
+func (ad *AttackDetector) UntrustAccessPoint(match string) error {
+	changed, err := ad.trustedAPs.Untrust(match)
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownAccessPoint, match)
+	}
+	ad.logger.LogInfo(fmt.Sprintf("Removed trusted access point %s", match))
+	return nil
+}
+
 // PerformDemoAttack creates demo attack scenarios for testing
 func (ad *AttackDetector) PerformDemoAttack() error {
 	fmt.Println("\033[33mSetting up demo scenario...\033[0m")
@@ -317,8 +555,8 @@ func (ad *AttackDetector) displayBluetoothDevices(devices []models.BluetoothDevi
 	}
 
 	fmt.Printf("\n\033[32mFound %d Bluetooth device(s):\033[0m\n", len(devices))
-	fmt.Println("\033[1mMAC Address         Device Name                    RSSI   Status\033[0m")
-	fmt.Println(strings.Repeat("-", 70))
+	fmt.Println("\033[1mMAC Address         Device Name                    Vendor                   RSSI   Status\033[0m")
+	fmt.Println(strings.Repeat("-", 95))
 
 	for _, device := range devices {
 		rssi := "N/A"
@@ -331,15 +569,38 @@ func (ad *AttackDetector) displayBluetoothDevices(devices []models.BluetoothDevi
 			status = "Unknown"
 		}
 
-		fmt.Printf("%-18s %-30s %-6s %-10s\n",
+		fmt.Printf("%-18s %-30s %-24.24s %-6s %-10s\n",
 			device.Address,
 			device.Name,
+			oui.Label(device.Vendor, device.LocallyAdministered),
 			rssi,
 			status)
 	}
 	fmt.Println()
 }
 
+// updateAnomalyHistory feeds scanned devices into the matching anomaly history
+func (ad *AttackDetector) updateAnomalyHistory(devices []interface{}) {
+	var networkDevices []models.NetworkDevice
+	var bluetoothDevices []models.BluetoothDevice
+
+	for _, device := range devices {
+		switch d := device.(type) {
+		case models.NetworkDevice:
+			networkDevices = append(networkDevices, d)
+		case models.BluetoothDevice:
+			bluetoothDevices = append(bluetoothDevices, d)
+		}
+	}
+
+	if len(networkDevices) > 0 {
+		ad.updateAnomalyDetector(networkDevices)
+	}
+	if len(bluetoothDevices) > 0 {
+		ad.updateBluetoothAnomalyDetector(bluetoothDevices)
+	}
+}
+
 func (ad *AttackDetector) updateAnomalyDetector(devices []models.NetworkDevice) {
 	currentTime := time.Now()
 
@@ -407,6 +668,50 @@ func (ad *AttackDetector) updateBluetoothAnomalyDetector(devices []models.Blueto
 	}
 }
 
+// recordInventory adds the devices found by the network, Bluetooth or WiFi
+// scanner to the device inventory
+func (ad *AttackDetector) recordInventory(scannerName string, devices []interface{}) {
+	switch scannerName {
+	case models.DeviceKindNetwork, models.DeviceKindBluetooth, models.DeviceKindWiFi:
+	default:
+		return
+	}
+
+	if err := ad.inventory.Observe(scannerName, inventorySightings(devices), time.Now()); err != nil {
+		ad.logger.LogError("Failed to update device inventory", err)
+	}
+}
+
+// inventorySightings converts scanned devices into inventory sightings
+func inventorySightings(devices []interface{}) []models.InventoryDevice {
+	var sightings []models.InventoryDevice
+
+	for _, device := range devices {
+		switch d := device.(type) {
+		case models.NetworkDevice:
+			sighting := models.InventoryDevice{IP: d.IP, MAC: d.MAC, Hostname: d.Name, Vendor: d.Vendor, LocallyAdministered: d.LocallyAdministered}
+			for _, port := range d.Ports {
+				if port.State != "" && port.State != "open" {
+					continue
+				}
+				sighting.Ports = append(sighting.Ports, models.InventoryPort{
+					Number:   port.Number,
+					Protocol: port.Protocol,
+					Service:  port.Service,
+					Version:  port.Version,
+				})
+			}
+			sightings = append(sightings, sighting)
+		case models.BluetoothDevice:
+			sightings = append(sightings, models.InventoryDevice{MAC: d.Address, BTName: d.Name, Vendor: d.Vendor, LocallyAdministered: d.LocallyAdministered})
+		case models.WiFiDevice:
+			sightings = append(sightings, models.InventoryDevice{MAC: d.Address, SSID: d.SSID, Vendor: d.Vendor, LocallyAdministered: d.LocallyAdministered})
+		}
+	}
+
+	return sightings
+}
+
 func (ad *AttackDetector) detectAIAnomalies() []models.Attack {
 	var attacks []models.Attack
 
@@ -463,7 +768,9 @@ func (ad *AttackDetector) detectAIAnomalies() []models.Attack {
 }
 
 func (ad *AttackDetector) logAttack(attack models.Attack) {
-	ad.attackLog = append(ad.attackLog, attack)
+	if err := ad.attackStore.Add(attack); err != nil {
+		ad.logger.LogError("Failed to store attack", err)
+	}
 	ad.logger.LogAttack(&attack)
 	ad.consoleLogger.DisplayAttack(&attack)
 
@@ -476,70 +783,70 @@ func (ad *AttackDetector) logAttack(attack models.Attack) {
 	}
 }
 
-// GetAttackCount returns the total number of detected attacks
+// GetAttackCount returns the total number of retained attacks
 func (ad *AttackDetector) GetAttackCount() int {
-	ad.mu.RLock()
-	defer ad.mu.RUnlock()
-	return len(ad.attackLog)
+	return ad.attackStore.Count()
 }
 
-// GetRecentAttacks returns the most recent attacks
+// GetRecentAttacks returns the most recent attacks, oldest first
 func (ad *AttackDetector) GetRecentAttacks(limit int) []models.Attack {
-	ad.mu.RLock()
-	defer ad.mu.RUnlock()
+	return ad.attackStore.Recent(limit)
+}
 
-	start := len(ad.attackLog) - limit
-	if start < 0 {
-		start = 0
-	}
+// QueryAttacks searches the persistent attack history
+func (ad *AttackDetector) QueryAttacks(query store.Query) []models.Attack {
+	return ad.attackStore.Query(query)
+}
 
-	return ad.attackLog[start:]
+// QueryDevices searches the device inventory
+func (ad *AttackDetector) QueryDevices(query store.DeviceQuery) ([]models.InventoryDevice, error) {
+	return ad.inventory.Query(query)
 }
 
 // BlockIP manually blocks an IP address
-func (ad *AttackDetector) BlockIP(ip string, reason string) error {
+func (ad *AttackDetector) BlockIP(ip string, reason string, opts BlockOptions) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
-	return ad.blocker.BlockIP(ip, reason)
+	return ad.blocker.BlockIP(ip, reason, opts)
 }
 
 // UnblockIP manually unblocks an IP address
 func (ad *AttackDetector) UnblockIP(ip string) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
 	return ad.blocker.UnblockIP(ip)
 }
 
 // BlockMAC manually blocks a MAC address
-func (ad *AttackDetector) BlockMAC(mac string, reason string) error {
+func (ad *AttackDetector) BlockMAC(mac string, reason string, opts BlockOptions) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
-	return ad.blocker.BlockMAC(mac, reason)
+	return ad.blocker.BlockMAC(mac, reason, opts)
 }
 
 // UnblockMAC manually unblocks a MAC address
 func (ad *AttackDetector) UnblockMAC(mac string) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
 	return ad.blocker.UnblockMAC(mac)
 }
 
 // BlockBluetoothDevice manually blocks a Bluetooth device
-func (ad *AttackDetector) BlockBluetoothDevice(btAddr string, reason string) error {
+func (ad *AttackDetector) BlockBluetoothDevice(btAddr string, reason string, opts BlockOptions) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
-	return ad.blocker.BlockBluetoothDevice(btAddr, reason)
+	return ad.blocker.BlockBluetoothDevice(btAddr, reason, opts)
 }
 
 // UnblockBluetoothDevice manually unblocks a Bluetooth device
 func (ad *AttackDetector) UnblockBluetoothDevice(btAddr string) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
 	return ad.blocker.UnblockBluetoothDevice(btAddr)
 }
@@ -547,7 +854,7 @@ func (ad *AttackDetector) UnblockBluetoothDevice(btAddr string) error {
 // DeauthWiFiClient sends deauthentication packets to disconnect a WiFi client
 func (ad *AttackDetector) DeauthWiFiClient(clientMAC string, apMAC string, reason string) error {
 	if ad.blocker == nil {
-		return fmt.Errorf("blocker not initialized")
+		return ErrBlockerUnavailable
 	}
 	return ad.blocker.DeauthWiFiClient(clientMAC, apMAC, reason)
 }
@@ -560,6 +867,55 @@ func (ad *AttackDetector) GetBlockedItems() models.BlockedItems {
 	return ad.blocker.GetBlockedItems()
 }
 
+// SetDryRun enables or disables simulating blocker actions instead of running them
+func (ad *AttackDetector) SetDryRun(enabled bool) {
+	if ad.blocker != nil {
+		ad.blocker.SetDryRun(enabled)
+	}
+}
+
+// IsDryRun reports whether blocker actions are only simulated
+func (ad *AttackDetector) IsDryRun() bool {
+	return ad.blocker != nil && ad.blocker.IsDryRun()
+}
+
+// GetSimulatedActions returns the most recent actions simulated in dry-run mode
+func (ad *AttackDetector) GetSimulatedActions(limit int) ([]models.SimulatedAction, error) {
+	if ad.blocker == nil {
+		return nil, ErrBlockerUnavailable
+	}
+	return ad.blocker.GetSimulatedActions(limit)
+}
+
+// CheckNftablesRuleset generates the nftables ruleset for the current block
+// list and validates it without applying it
+func (ad *AttackDetector) CheckNftablesRuleset() (string, error) {
+	if ad.blocker == nil {
+		return "", ErrBlockerUnavailable
+	}
+	return ad.blocker.CheckNftablesRuleset()
+}
+
+// StartBlockExpiry starts removing time-limited blocks in the background once
+// they run out. It is meant for long-running modes and is safe to call twice.
+func (ad *AttackDetector) StartBlockExpiry() {
+	if ad.blocker == nil {
+		return
+	}
+	ad.expiryOnce.Do(func() {
+		go ad.blocker.RunExpiry(blockExpiryInterval, ad.stopExpiry)
+	})
+}
+
+// ReconcileBlocks reports differences between the recorded blocks and the
+// rules installed in the firewall
+func (ad *AttackDetector) ReconcileBlocks() ([]models.BlockDrift, error) {
+	if ad.blocker == nil {
+		return nil, ErrBlockerUnavailable
+	}
+	return ad.blocker.Reconcile()
+}
+
 // SetAutoBlock enables or disables automatic blocking
 func (ad *AttackDetector) SetAutoBlock(enabled bool) {
 	if ad.blocker != nil {
@@ -569,9 +925,21 @@ func (ad *AttackDetector) SetAutoBlock(enabled bool) {
 
 // Close shuts down the attack detector and cleans up resources
 func (ad *AttackDetector) Close() error {
+	close(ad.stopExpiry)
+	closeResources(ad.resources, ad.logger)
 	return ad.logger.Close()
 }
 
+// closeResources closes resources newest first, logging failures to logger
+// if it is set
+func closeResources(resources []resource, logger *logging.Logger) {
+	for i := len(resources) - 1; i >= 0; i-- {
+		if err := resources[i].closer.Close(); err != nil && logger != nil {
+			logger.LogError(fmt.Sprintf("Failed to close %s", resources[i].name), err)
+		}
+	}
+}
+
 // Utility functions
 
 func isUnusualConnectionPattern(timestamps []time.Time) bool {
@@ -0,0 +1,57 @@
+package detector
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/logging"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/scanners"
+)
+
+// fakeScanner records whether it was asked to scan
+type fakeScanner struct {
+	name      string
+	available bool
+	scanned   bool
+}
+
+func (f *fakeScanner) Name() string { return f.name }
+
+func (f *fakeScanner) Scan() ([]interface{}, error) {
+	f.scanned = true
+	return nil, nil
+}
+
+func (f *fakeScanner) Detect(devices []interface{}) []models.Attack { return nil }
+
+func (f *fakeScanner) Health() models.ScannerHealth {
+	return models.ScannerHealth{Name: f.name, Available: f.available, Message: "fake"}
+}
+
+func TestPerformQuickScanSkipsUnavailableScanners(t *testing.T) {
+	logger, err := logging.NewLogger(filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	healthy := &fakeScanner{name: "healthy", available: true}
+	missing := &fakeScanner{name: "missing"}
+	registry := scanners.NewRegistry()
+	for _, scanner := range []scanners.Scanner{healthy, missing} {
+		if err := registry.Register(scanner); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ad := &AttackDetector{logger: logger, consoleLogger: logging.NewConsoleLogger(), registry: registry}
+	ad.PerformQuickScan()
+
+	if !healthy.scanned {
+		t.Error("available scanner was not run")
+	}
+	if missing.scanned {
+		t.Error("unavailable scanner was run")
+	}
+}
@@ -0,0 +1,199 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Firewall backends selectable with AttackDetectorConfig.FirewallBackend
+const (
+	BackendAuto     = "auto"     // ufw, firewalld or iptables, whichever is installed
+	BackendNftables = "nftables" // dedicated nftables table with address sets
+)
+
+// Names of the nftables objects managed by the nftables backend
+const (
+	nftFamily  = "inet"
+	nftTable   = "shheissee"
+	nftSetIPv4 = "blocked_ipv4"
+	nftSetIPv6 = "blocked_ipv6"
+	nftSetMACs = "blocked_macs"
+)
+
+// NftablesRuleset generates the shheissee table with items as set elements.
+// The ruleset deletes any previous version of the table, so loading it with
+// "nft -f" replaces the table in a single transaction.
+func NftablesRuleset(items models.BlockedItems) string {
+	var ipv4, ipv6 []string
+	for ip := range items.BlockedIPs {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			ipv6 = append(ipv6, ip)
+		} else {
+			ipv4 = append(ipv4, ip)
+		}
+	}
+	macs := sortedKeys(items.BlockedMACs)
+	sort.Strings(ipv4)
+	sort.Strings(ipv6)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by shheissee. Do not edit; changes are overwritten.\n")
+	fmt.Fprintf(&sb, "table %s %s\n", nftFamily, nftTable)
+	fmt.Fprintf(&sb, "delete table %s %s\n\n", nftFamily, nftTable)
+	fmt.Fprintf(&sb, "table %s %s {\n", nftFamily, nftTable)
+	writeNftSet(&sb, nftSetIPv4, "ipv4_addr", ipv4)
+	writeNftSet(&sb, nftSetIPv6, "ipv6_addr", ipv6)
+	writeNftSet(&sb, nftSetMACs, "ether_addr", macs)
+	fmt.Fprintf(&sb, "\tchain input {\n")
+	fmt.Fprintf(&sb, "\t\ttype filter hook input priority filter - 10; policy accept;\n")
+	fmt.Fprintf(&sb, "\t\tip saddr @%s drop\n", nftSetIPv4)
+	fmt.Fprintf(&sb, "\t\tip6 saddr @%s drop\n", nftSetIPv6)
+	fmt.Fprintf(&sb, "\t\tether saddr @%s drop\n", nftSetMACs)
+	fmt.Fprintf(&sb, "\t}\n")
+	fmt.Fprintf(&sb, "}\n")
+	return sb.String()
+}
+
+func writeNftSet(sb *strings.Builder, name string, setType string, elements []string) {
+	fmt.Fprintf(sb, "\tset %s {\n", name)
+	fmt.Fprintf(sb, "\t\ttype %s\n", setType)
+	if len(elements) > 0 {
+		fmt.Fprintf(sb, "\t\telements = { %s }\n", strings.Join(elements, ", "))
+	}
+	fmt.Fprintf(sb, "\t}\n\n")
+}
+
+// CheckNftablesRuleset writes the ruleset for the current block list and
+// validates it with "nft -c" without changing the running firewall
+func (b *Blocker) CheckNftablesRuleset() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.syncState()
+
+	if !b.runner.Available("nft") {
+		return "", fmt.Errorf("%w: nft not available", ErrNoBlockingTool)
+	}
+
+	ruleset, err := b.writeNftRuleset()
+	if err != nil {
+		return ruleset, err
+	}
+	if err := b.runner.Run("sudo", "nft", "-c", "-f", b.nftRulesetPath); err != nil {
+		return ruleset, fmt.Errorf("nftables rejected the generated ruleset: %v", err)
+	}
+	return ruleset, nil
+}
+
+// nftUpdate adds or deletes a single set element. Set updates are atomic, so
+// no rules are inserted or removed while blocking.
+func (b *Blocker) nftUpdate(operation string, set string, element string) error {
+	if !b.runner.Available("nft") {
+		return fmt.Errorf("%w: nft not available", ErrNoBlockingTool)
+	}
+	if err := b.nftEnsureTable(); err != nil {
+		return err
+	}
+	return b.runner.Run("sudo", "nft", operation, "element", nftFamily, nftTable, set, fmt.Sprintf("{ %s }", element))
+}
+
+// nftEnsureTable loads the shheissee table, with the recorded blocks, if it
+// is not installed yet
+func (b *Blocker) nftEnsureTable() error {
+	if b.nftReady {
+		return nil
+	}
+
+	if _, err := b.runner.Output("sudo", "nft", "list", "table", nftFamily, nftTable); err != nil {
+		// A dry run only records the nft -f it would run
+		if b.dryRun == nil {
+			if _, err := b.writeNftRuleset(); err != nil {
+				return err
+			}
+		}
+		if err := b.runner.Run("sudo", "nft", "-f", b.nftRulesetPath); err != nil {
+			return fmt.Errorf("failed to load nftables ruleset %s: %v", b.nftRulesetPath, err)
+		}
+		b.logger.LogInfo(fmt.Sprintf("Created nftables table %s %s", nftFamily, nftTable))
+	}
+
+	b.nftReady = true
+	return nil
+}
+
+func (b *Blocker) writeNftRuleset() (string, error) {
+	ruleset := NftablesRuleset(models.BlockedItems{
+		BlockedIPs:  b.blockedIPs,
+		BlockedMACs: b.blockedMACs,
+	})
+
+	if err := os.MkdirAll(filepath.Dir(b.nftRulesetPath), 0755); err != nil {
+		return ruleset, err
+	}
+	if err := os.WriteFile(b.nftRulesetPath, []byte(ruleset), 0644); err != nil {
+		return ruleset, fmt.Errorf("failed to write nftables ruleset: %v", err)
+	}
+	return ruleset, nil
+}
+
+// nftSetForIP returns the set holding addresses of the IP's family
+func nftSetForIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return nftSetIPv6
+	}
+	return nftSetIPv4
+}
+
+// nftSetElements lists the elements of one of the shheissee sets. A missing
+// table is reported as an empty set.
+func (b *Blocker) nftSetElements(set string) ([]string, error) {
+	if _, err := b.runner.Output("sudo", "nft", "list", "table", nftFamily, nftTable); err != nil {
+		return nil, nil
+	}
+
+	output, err := b.runner.Output("sudo", "nft", "-j", "list", "set", nftFamily, nftTable, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nftables set %s: %v", set, err)
+	}
+	return parseNftSetElements(output)
+}
+
+// parseNftSetElements extracts the elements from "nft -j list set" output
+func parseNftSetElements(output []byte) ([]string, error) {
+	if len(strings.TrimSpace(string(output))) == 0 {
+		return nil, nil
+	}
+
+	var listing struct {
+		Nftables []struct {
+			Set *struct {
+				Elem []interface{} `json:"elem"`
+			} `json:"set"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(output, &listing); err != nil {
+		return nil, fmt.Errorf("failed to parse nftables set listing: %v", err)
+	}
+
+	var elements []string
+	for _, entry := range listing.Nftables {
+		if entry.Set == nil {
+			continue
+		}
+		for _, elem := range entry.Set.Elem {
+			// Plain elements are strings; anything else (ranges, prefixes) was
+			// not added by the blocker
+			if value, ok := elem.(string); ok {
+				elements = append(elements, value)
+			}
+		}
+	}
+	return elements, nil
+}
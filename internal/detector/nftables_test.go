@@ -0,0 +1,170 @@
+package detector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestNftablesRuleset(t *testing.T) {
+	items := models.BlockedItems{
+		BlockedIPs: map[string]models.BlockRecord{
+			"192.168.1.50": {},
+			"2001:db8::1":  {},
+			"10.0.0.5":     {},
+		},
+	}
+
+	want, err := os.ReadFile("testdata/nftables_ruleset.nft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := NftablesRuleset(items); got != string(want) {
+		t.Errorf("NftablesRuleset() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNftablesBackendCommandSequence(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "nft", "iptables")
+	rulesetPath := filepath.Join(t.TempDir(), "shheissee.nft")
+	if err := blocker.SetBackend(BackendNftables, rulesetPath); err != nil {
+		t.Fatal(err)
+	}
+	replay.AddError("sudo nft list table inet shheissee", errors.New("exit status 1"))
+
+	if err := blocker.BlockIP("192.168.1.50", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockIP("2001:db8::1", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.BlockMAC("AA:BB:CC:DD:EE:FF", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := blocker.UnblockIP("192.168.1.50"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo nft list table inet shheissee",
+		"sudo nft -f " + rulesetPath,
+		"sudo nft add element inet shheissee blocked_ipv4 { 192.168.1.50 }",
+		"sudo nft add element inet shheissee blocked_ipv6 { 2001:db8::1 }",
+		"sudo nft add element inet shheissee blocked_macs { AA:BB:CC:DD:EE:FF }",
+		"sudo nft delete element inet shheissee blocked_ipv4 { 192.168.1.50 }",
+	}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls =\n%q\nwant\n%q", got, want)
+	}
+
+	if _, err := os.Stat(rulesetPath); err != nil {
+		t.Errorf("ruleset was not written: %v", err)
+	}
+}
+
+func TestNftablesDryRunWritesNoRuleset(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "nft")
+	rulesetPath := filepath.Join(t.TempDir(), "shheissee.nft")
+	if err := blocker.SetBackend(BackendNftables, rulesetPath); err != nil {
+		t.Fatal(err)
+	}
+	replay.AddError("sudo nft list table inet shheissee", errors.New("exit status 1"))
+	blocker.SetDryRun(true)
+
+	if err := blocker.BlockIP("192.168.1.50", "test", BlockOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(rulesetPath); !os.IsNotExist(err) {
+		t.Errorf("dry-run wrote the ruleset: %v", err)
+	}
+	if want := []string{"sudo nft list table inet shheissee"}; !reflect.DeepEqual(replay.Calls(), want) {
+		t.Errorf("calls = %q, want %q", replay.Calls(), want)
+	}
+}
+
+func TestBlockerRejectsInvalidTargets(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "nft")
+	if err := blocker.SetBackend(BackendNftables, filepath.Join(t.TempDir(), "shheissee.nft")); err != nil {
+		t.Fatal(err)
+	}
+
+	injected := "10.0.0.1 }; flush ruleset; add element inet shheissee blocked_ipv4 { 10.0.0.2"
+	if err := blocker.BlockIP(injected, "test", BlockOptions{}); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("BlockIP() error = %v, want %v", err, ErrInvalidTarget)
+	}
+	if err := blocker.BlockMAC("AA:BB:CC:DD:EE:FF } ; flush ruleset", "test", BlockOptions{}); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("BlockMAC() error = %v, want %v", err, ErrInvalidTarget)
+	}
+	if err := blocker.UnblockIP("192.168.1.0/24"); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("UnblockIP() error = %v, want %v", err, ErrInvalidTarget)
+	}
+	if calls := replay.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %q, want none", calls)
+	}
+}
+
+func TestCheckNftablesRulesetDoesNotApply(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "nft")
+	rulesetPath := filepath.Join(t.TempDir(), "shheissee.nft")
+	if err := blocker.SetBackend(BackendNftables, rulesetPath); err != nil {
+		t.Fatal(err)
+	}
+	blocker.blockedIPs["192.168.1.50"] = models.BlockRecord{BlockedAt: time.Now()}
+
+	ruleset, err := blocker.CheckNftablesRuleset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sudo nft -c -f " + rulesetPath}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	written, err := os.ReadFile(rulesetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != ruleset {
+		t.Errorf("written ruleset differs from returned ruleset")
+	}
+
+	replay.AddError("sudo nft -c -f "+rulesetPath, errors.New("syntax error"))
+	if _, err := blocker.CheckNftablesRuleset(); err == nil {
+		t.Error("CheckNftablesRuleset() accepted a ruleset rejected by nft")
+	}
+}
+
+func TestReconcileNftablesSets(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "nft")
+	if err := blocker.SetBackend(BackendNftables, filepath.Join(t.TempDir(), "shheissee.nft")); err != nil {
+		t.Fatal(err)
+	}
+	if err := replay.AddFile("sudo nft -j list set inet shheissee blocked_ipv4", "testdata/nft_list_set.json"); err != nil {
+		t.Fatal(err)
+	}
+	blocker.blockedIPs["192.168.1.50"] = models.BlockRecord{BlockedAt: time.Now()}
+
+	drift, err := blocker.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.BlockDrift{
+		{Kind: "ip", Target: "10.0.0.99", Issue: models.DriftUnmanagedRule},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("Reconcile() = %+v, want %+v", drift, want)
+	}
+}
+
+func TestSetBackendRejectsUnknown(t *testing.T) {
+	blocker, _ := newTestBlocker(t)
+	if err := blocker.SetBackend("pf", ""); err == nil {
+		t.Error("SetBackend() accepted an unknown backend")
+	}
+}
@@ -0,0 +1,227 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+// protectedNetwork is a protected address range and why it is protected
+type protectedNetwork struct {
+	network *net.IPNet
+	why     string
+}
+
+// ProtectionPolicy decides which targets the blocker must not block
+type ProtectionPolicy struct {
+	networks []protectedNetwork
+	macs     map[string]string // normalized MAC -> why
+	btAddrs  map[string]string // normalized Bluetooth address -> why
+
+	// gatewayMACs returns the gateways' current MACs, so a gateway whose
+	// MAC changed after startup stays protected
+	gatewayMACs func() []string
+}
+
+// NewProtectionPolicy builds a policy from configured targets. Auto-detected
+// targets are added separately with AddDetected.
+func NewProtectionPolicy(targets models.ProtectedTargets) (*ProtectionPolicy, error) {
+	p := &ProtectionPolicy{
+		macs:    make(map[string]string),
+		btAddrs: make(map[string]string),
+	}
+
+	for _, cidr := range targets.CIDRs {
+		if err := p.AddNetwork(cidr, "configured"); err != nil {
+			return nil, err
+		}
+	}
+	for _, mac := range targets.MACs {
+		if err := p.AddMAC(mac, "configured"); err != nil {
+			return nil, err
+		}
+	}
+	for _, btAddr := range targets.BluetoothAddrs {
+		normalized := normalizeMAC(btAddr)
+		if normalized == "" {
+			return nil, fmt.Errorf("invalid protected Bluetooth address %q", btAddr)
+		}
+		p.btAddrs[normalized] = "configured"
+	}
+
+	return p, nil
+}
+
+// AddNetwork protects a CIDR or a single IP address
+func (p *ProtectionPolicy) AddNetwork(cidr string, why string) error {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return fmt.Errorf("invalid protected address %q", cidr)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		p.networks = append(p.networks, protectedNetwork{
+			network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)},
+			why:     why,
+		})
+		return nil
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid protected network %q: %v", cidr, err)
+	}
+	p.networks = append(p.networks, protectedNetwork{network: network, why: why})
+	return nil
+}
+
+// AddMAC protects a MAC address
+func (p *ProtectionPolicy) AddMAC(mac string, why string) error {
+	normalized := normalizeMAC(mac)
+	if normalized == "" {
+		return fmt.Errorf("invalid protected MAC address %q", mac)
+	}
+	p.macs[normalized] = why
+	return nil
+}
+
+// AddDetected protects the host's default gateways, name servers and local
+// interfaces as enabled in targets. Detection failures are returned but the
+// targets found so far stay protected.
+func (p *ProtectionPolicy) AddDetected(targets models.ProtectedTargets) error {
+	var errs []string
+
+	if targets.ProtectGateway {
+		gateways, err := netinfo.DefaultGateways()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("gateway: %v", err))
+		}
+
+		// The gateway's MAC is protected too, so MAC blocks cannot cut it off.
+		// It is looked up again on every check, as a replaced router or an
+		// ARP table that had not resolved it yet changes it.
+		for _, gateway := range gateways {
+			p.AddNetwork(gateway.String(), "default gateway")
+		}
+		p.gatewayMACs = currentGatewayMACs
+	}
+
+	if targets.ProtectNameservers {
+		servers, err := netinfo.Nameservers()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("name servers: %v", err))
+		}
+		for _, server := range servers {
+			p.AddNetwork(server.String(), "name server")
+		}
+	}
+
+	if targets.ProtectLocalInterfaces {
+		ifaces, err := netinfo.LocalInterfaces()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("local interfaces: %v", err))
+		}
+		for _, iface := range ifaces {
+			why := fmt.Sprintf("local interface %s", iface.Name)
+			for _, addr := range iface.Addrs {
+				p.AddNetwork(addr.IP.String(), why)
+			}
+			if len(iface.MAC) == 6 {
+				p.AddMAC(iface.MAC.String(), why)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to detect protected targets: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Protects reports whether a target of the given block kind is protected, and why
+func (p *ProtectionPolicy) Protects(kind string, target string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+
+	switch kind {
+	case blockKindIP:
+		ip := net.ParseIP(target)
+		if ip == nil {
+			return "", false
+		}
+		for _, protected := range p.networks {
+			if protected.network.Contains(ip) {
+				return protected.why, true
+			}
+		}
+	case blockKindMAC:
+		mac := normalizeMAC(target)
+		if why, ok := p.macs[mac]; ok {
+			return why, true
+		}
+		if p.gatewayMACs != nil {
+			for _, gatewayMAC := range p.gatewayMACs() {
+				if normalizeMAC(gatewayMAC) == mac {
+					return "default gateway", true
+				}
+			}
+		}
+	case blockKindBluetooth:
+		why, ok := p.btAddrs[normalizeMAC(target)]
+		return why, ok
+	}
+	return "", false
+}
+
+// currentGatewayMACs returns the MACs the ARP table holds for the default
+// gateways
+func currentGatewayMACs() []string {
+	gateways, err := netinfo.DefaultGateways()
+	if err != nil {
+		return nil
+	}
+	arpTable, err := netinfo.ARPTable()
+	if err != nil {
+		return nil
+	}
+
+	var macs []string
+	for _, gateway := range gateways {
+		for _, entry := range arpTable {
+			if entry.IP.Equal(gateway) {
+				macs = append(macs, entry.MAC.String())
+			}
+		}
+	}
+	return macs
+}
+
+// LoadProtectedTargets loads the protected targets file, creating it with the
+// defaults if it does not exist
+func LoadProtectedTargets(filename string) (models.ProtectedTargets, error) {
+	targets := models.DefaultProtectedTargets()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			data, _ := json.MarshalIndent(targets, "", "  ")
+			_ = os.WriteFile(filename, data, 0644)
+			return targets, nil
+		}
+		return targets, err
+	}
+
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return targets, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	return targets, nil
+}
@@ -0,0 +1,105 @@
+package detector
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestProtectionPolicyProtects(t *testing.T) {
+	policy, err := NewProtectionPolicy(models.ProtectedTargets{
+		CIDRs:          []string{"10.0.0.0/24", "192.168.1.1", "fd00::/64"},
+		MACs:           []string{"aa:bb:cc:dd:ee:ff"},
+		BluetoothAddrs: []string{"11:22:33:44:55:66"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kind   string
+		target string
+		want   bool
+	}{
+		{kind: "ip", target: "10.0.0.42", want: true},
+		{kind: "ip", target: "10.0.1.42", want: false},
+		{kind: "ip", target: "192.168.1.1", want: true},
+		{kind: "ip", target: "192.168.1.2", want: false},
+		{kind: "ip", target: "fd00::1", want: true},
+		{kind: "ip", target: "not-an-ip", want: false},
+		{kind: "mac", target: "AA:BB:CC:DD:EE:FF", want: true},
+		{kind: "mac", target: "AA:BB:CC:DD:EE:00", want: false},
+		{kind: "bt", target: "11:22:33:44:55:66", want: true},
+		{kind: "bt", target: "AA:BB:CC:DD:EE:FF", want: false},
+	}
+
+	for _, tt := range tests {
+		if _, got := policy.Protects(tt.kind, tt.target); got != tt.want {
+			t.Errorf("Protects(%s, %s) = %v, want %v", tt.kind, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestNewProtectionPolicyRejectsInvalidTargets(t *testing.T) {
+	for _, targets := range []models.ProtectedTargets{
+		{CIDRs: []string{"10.0.0.0/33"}},
+		{CIDRs: []string{"gateway"}},
+		{MACs: []string{"AA:BB"}},
+		{BluetoothAddrs: []string{"phone"}},
+	} {
+		if _, err := NewProtectionPolicy(targets); err == nil {
+			t.Errorf("NewProtectionPolicy(%+v) succeeded, want error", targets)
+		}
+	}
+}
+
+func TestProtectionPolicyFollowsGatewayMAC(t *testing.T) {
+	policy, err := NewProtectionPolicy(models.ProtectedTargets{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gatewayMAC := "00:11:22:33:44:55"
+	policy.gatewayMACs = func() []string { return []string{gatewayMAC} }
+
+	// A replaced router's new MAC is protected as soon as the ARP table has it
+	gatewayMAC = "66:77:88:99:aa:bb"
+	if why, protected := policy.Protects(blockKindMAC, "66:77:88:99:AA:BB"); !protected || why != "default gateway" {
+		t.Errorf("Protects(new gateway MAC) = %q, %v, want default gateway", why, protected)
+	}
+	if _, protected := policy.Protects(blockKindMAC, "00:11:22:33:44:55"); protected {
+		t.Error("Protects(old gateway MAC) = true, want false")
+	}
+}
+
+func TestBlockerRefusesProtectedTargets(t *testing.T) {
+	blocker, replay := newTestBlocker(t, "iptables")
+	blocker.SetAutoBlock(true)
+
+	policy, err := NewProtectionPolicy(models.ProtectedTargets{CIDRs: []string{"192.168.1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocker.SetProtection(policy)
+
+	attack := models.Attack{Type: "UNKNOWN_DEVICE", Target: "192.168.1.1"}
+	if err := blocker.AutoBlockAttack(attack); !errors.Is(err, ErrProtectedTarget) {
+		t.Fatalf("AutoBlockAttack() error = %v, want %v", err, ErrProtectedTarget)
+	}
+	if err := blocker.BlockIP("192.168.1.1", "manual", BlockOptions{}); !errors.Is(err, ErrProtectedTarget) {
+		t.Fatalf("BlockIP() error = %v, want %v", err, ErrProtectedTarget)
+	}
+	if got := replay.Calls(); len(got) != 0 {
+		t.Fatalf("protected target reached the firewall: %v", got)
+	}
+
+	// An explicit manual override is allowed
+	if err := blocker.BlockIP("192.168.1.1", "manual", BlockOptions{Override: true}); err != nil {
+		t.Fatalf("BlockIP() with override error = %v", err)
+	}
+	want := []string{"sudo iptables -I INPUT -s 192.168.1.1 -j DROP"}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
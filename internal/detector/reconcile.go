@@ -0,0 +1,298 @@
+package detector
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// richRuleSourcePattern extracts the source address of a firewalld rich rule
+var richRuleSourcePattern = regexp.MustCompile(`source address=["']([^"']+)["']`)
+
+// Reconcile compares the recorded blocks with the rules installed in the
+// firewall and reports any drift. Nothing is changed; kinds whose tools are
+// not installed are skipped.
+func (b *Blocker) Reconcile() ([]models.BlockDrift, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.syncState()
+
+	var drift []models.BlockDrift
+
+	installedIPs, err := b.installedIPRules()
+	if err != nil {
+		return nil, err
+	}
+	if installedIPs != nil {
+		drift = append(drift, diffBlocks(blockKindIP, b.blockedIPs, installedIPs, normalizeIP)...)
+	}
+
+	installedMACs, err := b.installedMACRules()
+	if err != nil {
+		return nil, err
+	}
+	if installedMACs != nil {
+		drift = append(drift, diffBlocks(blockKindMAC, b.blockedMACs, installedMACs, normalizeMAC)...)
+	}
+
+	// rfkill blocks the whole radio, so only a missing block can be detected
+	if len(b.blockedBTAddrs) > 0 && b.runner.Available("rfkill") {
+		output, err := b.runner.Output("rfkill", "list", "bluetooth")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rfkill state: %v", err)
+		}
+		if !strings.Contains(string(output), "Soft blocked: yes") {
+			for _, btAddr := range sortedKeys(b.blockedBTAddrs) {
+				drift = append(drift, models.BlockDrift{Kind: blockKindBluetooth, Target: btAddr, Issue: models.DriftMissingRule})
+			}
+		}
+	}
+
+	for _, d := range drift {
+		b.logger.LogWarning(fmt.Sprintf("Block drift: %s %s (%s)", d.Kind, d.Target, d.Issue))
+	}
+
+	return drift, nil
+}
+
+// installedIPRules lists the IPs denied by the firewall tool BlockIP would use.
+// It returns nil when no supported tool is installed.
+func (b *Blocker) installedIPRules() (map[string]bool, error) {
+	installed := make(map[string]bool)
+
+	if b.backend == BackendNftables {
+		if !b.runner.Available("nft") {
+			return nil, nil
+		}
+		for _, set := range []string{nftSetIPv4, nftSetIPv6} {
+			elements, err := b.nftSetElements(set)
+			if err != nil {
+				return nil, err
+			}
+			for _, element := range elements {
+				if ip := normalizeIP(element); ip != "" {
+					installed[ip] = true
+				}
+			}
+		}
+	} else if b.runner.Available("ufw") {
+		output, err := b.runner.Output("sudo", "ufw", "status")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ufw rules: %v", err)
+		}
+		for _, ip := range parseUfwDenyRules(string(output)) {
+			installed[ip] = true
+		}
+	} else if b.runner.Available("firewall-cmd") {
+		output, err := b.runner.Output("sudo", "firewall-cmd", "--permanent", "--list-rich-rules")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list firewalld rules: %v", err)
+		}
+		for _, ip := range parseRichRules(string(output)) {
+			installed[ip] = true
+		}
+	} else if b.runner.Available("iptables") || b.runner.Available("ip6tables") {
+		for _, tool := range []string{"iptables", "ip6tables"} {
+			if !b.runner.Available(tool) {
+				continue
+			}
+			output, err := b.runner.Output("sudo", tool, "-S", "INPUT")
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s rules: %v", tool, err)
+			}
+			ips, _ := parseIptablesRules(string(output))
+			for _, ip := range ips {
+				installed[ip] = true
+			}
+		}
+	} else {
+		return nil, nil
+	}
+
+	return installed, nil
+}
+
+// installedMACRules lists the MACs dropped by the nftables set, or by
+// ebtables and iptables. It returns nil when no supported tool is installed.
+func (b *Blocker) installedMACRules() (map[string]bool, error) {
+	if b.backend == BackendNftables {
+		if !b.runner.Available("nft") {
+			return nil, nil
+		}
+		elements, err := b.nftSetElements(nftSetMACs)
+		if err != nil {
+			return nil, err
+		}
+		installed := make(map[string]bool)
+		for _, element := range elements {
+			if mac := normalizeMAC(element); mac != "" {
+				installed[mac] = true
+			}
+		}
+		return installed, nil
+	}
+
+	hasEbtables := b.runner.Available("ebtables")
+	hasIptables := b.runner.Available("iptables")
+	if !hasEbtables && !hasIptables {
+		return nil, nil
+	}
+
+	installed := make(map[string]bool)
+
+	// BlockMAC falls back to iptables when ebtables fails, so check both
+	if hasEbtables {
+		output, err := b.runner.Output("sudo", "ebtables", "-L", "INPUT")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ebtables rules: %v", err)
+		}
+		for _, mac := range parseEbtablesRules(string(output)) {
+			installed[mac] = true
+		}
+	}
+	if hasIptables {
+		output, err := b.runner.Output("sudo", "iptables", "-S", "INPUT")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list iptables rules: %v", err)
+		}
+		_, macs := parseIptablesRules(string(output))
+		for _, mac := range macs {
+			installed[mac] = true
+		}
+	}
+
+	return installed, nil
+}
+
+// diffBlocks reports recorded targets without a rule and rules without a record
+func diffBlocks(kind string, recorded map[string]models.BlockRecord, installed map[string]bool, normalize func(string) string) []models.BlockDrift {
+	var drift []models.BlockDrift
+
+	known := make(map[string]bool)
+	for _, target := range sortedKeys(recorded) {
+		known[normalize(target)] = true
+		if !installed[normalize(target)] {
+			drift = append(drift, models.BlockDrift{Kind: kind, Target: target, Issue: models.DriftMissingRule})
+		}
+	}
+
+	var unmanaged []string
+	for target := range installed {
+		if !known[target] {
+			unmanaged = append(unmanaged, target)
+		}
+	}
+	sort.Strings(unmanaged)
+	for _, target := range unmanaged {
+		drift = append(drift, models.BlockDrift{Kind: kind, Target: target, Issue: models.DriftUnmanagedRule})
+	}
+
+	return drift
+}
+
+// parseUfwDenyRules extracts source IPs from "ufw status" DENY rules
+func parseUfwDenyRules(output string) []string {
+	var ips []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "Anywhere" || !strings.Contains(line, "DENY") {
+			continue
+		}
+		if ip := normalizeIP(fields[len(fields)-1]); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// parseRichRules extracts source addresses from firewalld reject rich rules
+func parseRichRules(output string) []string {
+	var ips []string
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "reject") {
+			continue
+		}
+		if match := richRuleSourcePattern.FindStringSubmatch(line); match != nil {
+			if ip := normalizeIP(match[1]); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+// parseIptablesRules extracts the IP and MAC DROP rules created by the
+// blocker from "iptables -S INPUT" output
+func parseIptablesRules(output string) (ips []string, macs []string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 6 && fields[2] == "-s" && fields[4] == "-j" && fields[5] == "DROP":
+			if ip := normalizeIP(fields[3]); ip != "" {
+				ips = append(ips, ip)
+			}
+		case len(fields) == 8 && fields[4] == "--mac-source" && fields[7] == "DROP":
+			if mac := normalizeMAC(fields[5]); mac != "" {
+				macs = append(macs, mac)
+			}
+		}
+	}
+	return ips, macs
+}
+
+// parseEbtablesRules extracts source MACs from "ebtables -L INPUT" DROP rules
+func parseEbtablesRules(output string) []string {
+	var macs []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[0] == "-s" && fields[3] == "DROP" {
+			if mac := normalizeMAC(fields[1]); mac != "" {
+				macs = append(macs, mac)
+			}
+		}
+	}
+	return macs
+}
+
+// normalizeIP returns the canonical form of a single-host address, or ""
+func normalizeIP(address string) string {
+	address = strings.TrimSuffix(strings.TrimSuffix(address, "/32"), "/128")
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// normalizeMAC returns an upper-case, zero-padded MAC address, or "".
+// ebtables prints MACs without leading zeros (0:11:22:...).
+func normalizeMAC(address string) string {
+	parts := strings.Split(address, ":")
+	if len(parts) != 6 {
+		return ""
+	}
+	for i, part := range parts {
+		if len(part) == 1 {
+			parts[i] = "0" + part
+		}
+	}
+	mac, err := net.ParseMAC(strings.Join(parts, ":"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToUpper(mac.String())
+}
+
+func sortedKeys(m map[string]models.BlockRecord) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,219 @@
+// Package dhcpprobe finds the DHCP servers answering on a local network by
+// broadcasting a DHCPDISCOVER on a raw socket and collecting every offer.
+// No lease is requested, so probing does not change the host's address.
+package dhcpprobe
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// DefaultTimeout is how long Probe waits for offers
+const DefaultTimeout = 3 * time.Second
+
+// ErrUnsupported is returned on platforms without raw packet sockets
+var ErrUnsupported = errors.New("DHCP probing is not supported on this platform")
+
+// Ethernet, IPv4, UDP and DHCP constants
+const (
+	etherTypeIPv4 = 0x0800
+	ethHeaderLen  = 14
+	ipHeaderLen   = 20
+	udpHeaderLen  = 8
+	protoUDP      = 17
+
+	serverPort = 67
+	clientPort = 68
+
+	bootRequest   = 1
+	bootReply     = 2
+	bootpLen      = 236 // fixed BOOTP fields before the magic cookie
+	minDHCPLen    = 300 // BOOTP minimum message size
+	flagBroadcast = 0x8000
+
+	optPad          = 0
+	optSubnetMask   = 1
+	optRouter       = 3
+	optDNS          = 6
+	optDomainName   = 15
+	optLeaseTime    = 51
+	optMessageType  = 53
+	optServerID     = 54
+	optParamRequest = 55
+	optEnd          = 255
+
+	msgDiscover = 1
+	msgOffer    = 2
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+// Options controls a probe
+type Options struct {
+	Timeout time.Duration // how long to wait for offers after the discover
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	return o
+}
+
+// Offer is a DHCPOFFER received in answer to a probe
+type Offer struct {
+	Server       net.IP           // server identifier, or the packet source
+	ServerMAC    net.HardwareAddr // Ethernet source of the offer
+	OfferedIP    net.IP
+	SubnetMask   net.IPMask
+	Gateways     []net.IP
+	DNS          []net.IP
+	DomainName   string
+	LeaseTime    time.Duration
+	ResponseTime time.Duration
+}
+
+// Discover builds a broadcast Ethernet frame carrying a DHCPDISCOVER from
+// mac with transaction ID xid. The broadcast flag asks servers to broadcast
+// their offers, so they are seen without an address.
+func Discover(mac net.HardwareAddr, xid uint32) []byte {
+	msg := make([]byte, bootpLen, minDHCPLen)
+	msg[0] = bootRequest
+	msg[1] = 1 // hardware type: Ethernet
+	msg[2] = 6 // hardware address length
+	binary.BigEndian.PutUint32(msg[4:8], xid)
+	binary.BigEndian.PutUint16(msg[10:12], flagBroadcast)
+	copy(msg[28:44], mac)
+
+	msg = append(msg, magicCookie...)
+	msg = append(msg, optMessageType, 1, msgDiscover)
+	msg = append(msg, optParamRequest, 6, optSubnetMask, optRouter, optDNS, optDomainName, optLeaseTime, optServerID)
+	msg = append(msg, optEnd)
+	for len(msg) < minDHCPLen {
+		msg = append(msg, optPad)
+	}
+
+	broadcast := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	return udpFrame(mac, broadcast, net.IPv4zero, net.IPv4bcast, clientPort, serverPort, msg)
+}
+
+// ParseOffer extracts a DHCPOFFER for transaction xid from an Ethernet frame
+func ParseOffer(frame []byte, xid uint32) (Offer, bool) {
+	var offer Offer
+
+	if len(frame) < ethHeaderLen+ipHeaderLen+udpHeaderLen || binary.BigEndian.Uint16(frame[12:14]) != etherTypeIPv4 {
+		return offer, false
+	}
+	ip := frame[ethHeaderLen:]
+	ihl := int(ip[0]&0x0f) * 4
+	if ip[0]>>4 != 4 || ihl < ipHeaderLen || len(ip) < ihl+udpHeaderLen || ip[9] != protoUDP {
+		return offer, false
+	}
+	udp := ip[ihl:]
+	if binary.BigEndian.Uint16(udp[0:2]) != serverPort || binary.BigEndian.Uint16(udp[2:4]) != clientPort {
+		return offer, false
+	}
+	msg := udp[udpHeaderLen:]
+	if len(msg) < bootpLen+len(magicCookie) || msg[0] != bootReply ||
+		binary.BigEndian.Uint32(msg[4:8]) != xid || string(msg[bootpLen:bootpLen+4]) != string(magicCookie) {
+		return offer, false
+	}
+
+	offer.ServerMAC = append(net.HardwareAddr(nil), frame[6:12]...)
+	offer.Server = net.IP(append([]byte(nil), ip[12:16]...))
+	offer.OfferedIP = net.IP(append([]byte(nil), msg[16:20]...))
+
+	messageType := 0
+	options := msg[bootpLen+4:]
+	for i := 0; i < len(options); {
+		code := options[i]
+		if code == optEnd {
+			break
+		}
+		if code == optPad {
+			i++
+			continue
+		}
+		if i+1 >= len(options) || i+2+int(options[i+1]) > len(options) {
+			return offer, false
+		}
+		value := options[i+2 : i+2+int(options[i+1])]
+		i += 2 + len(value)
+
+		switch code {
+		case optMessageType:
+			if len(value) == 1 {
+				messageType = int(value[0])
+			}
+		case optServerID:
+			if len(value) == 4 {
+				offer.Server = net.IP(append([]byte(nil), value...))
+			}
+		case optSubnetMask:
+			if len(value) == 4 {
+				offer.SubnetMask = net.IPMask(append([]byte(nil), value...))
+			}
+		case optRouter:
+			offer.Gateways = addresses(value)
+		case optDNS:
+			offer.DNS = addresses(value)
+		case optDomainName:
+			offer.DomainName = string(value)
+		case optLeaseTime:
+			if len(value) == 4 {
+				offer.LeaseTime = time.Duration(binary.BigEndian.Uint32(value)) * time.Second
+			}
+		}
+	}
+
+	return offer, messageType == msgOffer
+}
+
+// addresses splits an option value into IPv4 addresses
+func addresses(value []byte) []net.IP {
+	var ips []net.IP
+	for i := 0; i+4 <= len(value); i += 4 {
+		ips = append(ips, net.IP(append([]byte(nil), value[i:i+4]...)))
+	}
+	return ips
+}
+
+// udpFrame wraps a UDP payload in IPv4 and Ethernet headers. The UDP
+// checksum is left zero, which IPv4 allows.
+func udpFrame(srcMAC, dstMAC net.HardwareAddr, srcIP, dstIP net.IP, srcPort, dstPort uint16, payload []byte) []byte {
+	frame := make([]byte, ethHeaderLen+ipHeaderLen+udpHeaderLen+len(payload))
+
+	copy(frame[0:6], dstMAC)
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+
+	ip := frame[ethHeaderLen:]
+	ip[0] = 0x45 // version 4, 20 byte header
+	binary.BigEndian.PutUint16(ip[2:4], uint16(ipHeaderLen+udpHeaderLen+len(payload)))
+	ip[8] = 64 // TTL
+	ip[9] = protoUDP
+	copy(ip[12:16], srcIP.To4())
+	copy(ip[16:20], dstIP.To4())
+	binary.BigEndian.PutUint16(ip[10:12], checksum(ip[:ipHeaderLen]))
+
+	udp := ip[ipHeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(payload)))
+	copy(udp[udpHeaderLen:], payload)
+	return frame
+}
+
+// checksum computes the Internet checksum of an IPv4 header
+func checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i : i+2]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,87 @@
+package dhcpprobe
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0xaa, 0xbb, 0xcc}
+	frame := Discover(mac, 0x12345678)
+
+	if got, want := len(frame), ethHeaderLen+ipHeaderLen+udpHeaderLen+minDHCPLen; got != want {
+		t.Fatalf("frame length = %d, want %d", got, want)
+	}
+	ip := frame[ethHeaderLen:]
+	if checksum(ip[:ipHeaderLen]) != 0 {
+		t.Error("IPv4 header checksum does not verify")
+	}
+	msg := ip[ipHeaderLen+udpHeaderLen:]
+	if msg[0] != bootRequest || binary.BigEndian.Uint32(msg[4:8]) != 0x12345678 || !reflect.DeepEqual(net.HardwareAddr(msg[28:34]), mac) {
+		t.Errorf("BOOTP header = % x", msg[:44])
+	}
+	if got := msg[bootpLen+4 : bootpLen+7]; !reflect.DeepEqual(got, []byte{optMessageType, 1, msgDiscover}) {
+		t.Errorf("first option = % x, want DHCPDISCOVER", got)
+	}
+}
+
+func TestParseOffer(t *testing.T) {
+	serverMAC := net.HardwareAddr{0xde, 0xad, 0xbe, 0xef, 0x00, 0x01}
+	options := []byte{
+		optMessageType, 1, msgOffer,
+		optServerID, 4, 10, 0, 0, 1,
+		optSubnetMask, 4, 255, 255, 255, 0,
+		optRouter, 4, 10, 0, 0, 254,
+		optDNS, 8, 1, 1, 1, 1, 8, 8, 8, 8,
+		optDomainName, 5, 'g', 'u', 'e', 's', 't',
+		optLeaseTime, 4, 0, 0, 0x0e, 0x10,
+		optEnd,
+	}
+	frame := offerFrame(serverMAC, 0xcafe, options)
+
+	offer, ok := ParseOffer(frame, 0xcafe)
+	if !ok {
+		t.Fatal("ParseOffer() rejected a valid offer")
+	}
+	want := Offer{
+		Server:     net.IPv4(10, 0, 0, 1).To4(),
+		ServerMAC:  serverMAC,
+		OfferedIP:  net.IPv4(10, 0, 0, 50).To4(),
+		SubnetMask: net.IPv4Mask(255, 255, 255, 0),
+		Gateways:   []net.IP{net.IPv4(10, 0, 0, 254).To4()},
+		DNS:        []net.IP{net.IPv4(1, 1, 1, 1).To4(), net.IPv4(8, 8, 8, 8).To4()},
+		DomainName: "guest",
+		LeaseTime:  time.Hour,
+	}
+	if !reflect.DeepEqual(offer, want) {
+		t.Errorf("offer = %+v, want %+v", offer, want)
+	}
+
+	if _, ok := ParseOffer(frame, 0xbeef); ok {
+		t.Error("ParseOffer() accepted another transaction")
+	}
+	if _, ok := ParseOffer(offerFrame(serverMAC, 0xcafe, []byte{optMessageType, 1, 5, optEnd}), 0xcafe); ok {
+		t.Error("ParseOffer() accepted a DHCPACK")
+	}
+	if _, ok := ParseOffer(offerFrame(serverMAC, 0xcafe, []byte{optMessageType, 1, msgOffer, optRouter, 8, 10}), 0xcafe); ok {
+		t.Error("ParseOffer() accepted a truncated option")
+	}
+}
+
+// offerFrame builds a broadcast server reply offering 10.0.0.50
+func offerFrame(serverMAC net.HardwareAddr, xid uint32, options []byte) []byte {
+	msg := make([]byte, bootpLen)
+	msg[0] = bootReply
+	msg[1] = 1
+	msg[2] = 6
+	binary.BigEndian.PutUint32(msg[4:8], xid)
+	copy(msg[16:20], net.IPv4(10, 0, 0, 50).To4())
+	msg = append(msg, magicCookie...)
+	msg = append(msg, options...)
+
+	broadcast := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	return udpFrame(serverMAC, broadcast, net.IPv4(10, 0, 0, 1), net.IPv4bcast, serverPort, clientPort, msg)
+}
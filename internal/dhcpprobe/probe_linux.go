@@ -0,0 +1,103 @@
+//go:build linux
+
+package dhcpprobe
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/rawsock"
+)
+
+// readTimeout bounds each receive so the probe notices its deadline
+const readTimeout = 100 * time.Millisecond
+
+// Probe broadcasts a DHCPDISCOVER through the named interface and returns
+// every offer received before the timeout, one per server. It needs
+// CAP_NET_RAW.
+func Probe(ifaceName string, opts Options) ([]Offer, error) {
+	opts = opts.withDefaults()
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("%s is not an Ethernet interface", ifaceName)
+	}
+
+	var id [4]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	xid := binary.BigEndian.Uint32(id[:])
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(rawsock.Htons(etherTypeIPv4)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	link := &syscall.SockaddrLinklayer{Protocol: rawsock.Htons(etherTypeIPv4), Ifindex: iface.Index}
+	if err := syscall.Bind(fd, link); err != nil {
+		return nil, fmt.Errorf("failed to bind raw socket to %s: %v", ifaceName, err)
+	}
+	timeout := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return nil, err
+	}
+
+	broadcast := &syscall.SockaddrLinklayer{
+		Protocol: rawsock.Htons(etherTypeIPv4),
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	sent := time.Now()
+	if err := syscall.Sendto(fd, Discover(iface.HardwareAddr, xid), 0, broadcast); err != nil {
+		return nil, fmt.Errorf("failed to send DHCPDISCOVER: %v", err)
+	}
+
+	var offers []Offer
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+	deadline := sent.Add(opts.Timeout)
+
+	for time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return offers, fmt.Errorf("failed to read DHCP offer: %v", err)
+		}
+
+		offer, ok := ParseOffer(buf[:n], xid)
+		if !ok {
+			continue
+		}
+		key := offer.Server.String() + " " + offer.ServerMAC.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		offer.ResponseTime = time.Since(sent)
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+// Check reports whether this process may open the raw socket Probe needs
+func Check() error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(rawsock.Htons(etherTypeIPv4)))
+	if err != nil {
+		return fmt.Errorf("failed to open raw socket: %v", err)
+	}
+	return syscall.Close(fd)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package dhcpprobe
+
+// Probe is only implemented on Linux
+func Probe(ifaceName string, opts Options) ([]Offer, error) {
+	return nil, ErrUnsupported
+}
+
+// Check reports that probing is unsupported
+func Check() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package dot11
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/rawsock"
+)
+
+// Capture constants
+const (
+	etherTypeAll   = 0x0003 // ETH_P_ALL
+	arphrdRadiotap = "803"  // ARPHRD_IEEE80211_RADIOTAP in /sys/class/net/<iface>/type
+	readTimeout    = 100 * time.Millisecond
+	maxFrameLength = 4096
+)
+
+// Capture reads the management frames arriving on a monitor-mode interface
+// for the given duration. It needs CAP_NET_RAW.
+func Capture(ifaceName string, duration time.Duration) ([]Frame, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	linkType, err := os.ReadFile("/sys/class/net/" + ifaceName + "/type")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(linkType)) != arphrdRadiotap {
+		return nil, fmt.Errorf("%s is not in monitor mode", ifaceName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(rawsock.Htons(etherTypeAll)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raw socket: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	link := &syscall.SockaddrLinklayer{Protocol: rawsock.Htons(etherTypeAll), Ifindex: iface.Index}
+	if err := syscall.Bind(fd, link); err != nil {
+		return nil, fmt.Errorf("failed to bind raw socket to %s: %v", ifaceName, err)
+	}
+	timeout := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return nil, err
+	}
+
+	var frames []Frame
+	buf := make([]byte, maxFrameLength)
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return frames, fmt.Errorf("failed to read frame: %v", err)
+		}
+		if frame, ok := ParseRadiotap(buf[:n], time.Now()); ok {
+			frames = append(frames, frame)
+		}
+	}
+	return frames, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package dot11
+
+import "time"
+
+// Capture is only implemented on Linux
+func Capture(ifaceName string, duration time.Duration) ([]Frame, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,220 @@
+// Package dot11 decodes 802.11 management frames captured on a monitor-mode
+// interface, as radiotap-prefixed frames from a raw socket or a pcap file.
+// Only the frames that matter for attack detection are decoded: beacons,
+// probe requests and responses, deauthentications and disassociations.
+package dot11
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms without raw packet sockets
+var ErrUnsupported = errors.New("802.11 capture is not supported on this platform")
+
+// Subtype is the subtype of a management frame
+type Subtype int
+
+// Management frame subtypes
+const (
+	ProbeRequest     Subtype = 4
+	ProbeResponse    Subtype = 5
+	Beacon           Subtype = 8
+	Disassociation   Subtype = 10
+	Deauthentication Subtype = 12
+)
+
+func (s Subtype) String() string {
+	switch s {
+	case ProbeRequest:
+		return "probe request"
+	case ProbeResponse:
+		return "probe response"
+	case Beacon:
+		return "beacon"
+	case Disassociation:
+		return "disassociation"
+	case Deauthentication:
+		return "deauthentication"
+	}
+	return fmt.Sprintf("subtype %d", int(s))
+}
+
+// Broadcast is the destination of frames sent to every station
+const Broadcast = "FF:FF:FF:FF:FF:FF"
+
+// 802.11 and radiotap layout
+const (
+	headerLength     = 24 // frame control, duration, three addresses, sequence
+	fixedBeaconBytes = 12 // timestamp, beacon interval, capabilities
+	elementSSID      = 0
+	radiotapFlagsFCS = 0x10 // frame ends with a 4 byte frame check sequence
+)
+
+// Frame is a decoded management frame
+type Frame struct {
+	Time        time.Time
+	Subtype     Subtype
+	Destination string // upper-case MAC address
+	Source      string
+	BSSID       string
+	Reason      uint16 // reason code of a deauthentication or disassociation
+	SSID        string // of a beacon or probe
+	Signal      int    // dBm, 0 when the radiotap header carries none
+	Frequency   int    // MHz, 0 when the radiotap header carries none
+}
+
+// radiotapInfo holds the radiotap fields the decoder uses
+type radiotapInfo struct {
+	length    int
+	flags     byte
+	frequency int
+	signal    int
+}
+
+// radiotapFields gives the alignment and size of the radiotap fields up to
+// the antenna signal, indexed by their presence bit
+var radiotapFields = []struct{ align, size int }{
+	{8, 8}, // TSFT
+	{1, 1}, // flags
+	{1, 1}, // rate
+	{2, 4}, // channel: frequency and flags
+	{2, 2}, // FHSS
+	{1, 1}, // antenna signal in dBm
+}
+
+// ParseRadiotap decodes a radiotap-prefixed 802.11 frame. ok is false for
+// anything but one of the management frames this package knows.
+func ParseRadiotap(data []byte, at time.Time) (Frame, bool) {
+	info, err := parseRadiotap(data)
+	if err != nil {
+		return Frame{}, false
+	}
+	body := data[info.length:]
+	if info.flags&radiotapFlagsFCS != 0 {
+		if len(body) < 4 {
+			return Frame{}, false
+		}
+		body = body[:len(body)-4]
+	}
+
+	frame, ok := ParseFrame(body, at)
+	if !ok {
+		return Frame{}, false
+	}
+	frame.Signal = info.signal
+	frame.Frequency = info.frequency
+	return frame, true
+}
+
+func parseRadiotap(data []byte) (radiotapInfo, error) {
+	var info radiotapInfo
+	if len(data) < 8 || data[0] != 0 {
+		return info, errors.New("not a radiotap header")
+	}
+	info.length = int(binary.LittleEndian.Uint16(data[2:4]))
+	if info.length < 8 || info.length > len(data) {
+		return info, errors.New("invalid radiotap length")
+	}
+
+	// The present words chain while bit 31 is set; the fields start after
+	// the last one. Only the first word's fields are decoded.
+	present := binary.LittleEndian.Uint32(data[4:8])
+	offset := 8
+	for word := present; word&(1<<31) != 0; {
+		if offset+4 > info.length {
+			return info, errors.New("truncated radiotap presence bitmap")
+		}
+		word = binary.LittleEndian.Uint32(data[offset : offset+4])
+		offset += 4
+	}
+
+	for bit, field := range radiotapFields {
+		if present&(1<<uint(bit)) == 0 {
+			continue
+		}
+		// Fields are aligned to their natural size from the header start
+		offset = (offset + field.align - 1) / field.align * field.align
+		if offset+field.size > info.length {
+			return info, errors.New("truncated radiotap field")
+		}
+		switch bit {
+		case 1:
+			info.flags = data[offset]
+		case 3:
+			info.frequency = int(binary.LittleEndian.Uint16(data[offset : offset+2]))
+		case 5:
+			info.signal = int(int8(data[offset]))
+		}
+		offset += field.size
+	}
+	return info, nil
+}
+
+// ParseFrame decodes an 802.11 frame without radiotap header or FCS
+func ParseFrame(data []byte, at time.Time) (Frame, bool) {
+	if len(data) < headerLength {
+		return Frame{}, false
+	}
+	control := data[0]
+	if control&0x03 != 0 || (control>>2)&0x03 != 0 { // version 0, management type
+		return Frame{}, false
+	}
+
+	frame := Frame{
+		Time:        at,
+		Subtype:     Subtype(control >> 4),
+		Destination: address(data[4:10]),
+		Source:      address(data[10:16]),
+		BSSID:       address(data[16:22]),
+	}
+	body := data[headerLength:]
+
+	switch frame.Subtype {
+	case Deauthentication, Disassociation:
+		if len(body) < 2 {
+			return Frame{}, false
+		}
+		frame.Reason = binary.LittleEndian.Uint16(body[0:2])
+	case Beacon, ProbeResponse:
+		if len(body) < fixedBeaconBytes {
+			return Frame{}, false
+		}
+		frame.SSID = ssid(body[fixedBeaconBytes:])
+	case ProbeRequest:
+		frame.SSID = ssid(body)
+	default:
+		return Frame{}, false
+	}
+	return frame, true
+}
+
+// ssid returns the SSID element of a list of information elements
+func ssid(elements []byte) string {
+	for len(elements) >= 2 {
+		id, length := elements[0], int(elements[1])
+		if 2+length > len(elements) {
+			return ""
+		}
+		if id == elementSSID {
+			value := elements[2 : 2+length]
+			for _, b := range value {
+				if b != 0 {
+					return string(value)
+				}
+			}
+			return "" // hidden networks send zeros
+		}
+		elements = elements[2+length:]
+	}
+	return ""
+}
+
+// address formats a MAC address the way the scanners report them
+func address(b []byte) string {
+	return strings.ToUpper(net.HardwareAddr(b).String())
+}
@@ -0,0 +1,67 @@
+package dot11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestReadPcapFile(t *testing.T) {
+	frames, err := ReadPcapFile("testdata/deauth_flood.pcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 35 management frames; the data frame is skipped
+	if len(frames) != 35 {
+		t.Fatalf("got %d frames, want 35", len(frames))
+	}
+
+	beacon := frames[0]
+	if beacon.Subtype != Beacon || beacon.BSSID != "3C:84:6A:12:34:56" || beacon.SSID != "SmithHome" ||
+		beacon.Signal != -48 || beacon.Frequency != 2437 || !beacon.Time.Equal(time.Unix(1714564800, 0)) {
+		t.Errorf("beacon = %+v", beacon)
+	}
+
+	probe := frames[1]
+	if probe.Subtype != ProbeRequest || probe.Source != "AA:BB:CC:00:00:02" || probe.SSID != "SmithHome" || probe.Signal != -60 {
+		t.Errorf("probe = %+v", probe)
+	}
+
+	// Sent with an FCS, which must not end up in the reason code
+	deauth := frames[2]
+	if deauth.Subtype != Deauthentication || deauth.Destination != "AA:BB:CC:00:00:01" || deauth.Source != "3C:84:6A:12:34:56" ||
+		deauth.Reason != 7 || !deauth.Time.Equal(time.Unix(1714564801, 0)) {
+		t.Errorf("deauth = %+v", deauth)
+	}
+
+	broadcast := frames[14]
+	if broadcast.Subtype != Deauthentication || broadcast.Destination != Broadcast {
+		t.Errorf("broadcast deauth = %+v", broadcast)
+	}
+
+	disassoc := frames[34]
+	if disassoc.Subtype != Disassociation || disassoc.Source != "AA:BB:CC:00:00:02" || disassoc.Reason != 8 {
+		t.Errorf("disassociation = %+v", disassoc)
+	}
+}
+
+func TestReadPcapRejectsOtherLinkTypes(t *testing.T) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint32(header[20:24], 1) // Ethernet
+	if _, err := ReadPcap(bytes.NewReader(header)); err == nil {
+		t.Error("ReadPcap() accepted an Ethernet capture")
+	}
+}
+
+func TestParseFrameHiddenSSID(t *testing.T) {
+	frame := make([]byte, headerLength+fixedBeaconBytes)
+	frame[0] = byte(Beacon) << 4
+	frame = append(frame, elementSSID, 4, 0, 0, 0, 0)
+
+	parsed, ok := ParseFrame(frame, time.Time{})
+	if !ok || parsed.Subtype != Beacon || parsed.SSID != "" {
+		t.Errorf("ParseFrame() = %+v, %v", parsed, ok)
+	}
+}
@@ -0,0 +1,106 @@
+package dot11
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Link types of the pcap files ReadPcap understands
+const (
+	linkTypeIEEE80211         = 105 // bare 802.11 frames
+	linkTypeIEEE80211Radiotap = 127 // radiotap header, then the 802.11 frame
+)
+
+// maxPcapRecord bounds a single captured packet
+const maxPcapRecord = 262144
+
+// ReadPcapFile reads the management frames of a pcap file
+func ReadPcapFile(path string) ([]Frame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	frames, err := ReadPcap(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return frames, nil
+}
+
+// ReadPcap reads the management frames of a classic pcap stream (not pcapng)
+// captured with 802.11 or radiotap link headers, as tcpdump -i wlan0mon -w or
+// airodump-ng's .cap files write them
+func ReadPcap(r io.Reader) ([]Frame, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("missing pcap header: %v", err)
+	}
+
+	var order binary.ByteOrder
+	nanoseconds := false
+	switch {
+	case binary.LittleEndian.Uint32(header[0:4]) == 0xa1b2c3d4:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[0:4]) == 0xa1b2c3d4:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header[0:4]) == 0xa1b23c4d:
+		order, nanoseconds = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header[0:4]) == 0xa1b23c4d:
+		order, nanoseconds = binary.BigEndian, true
+	default:
+		return nil, errors.New("not a pcap file")
+	}
+
+	linkType := order.Uint32(header[20:24]) & 0x0fffffff
+	if linkType != linkTypeIEEE80211 && linkType != linkTypeIEEE80211Radiotap {
+		return nil, fmt.Errorf("unsupported link type %d, want 802.11 or radiotap", linkType)
+	}
+
+	var frames []Frame
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(br, record); err != nil {
+			if err == io.EOF {
+				return frames, nil
+			}
+			return frames, fmt.Errorf("truncated pcap record: %v", err)
+		}
+
+		seconds := int64(order.Uint32(record[0:4]))
+		fraction := int64(order.Uint32(record[4:8]))
+		length := order.Uint32(record[8:12])
+		if length > maxPcapRecord {
+			return frames, fmt.Errorf("pcap record of %d bytes", length)
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return frames, fmt.Errorf("truncated pcap record: %v", err)
+		}
+
+		if !nanoseconds {
+			fraction *= int64(time.Microsecond)
+		}
+		at := time.Unix(seconds, fraction)
+
+		var frame Frame
+		var ok bool
+		if linkType == linkTypeIEEE80211Radiotap {
+			frame, ok = ParseRadiotap(data, at)
+		} else {
+			frame, ok = ParseFrame(data, at)
+		}
+		if ok {
+			frames = append(frames, frame)
+		}
+	}
+}
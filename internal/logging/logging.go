@@ -192,6 +192,6 @@ func (c *ConsoleLogger) DisplayMenu() {
 		models.ColorGreen, models.ColorReset)
 	fmt.Printf("  7. %sExit%s\n",
 		models.ColorRed, models.ColorReset)
-	fmt.Printf("%s==========================================%s\n",
+	fmt.Printf("%s%s==========================================%s\n",
 		models.ColorPurple, models.ColorBold, models.ColorReset)
 }
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,6 +43,20 @@ func (s Severity) String() string {
 	}
 }
 
+// ParseSeverity parses a severity name such as "high" (case-insensitive)
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "LOW":
+		return SeverityLow, nil
+	case "MEDIUM":
+		return SeverityMedium, nil
+	case "HIGH":
+		return SeverityHigh, nil
+	default:
+		return SeverityLow, fmt.Errorf("unknown severity %q", s)
+	}
+}
+
 // Attack represents a detected security threat
 type Attack struct {
 	Type       string    `json:"type"`
@@ -52,11 +68,53 @@ type Attack struct {
 
 // NetworkDevice represents a device on the network
 type NetworkDevice struct {
-	IP     string `json:"ip"`
-	MAC    string `json:"mac,omitempty"`
-	Name   string `json:"name,omitempty"`
-	State  string `json:"state,omitempty"`
-	Ports  []Port `json:"ports,omitempty"`
+	IP                  string        `json:"ip"`
+	MAC                 string        `json:"mac,omitempty"`
+	Name                string        `json:"name,omitempty"`
+	Vendor              string        `json:"vendor,omitempty"`
+	LocallyAdministered bool          `json:"locally_administered,omitempty"` // randomized or virtual MAC
+	OS                  string        `json:"os,omitempty"`                   // best operating system guess
+	State               string        `json:"state,omitempty"`
+	Ports               []Port        `json:"ports,omitempty"`
+	Interface           string        `json:"interface,omitempty"` // local interface the device was found through
+	Subnet              string        `json:"subnet,omitempty"`    // scanned subnet, e.g. 192.168.1.0/24
+	Latency             time.Duration `json:"latency,omitempty"`   // discovery response time, if measured
+}
+
+// ARPBinding is an IP-to-MAC mapping seen on the local network
+type ARPBinding struct {
+	IP        string    `json:"ip"`
+	MAC       string    `json:"mac"`
+	Interface string    `json:"interface,omitempty"`
+	Source    string    `json:"source"` // "arp_sweep" or "arp_table"
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// DHCPOffer is an answer from a DHCP server to a discovery probe
+type DHCPOffer struct {
+	Server     string   `json:"server"`
+	ServerMAC  string   `json:"server_mac,omitempty"`
+	Interface  string   `json:"interface"`
+	OfferedIP  string   `json:"offered_ip,omitempty"`
+	Gateways   []string `json:"gateways,omitempty"`
+	DNS        []string `json:"dns,omitempty"`
+	DomainName string   `json:"domain_name,omitempty"`
+}
+
+// DNSAnswer is the answer of one resolver to a canary lookup
+type DNSAnswer struct {
+	Resolver  string   `json:"resolver"` // "system" or the pinned resolver address
+	Host      string   `json:"host"`
+	Addresses []string `json:"addresses,omitempty"`
+	NXDomain  bool     `json:"nxdomain,omitempty"`
+	NXCheck   bool     `json:"nx_check,omitempty"` // the host is a name that should not exist
+	Error     string   `json:"error,omitempty"`
+}
+
+// DNSReport is the result of one round of DNS canary lookups
+type DNSReport struct {
+	Nameservers []string    `json:"nameservers"` // nil when resolv.conf is unreadable
+	Answers     []DNSAnswer `json:"answers"`
 }
 
 // Port represents an open port on a device
@@ -64,24 +122,171 @@ type Port struct {
 	Number   int    `json:"number"`
 	Protocol string `json:"protocol"`
 	Service  string `json:"service"`
+	Version  string `json:"version,omitempty"` // product and version, if identified
 	State    string `json:"state"`
 }
 
 // BluetoothDevice represents a Bluetooth device
 type BluetoothDevice struct {
-	Address string `json:"address"`
-	Name    string `json:"name,omitempty"`
-	RSSI    int    `json:"rssi,omitempty"`
-	Status  string `json:"status"`
+	Address             string `json:"address"`
+	Name                string `json:"name,omitempty"`
+	Vendor              string `json:"vendor,omitempty"`
+	LocallyAdministered bool   `json:"locally_administered,omitempty"` // random address
+	RSSI                int    `json:"rssi,omitempty"`
+	Status              string `json:"status"`
 }
 
 // WiFiDevice represents a WiFi access point or device
 type WiFiDevice struct {
-	Address string `json:"address"`
-	SSID    string `json:"ssid,omitempty"`
-	Signal  string `json:"signal,omitempty"`
-	Channel string `json:"channel,omitempty"`
-	Status  string `json:"status"`
+	Address             string        `json:"address"` // BSSID of the access point
+	SSID                string        `json:"ssid,omitempty"`
+	Vendor              string        `json:"vendor,omitempty"`
+	LocallyAdministered bool          `json:"locally_administered,omitempty"` // randomized or virtual BSSID
+	Signal              string        `json:"signal,omitempty"`
+	Channel             string        `json:"channel,omitempty"`
+	Frequency           int           `json:"frequency,omitempty"` // MHz
+	Status              string        `json:"status"`
+	Security            *WiFiSecurity `json:"security,omitempty"` // nil when the scan did not report it
+	Power               int           `json:"power,omitempty"`    // dBm
+	Beacons             int           `json:"beacons,omitempty"`
+	Clients             []string      `json:"clients,omitempty"`   // MAC addresses of associated stations
+	LastSeen            *time.Time    `json:"last_seen,omitempty"` // nil when the scan did not report it
+}
+
+// SignalDBm returns the signal strength in dBm, if the scan reported it
+func (d WiFiDevice) SignalDBm() (int, bool) {
+	if d.Power < 0 {
+		return d.Power, true
+	}
+	var dbm int
+	if _, err := fmt.Sscanf(d.Signal, "%d dBm", &dbm); err != nil {
+		return 0, false
+	}
+	return dbm, true
+}
+
+// WiFiSecurity describes the encryption an access point advertises
+type WiFiSecurity struct {
+	Encrypted       bool     `json:"encrypted"`
+	Protocols       []string `json:"protocols,omitempty"`        // "WEP", "WPA", "WPA2", "WPA3"
+	GroupCipher     string   `json:"group_cipher,omitempty"`     // e.g. "CCMP" or "TKIP"
+	PairwiseCiphers []string `json:"pairwise_ciphers,omitempty"` // e.g. "CCMP", "GCMP-256", "TKIP"
+	AuthSuites      []string `json:"auth_suites,omitempty"`      // e.g. "PSK", "SAE", "802.1X", "OWE"
+}
+
+// HasProtocol reports whether the access point offers a protocol
+func (s WiFiSecurity) HasProtocol(protocol string) bool {
+	for _, p := range s.Protocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reports whether the network has no encryption at all
+func (s WiFiSecurity) Open() bool {
+	return !s.Encrypted
+}
+
+// WEP reports whether the network is protected by WEP only
+func (s WiFiSecurity) WEP() bool {
+	return s.Encrypted && len(s.Protocols) == 1 && s.Protocols[0] == "WEP"
+}
+
+// TKIPOnly reports whether WPA clients can only use TKIP for unicast traffic
+func (s WiFiSecurity) TKIPOnly() bool {
+	if len(s.PairwiseCiphers) == 0 {
+		return false
+	}
+	for _, cipher := range s.PairwiseCiphers {
+		if cipher != "TKIP" {
+			return false
+		}
+	}
+	return true
+}
+
+// Transition reports whether a WPA3 network also accepts WPA2 pre-shared
+// keys, which lets an attacker force clients down to WPA2
+func (s WiFiSecurity) Transition() bool {
+	return s.HasProtocol("WPA3") && s.HasProtocol("WPA2")
+}
+
+// Weaker reports whether s offers clients less protection than trusted: an
+// older protocol (including a WPA2 fallback of a WPA3 network), TKIP as the
+// only cipher, or pre-shared keys where the trusted network used 802.1X
+func (s WiFiSecurity) Weaker(trusted WiFiSecurity) bool {
+	if s.strength() < trusted.strength() {
+		return true
+	}
+	if s.TKIPOnly() && !trusted.TKIPOnly() {
+		return true
+	}
+	return trusted.hasAuthSuite("802.1X") && len(s.AuthSuites) > 0 && !s.hasAuthSuite("802.1X")
+}
+
+// strength ranks the weakest protocol the network accepts, from 0 for an
+// open network to 4 for WPA3 only
+func (s WiFiSecurity) strength() int {
+	if !s.Encrypted {
+		return 0
+	}
+	ranks := map[string]int{"WEP": 1, "WPA": 2, "WPA2": 3, "WPA3": 4}
+	weakest := 0
+	for _, protocol := range s.Protocols {
+		if rank, ok := ranks[protocol]; ok && (weakest == 0 || rank < weakest) {
+			weakest = rank
+		}
+	}
+	if weakest == 0 {
+		return 1 // encrypted with an unknown protocol is treated like WEP
+	}
+	return weakest
+}
+
+func (s WiFiSecurity) hasAuthSuite(suite string) bool {
+	for _, name := range s.AuthSuites {
+		if name == suite {
+			return true
+		}
+	}
+	return false
+}
+
+// String summarizes the security, e.g. "WPA2 WPA3 (SAE PSK, CCMP)" or "Open"
+func (s WiFiSecurity) String() string {
+	if !s.Encrypted {
+		return "Open"
+	}
+	summary := "Encrypted"
+	if len(s.Protocols) > 0 {
+		summary = strings.Join(s.Protocols, " ")
+	}
+	var details []string
+	if len(s.AuthSuites) > 0 {
+		details = append(details, strings.Join(s.AuthSuites, " "))
+	}
+	if len(s.PairwiseCiphers) > 0 {
+		details = append(details, strings.Join(s.PairwiseCiphers, " "))
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+	return summary
+}
+
+// TrustedNetwork is an SSID whose access points the operator approved. An
+// access point advertising the SSID must match it, or it is a possible evil
+// twin.
+type TrustedNetwork struct {
+	SSID      string         `json:"ssid"`
+	BSSIDs    []string       `json:"bssids,omitempty"`
+	OUIs      []string       `json:"ouis,omitempty"`     // e.g. "3C:84:6A"; any BSSID with the prefix is approved
+	Channels  []string       `json:"channels,omitempty"` // empty allows every channel
+	Security  *WiFiSecurity  `json:"security,omitempty"` // weakest security accepted; nil skips the check
+	Signals   map[string]int `json:"signals,omitempty"`  // BSSID -> dBm when the access point was trusted
+	TrustedAt time.Time      `json:"trusted_at"`
 }
 
 // KnownDevices contains lists of known/authorized devices
@@ -100,14 +305,55 @@ type ScanResult struct {
 	Error     string         `json:"error,omitempty"`
 }
 
+// ScannerHealth reports whether a scanner is able to run on this host
+type ScannerHealth struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Message   string `json:"message,omitempty"`
+}
+
 // AttackDetectorConfig represents configuration for the attack detector
 type AttackDetectorConfig struct {
-	KnownDevicesFile        string        `json:"known_devices_file"`
-	BluetoothDevicesFile    string        `json:"bluetooth_devices_file"`
-	LogFile                 string        `json:"log_file"`
-	ScanInterval            time.Duration `json:"scan_interval"`
-	AnomalyThreshold        float64       `json:"anomaly_threshold"`
-	WebServerPort           int           `json:"web_server_port"`
+	KnownDevicesFile        string         `json:"known_devices_file"`
+	DHCPServersFile         string         `json:"dhcp_servers_file"` // authorized DHCP server IPs and MACs
+	BluetoothDevicesFile    string         `json:"bluetooth_devices_file"`
+	LogFile                 string         `json:"log_file"`
+	AttackStoreFile         string         `json:"attack_store_file"`
+	AttackRetention         time.Duration  `json:"attack_retention"`
+	MaxStoredAttacks        int            `json:"max_stored_attacks"`
+	BlockStateFile          string         `json:"block_state_file"`
+	AutoBlockTTLs           []BlockTTLRule `json:"auto_block_ttls"`
+	FirewallBackend         string         `json:"firewall_backend"`
+	NftablesRulesetFile     string         `json:"nftables_ruleset_file"`
+	BlockerDryRun           bool           `json:"blocker_dry_run"`
+	SimulationLogFile       string         `json:"simulation_log_file"`
+	ProtectedTargetsFile    string         `json:"protected_targets_file"`
+	RulesFile               string         `json:"rules_file"`
+	NetworkIncludeCIDRs     []string       `json:"network_include_cidrs"` // empty scans every local subnet
+	NetworkExcludeCIDRs     []string       `json:"network_exclude_cidrs"`
+	PortScanBackend         string         `json:"port_scan_backend"`   // "builtin" or "nmap"
+	PortScanTCPPorts        string         `json:"port_scan_tcp_ports"` // e.g. "22,80,8000-8100"
+	PortScanUDPPorts        string         `json:"port_scan_udp_ports"`
+	PortScanTimeout         time.Duration  `json:"port_scan_timeout"`
+	PortScanConcurrency     int            `json:"port_scan_concurrency"`
+	PortScanRate            int            `json:"port_scan_rate"`     // probes per second
+	PortProfilesFile        string         `json:"port_profiles_file"` // ports each known device may open without an alert
+	PortBaselineFile        string         `json:"port_baseline_file"`
+	ARPMaxIPsPerMAC         int            `json:"arp_max_ips_per_mac"` // more IPs behind one MAC raise ARP_SPOOFING
+	DNSCanaryHosts          []string       `json:"dns_canary_hosts"`
+	DNSPinnedResolvers      []string       `json:"dns_pinned_resolvers"` // IP[:port], compared with the system resolver
+	DeviceInventoryFile     string         `json:"device_inventory_file"`
+	OUIFile                 string         `json:"oui_file"`               // installed IEEE vendor registry, extends the built-in one
+	WiFiMonitorInterface    string         `json:"wifi_monitor_interface"` // empty uses the first interface in monitor mode
+	DeauthWindow            time.Duration  `json:"deauth_window"`
+	DeauthMaxPerBSSID       int            `json:"deauth_max_per_bssid"` // deauth/disassoc frames per window for one access point
+	DeauthMaxPerClient      int            `json:"deauth_max_per_client"`
+	TrustedAPsFile          string         `json:"trusted_aps_file"`
+	EvilTwinSignalDeviation int            `json:"evil_twin_signal_deviation"` // dB a trusted access point's signal may drift
+	ScanInterval            time.Duration  `json:"scan_interval"`
+	AnomalyThreshold        float64        `json:"anomaly_threshold"`
+	WebServerHost           string         `json:"web_server_host"` // listen address, "" for every interface
+	WebServerPort           int            `json:"web_server_port"`
 }
 
 // DefaultConfig returns default configuration
@@ -118,12 +364,47 @@ func DefaultConfig() *AttackDetectorConfig {
 			port = p
 		}
 	}
+	host := os.Getenv("APP_HOST")
 	return &AttackDetectorConfig{
 		KnownDevicesFile:        "model/known_devices.json",
+		DHCPServersFile:         "model/known_dhcp_servers.json",
 		BluetoothDevicesFile:    "model/known_bluetooth_devices.json",
 		LogFile:                 "log/intrusion_log.log",
+		AttackStoreFile:         "log/attacks.jsonl",
+		AttackRetention:         30 * 24 * time.Hour,
+		MaxStoredAttacks:        10000,
+		BlockStateFile:          "model/blocked_items.json",
+		AutoBlockTTLs: []BlockTTLRule{
+			{Type: "UNKNOWN_DEVICE", TTL: time.Hour},
+			{Severity: "HIGH", TTL: 24 * time.Hour},
+			{TTL: 6 * time.Hour},
+		},
+		FirewallBackend:         "auto",
+		NftablesRulesetFile:     "model/shheissee.nft",
+		SimulationLogFile:       "log/simulated_actions.jsonl",
+		ProtectedTargetsFile:    "model/protected_targets.json",
+		RulesFile:               "model/detection_rules.json",
+		PortScanBackend:         "builtin",
+		PortScanTCPPorts:        "21-23,25,53,80,110,143,443,445,993,995,3389",
+		PortScanUDPPorts:        "53,123,161",
+		PortScanTimeout:         time.Second,
+		PortScanConcurrency:     100,
+		PortScanRate:            500,
+		PortProfilesFile:        "model/port_profiles.json",
+		PortBaselineFile:        "model/port_baseline.json",
+		ARPMaxIPsPerMAC:         3,
+		DNSCanaryHosts:          []string{"one.one.one.one", "dns.google"},
+		DNSPinnedResolvers:      []string{"1.1.1.1", "9.9.9.9"},
+		DeviceInventoryFile:     "model/device_inventory.json",
+		OUIFile:                 "model/oui.csv",
+		DeauthWindow:            10 * time.Second,
+		DeauthMaxPerBSSID:       30,
+		DeauthMaxPerClient:      10,
+		TrustedAPsFile:          "model/trusted_aps.json",
+		EvilTwinSignalDeviation: 25,
 		ScanInterval:            60 * time.Second,
 		AnomalyThreshold:        2.0,
+		WebServerHost:           host,
 		WebServerPort:           port,
 	}
 }
@@ -142,6 +423,56 @@ type DeviceHistory struct {
 	RSSI      *RSSIHistory `json:"rssi,omitempty"`
 }
 
+// Device kinds in the inventory, named after the scanners that find them
+const (
+	DeviceKindNetwork   = "network"
+	DeviceKindBluetooth = "bluetooth"
+	DeviceKindWiFi      = "wifi"
+)
+
+// Device states in the inventory
+const (
+	DeviceOnline  = "online"
+	DeviceOffline = "offline"
+)
+
+// InventoryDevice is everything recorded about one device across scans
+type InventoryDevice struct {
+	Key                 string          `json:"key"` // kind/address, e.g. network/AA:BB:CC:DD:EE:FF
+	Kind                string          `json:"kind"`
+	IP                  string          `json:"ip,omitempty"`
+	MAC                 string          `json:"mac,omitempty"` // also the Bluetooth address or Wi-Fi BSSID
+	LocallyAdministered bool            `json:"locally_administered,omitempty"`
+	Hostname            string          `json:"hostname,omitempty"`
+	Vendor              string          `json:"vendor,omitempty"`
+	BTName              string          `json:"bt_name,omitempty"`
+	SSID                string          `json:"ssid,omitempty"`
+	Status              string          `json:"status"` // "online" or "offline"
+	FirstSeen           time.Time       `json:"first_seen"`
+	LastSeen            time.Time       `json:"last_seen"`
+	Ports               []InventoryPort `json:"ports,omitempty"`
+	Changes             []DeviceChange  `json:"changes,omitempty"` // oldest first
+}
+
+// InventoryPort is the history of one port of an inventory device
+type InventoryPort struct {
+	Number    int       `json:"number"`
+	Protocol  string    `json:"protocol"`
+	Service   string    `json:"service,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Open      bool      `json:"open"`
+	FirstSeen time.Time `json:"first_seen"` // first seen open
+	LastSeen  time.Time `json:"last_seen"`  // last seen open
+}
+
+// DeviceChange is a change of an inventory device between two scans
+type DeviceChange struct {
+	Time  time.Time `json:"time"`
+	Field string    `json:"field"` // e.g. "status", "ip" or "port 22/tcp"
+	From  string    `json:"from,omitempty"`
+	To    string    `json:"to,omitempty"`
+}
+
 // AnomalyDetector holds data for AI-based anomaly detection
 type AnomalyDetector struct {
 	DeviceHistory     map[string]*DeviceHistory      `json:"device_history"`
@@ -150,9 +481,97 @@ type AnomalyDetector struct {
 	AnomalyThreshold  float64                        `json:"anomaly_threshold"`
 }
 
-// BlockedItems represents currently blocked network elements
+// BlockOrigin records whether a block was requested by an operator or by auto-blocking
+type BlockOrigin string
+
+// Block origins
+const (
+	BlockOriginManual BlockOrigin = "manual"
+	BlockOriginAuto   BlockOrigin = "auto"
+)
+
+// BlockRecord describes a single active block
+type BlockRecord struct {
+	Reason    string      `json:"reason"`
+	Origin    BlockOrigin `json:"origin"`
+	BlockedAt time.Time   `json:"blocked_at"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"` // nil for permanent blocks
+}
+
+// Expired reports whether a time-limited block has run out at now
+func (r BlockRecord) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// BlockTTLRule sets how long auto-blocks for matching attacks last. Empty Type
+// or Severity match any attack, and a zero TTL makes the block permanent.
+// The first matching rule wins.
+type BlockTTLRule struct {
+	Type     string        `json:"type,omitempty"`
+	Severity string        `json:"severity,omitempty"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+// Matches reports whether the rule applies to attack
+func (r BlockTTLRule) Matches(attack Attack) bool {
+	if r.Type != "" && !strings.EqualFold(r.Type, attack.Type) {
+		return false
+	}
+	if r.Severity != "" && !strings.EqualFold(r.Severity, attack.Severity.String()) {
+		return false
+	}
+	return true
+}
+
+// BlockedItems represents currently blocked network elements, keyed by address
 type BlockedItems struct {
-	BlockedIPs     map[string]time.Time `json:"blocked_ips"`
-	BlockedMACs    map[string]time.Time `json:"blocked_macs"`
-	BlockedBTAddrs map[string]time.Time `json:"blocked_bt_addrs"`
+	BlockedIPs     map[string]BlockRecord `json:"blocked_ips"`
+	BlockedMACs    map[string]BlockRecord `json:"blocked_macs"`
+	BlockedBTAddrs map[string]BlockRecord `json:"blocked_bt_addrs"`
+}
+
+// ProtectedTargets lists targets the blocker must never block automatically.
+// Manual blocks of protected targets need an explicit override.
+type ProtectedTargets struct {
+	CIDRs                  []string `json:"cidrs"` // single addresses are allowed too
+	MACs                   []string `json:"macs"`
+	BluetoothAddrs         []string `json:"bluetooth_addrs"`
+	ProtectGateway         bool     `json:"protect_gateway"`          // default gateways and their MACs
+	ProtectNameservers     bool     `json:"protect_nameservers"`      // servers from /etc/resolv.conf
+	ProtectLocalInterfaces bool     `json:"protect_local_interfaces"` // this host's addresses and MACs
+}
+
+// DefaultProtectedTargets returns the protected targets used when none are configured
+func DefaultProtectedTargets() ProtectedTargets {
+	return ProtectedTargets{
+		CIDRs:                  []string{"127.0.0.0/8", "::1/128"},
+		MACs:                   []string{},
+		BluetoothAddrs:         []string{},
+		ProtectGateway:         true,
+		ProtectNameservers:     true,
+		ProtectLocalInterfaces: true,
+	}
+}
+
+// SimulatedAction is a blocker action recorded in dry-run mode instead of being executed
+type SimulatedAction struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"` // e.g. "block ip" or "deauth wifi client"
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason,omitempty"`
+	Commands  []string  `json:"commands"`
+	Attack    *Attack   `json:"attack,omitempty"` // nil for manual actions
+}
+
+// Block drift issues reported by reconciliation
+const (
+	DriftMissingRule   = "missing_rule"   // recorded as blocked but no rule is installed
+	DriftUnmanagedRule = "unmanaged_rule" // rule is installed but not recorded
+)
+
+// BlockDrift is a difference between the recorded blocks and the installed rules
+type BlockDrift struct {
+	Kind   string `json:"kind"` // "ip", "mac" or "bt"
+	Target string `json:"target"`
+	Issue  string `json:"issue"`
 }
@@ -0,0 +1,259 @@
+// Package netinfo reads the host's own network configuration: default
+// gateways, name servers, the ARP table and local interface addresses.
+package netinfo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Files read by this package
+const (
+	RouteFile      = "/proc/net/route"
+	IPv6RouteFile  = "/proc/net/ipv6_route"
+	ARPFile        = "/proc/net/arp"
+	ResolvConfFile = "/etc/resolv.conf"
+)
+
+// ARPEntry is a resolved neighbour from the kernel ARP table
+type ARPEntry struct {
+	IP     net.IP
+	MAC    net.HardwareAddr
+	Device string
+}
+
+// Interface is a local network interface with its addresses
+type Interface struct {
+	Name  string
+	MAC   net.HardwareAddr
+	Addrs []*net.IPNet
+}
+
+// Subnet is a network directly attached to a local interface
+type Subnet struct {
+	Interface string
+	Network   *net.IPNet
+}
+
+// IsIPv4 reports whether the subnet is an IPv4 network
+func (s Subnet) IsIPv4() bool {
+	return s.Network.IP.To4() != nil
+}
+
+// DefaultGateways returns the IPv4 and IPv6 default gateways of the host
+func DefaultGateways() ([]net.IP, error) {
+	var gateways []net.IP
+
+	ipv4, err := readFile(RouteFile, ParseRouteGateways)
+	if err != nil {
+		return nil, err
+	}
+	gateways = append(gateways, ipv4...)
+
+	// IPv6 may be disabled, in which case the file does not exist
+	ipv6, err := readFile(IPv6RouteFile, ParseIPv6RouteGateways)
+	if err != nil && !os.IsNotExist(err) {
+		return gateways, err
+	}
+	return append(gateways, ipv6...), nil
+}
+
+// Nameservers returns the name servers configured in /etc/resolv.conf
+func Nameservers() ([]net.IP, error) {
+	return readFile(ResolvConfFile, ParseNameservers)
+}
+
+// ARPTable returns the complete entries of the kernel ARP table
+func ARPTable() ([]ARPEntry, error) {
+	return readFile(ARPFile, ParseARPTable)
+}
+
+// LocalInterfaces returns the host's interfaces that are up, with their addresses
+func LocalInterfaces() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read addresses of %s: %v", iface.Name, err)
+		}
+
+		local := Interface{Name: iface.Name, MAC: iface.HardwareAddr}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				local.Addrs = append(local.Addrs, ipNet)
+			}
+		}
+		result = append(result, local)
+	}
+	return result, nil
+}
+
+// LocalSubnets returns the IPv4 and IPv6 networks of the host's interfaces.
+// Loopback and link-local networks and single-host prefixes are left out.
+func LocalSubnets() ([]Subnet, error) {
+	ifaces, err := LocalInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	return InterfaceSubnets(ifaces), nil
+}
+
+// InterfaceSubnets derives the attached networks from interface addresses
+func InterfaceSubnets(ifaces []Interface) []Subnet {
+	var subnets []Subnet
+	seen := make(map[string]bool)
+
+	for _, iface := range ifaces {
+		for _, addr := range iface.Addrs {
+			ip := addr.IP
+			if ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+
+			ones, bits := addr.Mask.Size()
+			if bits == 0 || ones == bits {
+				continue
+			}
+
+			network := &net.IPNet{IP: ip.Mask(addr.Mask), Mask: addr.Mask}
+			key := iface.Name + " " + network.String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			subnets = append(subnets, Subnet{Interface: iface.Name, Network: network})
+		}
+	}
+	return subnets
+}
+
+// ParseRouteGateways extracts default gateways from /proc/net/route, where
+// addresses are little-endian hex
+func ParseRouteGateways(r io.Reader) ([]net.IP, error) {
+	var gateways []net.IP
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+
+		// Default routes have a zero destination and mask
+		if fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil || value == 0 {
+			continue
+		}
+		gateway := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(gateway, uint32(value))
+		gateways = append(gateways, gateway)
+	}
+	return gateways, scanner.Err()
+}
+
+// ParseIPv6RouteGateways extracts default gateways from /proc/net/ipv6_route
+func ParseIPv6RouteGateways(r io.Reader) ([]net.IP, error) {
+	var gateways []net.IP
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		// destination ::/0 with a next hop
+		if strings.Trim(fields[0], "0") != "" || fields[1] != "00" {
+			continue
+		}
+		nextHop, err := hex.DecodeString(fields[4])
+		if err != nil || len(nextHop) != net.IPv6len {
+			continue
+		}
+		gateway := net.IP(nextHop)
+		if gateway.IsUnspecified() {
+			continue
+		}
+		gateways = append(gateways, gateway)
+	}
+	return gateways, scanner.Err()
+}
+
+// ParseNameservers extracts nameserver addresses from resolv.conf content
+func ParseNameservers(r io.Reader) ([]net.IP, error) {
+	var servers []net.IP
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+
+		// Link-local servers may carry a zone, e.g. fe80::1%eth0
+		address := strings.SplitN(fields[1], "%", 2)[0]
+		if ip := net.ParseIP(address); ip != nil {
+			servers = append(servers, ip)
+		}
+	}
+	return servers, scanner.Err()
+}
+
+// ParseARPTable extracts complete entries from /proc/net/arp
+func ParseARPTable(r io.Reader) ([]ARPEntry, error) {
+	var entries []ARPEntry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] == "IP" {
+			continue
+		}
+
+		// Flag 0x2 (ATF_COM) marks a resolved entry
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if err != nil || flags&0x2 == 0 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		mac, err := net.ParseMAC(fields[3])
+		if ip == nil || err != nil {
+			continue
+		}
+		entries = append(entries, ARPEntry{IP: ip, MAC: mac, Device: fields[5]})
+	}
+	return entries, scanner.Err()
+}
+
+func readFile[T any](path string, parse func(io.Reader) ([]T, error)) ([]T, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parse(file)
+}
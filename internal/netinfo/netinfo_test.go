@@ -0,0 +1,88 @@
+package netinfo
+
+import (
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func parseFixture[T any](t *testing.T, path string, parse func(io.Reader) ([]T, error)) []T {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	result, err := parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestParseRouteGateways(t *testing.T) {
+	got := parseFixture(t, "testdata/route.txt", ParseRouteGateways)
+	want := []net.IP{net.IPv4(192, 168, 1, 1).To4()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRouteGateways() = %v, want %v", got, want)
+	}
+}
+
+func TestParseIPv6RouteGateways(t *testing.T) {
+	got := parseFixture(t, "testdata/ipv6_route.txt", ParseIPv6RouteGateways)
+	if len(got) != 1 || !got[0].Equal(net.ParseIP("fe80::1")) {
+		t.Errorf("ParseIPv6RouteGateways() = %v, want [fe80::1]", got)
+	}
+}
+
+func TestParseNameservers(t *testing.T) {
+	got := parseFixture(t, "testdata/resolv.conf", ParseNameservers)
+	if len(got) != 2 || !got[0].Equal(net.ParseIP("192.168.1.1")) || !got[1].Equal(net.ParseIP("fe80::1")) {
+		t.Errorf("ParseNameservers() = %v", got)
+	}
+}
+
+func TestParseARPTable(t *testing.T) {
+	got := parseFixture(t, "testdata/arp.txt", ParseARPTable)
+	if len(got) != 2 {
+		t.Fatalf("ParseARPTable() returned %d entries, want 2 (incomplete entry skipped)", len(got))
+	}
+	if got[0].MAC.String() != "a4:2b:b0:11:22:33" || got[0].Device != "eth0" || !got[0].IP.Equal(net.ParseIP("192.168.1.1")) {
+		t.Errorf("first entry = %+v", got[0])
+	}
+}
+
+func TestInterfaceSubnets(t *testing.T) {
+	parse := func(cidr string) *net.IPNet {
+		ip, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		network.IP = ip
+		return network
+	}
+
+	ifaces := []Interface{
+		{Name: "lo", Addrs: []*net.IPNet{parse("127.0.0.1/8"), parse("::1/128")}},
+		{Name: "eth0", Addrs: []*net.IPNet{
+			parse("10.20.30.40/16"),
+			parse("2001:db8:1::25/64"),
+			parse("fe80::1c2d:3eff:fe4f:5a6b/64"),
+		}},
+		{Name: "wlan0", Addrs: []*net.IPNet{parse("192.168.1.23/24"), parse("192.168.1.24/24")}},
+		{Name: "tun0", Addrs: []*net.IPNet{parse("10.8.0.2/32")}},
+	}
+
+	var got []string
+	for _, subnet := range InterfaceSubnets(ifaces) {
+		got = append(got, subnet.Interface+" "+subnet.Network.String())
+	}
+	want := []string{"eth0 10.20.0.0/16", "eth0 2001:db8:1::/64", "wlan0 192.168.1.0/24"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InterfaceSubnets() = %v, want %v", got, want)
+	}
+}
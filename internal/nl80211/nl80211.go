@@ -0,0 +1,376 @@
+// Package nl80211 scans for WiFi access points through the kernel's nl80211
+// interface over generic netlink, without iw, iwlist or NetworkManager. It
+// triggers a scan on every wireless station interface and reads the BSS
+// entries the kernel collected, with their frequency, signal, information
+// elements and the time they were last seen.
+package nl80211
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms without nl80211
+var ErrUnsupported = errors.New("nl80211 is not supported on this platform")
+
+// Netlink and generic netlink constants
+const (
+	netlinkGeneric = 16 // NETLINK_GENERIC
+
+	nlmsgHeaderLength = 16
+	genlHeaderLength  = 4
+	attrHeaderLength  = 4
+
+	nlmsgError = 2
+	nlmsgDone  = 3
+
+	flagRequest = 0x1
+	flagMulti   = 0x2
+	flagAck     = 0x4
+	flagDump    = 0x300
+
+	attrTypeMask = 0x3fff // strips NLA_F_NESTED and NLA_F_NET_BYTEORDER
+
+	genlIDCtrl           = 0x10
+	ctrlCmdGetFamily     = 3
+	ctrlAttrFamilyID     = 1
+	ctrlAttrFamilyName   = 2
+	ctrlAttrMcastGroups  = 7
+	ctrlAttrMcastGrpName = 1
+	ctrlAttrMcastGrpID   = 2
+	familyName           = "nl80211"
+	scanMulticastGroup   = "scan"
+	genlVersion          = 1
+	netlinkAddMembership = 1     // NETLINK_ADD_MEMBERSHIP
+	solNetlink           = 270   // SOL_NETLINK
+	receiveBufferSize    = 65536 // large enough for a dump of scan results
+)
+
+// nl80211 commands, attributes and values
+const (
+	cmdGetInterface   = 5
+	cmdGetScan        = 32
+	cmdTriggerScan    = 33
+	cmdNewScanResults = 34
+	cmdScanAborted    = 35
+
+	attrIfindex   = 3
+	attrIfname    = 4
+	attrIftype    = 5
+	attrScanSSIDs = 45
+	attrBSS       = 47
+
+	bssBSSID      = 1
+	bssFrequency  = 2
+	bssCapability = 5
+	bssIEs        = 6
+	bssSignalMBm  = 7
+	bssStatus     = 9
+	bssSeenMsAgo  = 10
+	bssBeaconIEs  = 11
+
+	iftypeStation     = 2
+	bssStatusAssoc    = 1
+	capabilityPrivacy = 0x0010
+)
+
+// Information element IDs
+const (
+	ElementSSID   = 0
+	ElementRSN    = 48
+	ElementVendor = 221
+)
+
+// BSS is an access point from the kernel's scan results
+type BSS struct {
+	Interface  string    // wireless interface that saw it
+	BSSID      string    // upper-case MAC address
+	Frequency  int       // MHz
+	SignalMBm  int       // 1/100 dBm; 0 when the driver reports no dBm signal
+	Capability uint16    // capability information field of the beacon
+	IEs        []byte    // information elements of the probe response or beacon
+	LastSeen   time.Time // when a beacon or probe response last arrived
+	Associated bool      // the interface is associated with it
+}
+
+// SSID returns the network name, or "" for a hidden network
+func (b BSS) SSID() string {
+	value := b.Element(ElementSSID)
+	for _, c := range value {
+		if c != 0 {
+			return string(value)
+		}
+	}
+	return "" // hidden networks send an empty or zeroed SSID
+}
+
+// Channel returns the channel number of the frequency, or 0 if unknown
+func (b BSS) Channel() int {
+	return FrequencyToChannel(b.Frequency)
+}
+
+// Privacy reports whether the capability field requires encryption
+func (b BSS) Privacy() bool {
+	return b.Capability&capabilityPrivacy != 0
+}
+
+// Element returns the body of the first information element with id
+func (b BSS) Element(id byte) []byte {
+	var found []byte
+	walkElements(b.IEs, func(eid byte, body []byte) bool {
+		if eid == id {
+			found = body
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// VendorElement returns the body, after the OUI and type, of the first
+// vendor specific element with the given OUI and type, such as the WPA
+// element (00:50:F2, type 1)
+func (b BSS) VendorElement(oui [3]byte, kind byte) []byte {
+	var found []byte
+	walkElements(b.IEs, func(eid byte, body []byte) bool {
+		if eid == ElementVendor && len(body) >= 4 && body[0] == oui[0] && body[1] == oui[1] && body[2] == oui[2] && body[3] == kind {
+			found = body[4:]
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// walkElements calls fn for each well-formed element until it returns false
+func walkElements(ies []byte, fn func(id byte, body []byte) bool) {
+	for len(ies) >= 2 {
+		id, length := ies[0], int(ies[1])
+		if 2+length > len(ies) {
+			return
+		}
+		if !fn(id, ies[2:2+length]) {
+			return
+		}
+		ies = ies[2+length:]
+	}
+}
+
+// FrequencyToChannel converts a center frequency in MHz to its channel
+// number in the 2.4, 5, 6 and 60 GHz bands, or 0 if it is none of them
+func FrequencyToChannel(mhz int) int {
+	switch {
+	case mhz == 2484:
+		return 14
+	case mhz >= 2412 && mhz <= 2472:
+		return (mhz - 2407) / 5
+	case mhz >= 5955 && mhz <= 7115:
+		return (mhz - 5950) / 5
+	case mhz >= 5000 && mhz <= 5895:
+		return (mhz - 5000) / 5
+	case mhz >= 58320 && mhz <= 70200:
+		return (mhz - 56160) / 2160
+	}
+	return 0
+}
+
+// message is a netlink message
+type message struct {
+	Type    uint16
+	Flags   uint16
+	Seq     uint32
+	Payload []byte
+}
+
+// parseMessages splits a netlink datagram into its messages
+func parseMessages(b []byte) ([]message, error) {
+	var messages []message
+	for len(b) >= nlmsgHeaderLength {
+		length := int(binary.NativeEndian.Uint32(b[0:4]))
+		if length < nlmsgHeaderLength || length > len(b) {
+			return messages, fmt.Errorf("malformed netlink message of %d bytes", length)
+		}
+		messages = append(messages, message{
+			Type:    binary.NativeEndian.Uint16(b[4:6]),
+			Flags:   binary.NativeEndian.Uint16(b[6:8]),
+			Seq:     binary.NativeEndian.Uint32(b[8:12]),
+			Payload: b[nlmsgHeaderLength:length],
+		})
+		if align(length) >= len(b) {
+			break
+		}
+		b = b[align(length):]
+	}
+	return messages, nil
+}
+
+// encodeRequest builds a generic netlink request carrying attrs
+func encodeRequest(family uint16, flags uint16, seq uint32, cmd uint8, attrs []byte) []byte {
+	length := nlmsgHeaderLength + genlHeaderLength + len(attrs)
+	b := make([]byte, length)
+	binary.NativeEndian.PutUint32(b[0:4], uint32(length))
+	binary.NativeEndian.PutUint16(b[4:6], family)
+	binary.NativeEndian.PutUint16(b[6:8], flags)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+	// The port ID stays 0 so the kernel fills it in
+	b[16] = cmd
+	b[17] = genlVersion
+	copy(b[20:], attrs)
+	return b
+}
+
+// errorCode returns the errno of an NLMSG_ERROR message; 0 is an ack
+func errorCode(m message) int32 {
+	if len(m.Payload) < 4 {
+		return 0
+	}
+	return int32(binary.NativeEndian.Uint32(m.Payload[0:4]))
+}
+
+// genlCommand returns the command of a generic netlink message
+func genlCommand(m message) uint8 {
+	if len(m.Payload) < genlHeaderLength {
+		return 0
+	}
+	return m.Payload[0]
+}
+
+// genlAttributes returns the attributes of a generic netlink message
+func genlAttributes(m message) map[uint16][]byte {
+	if len(m.Payload) < genlHeaderLength {
+		return nil
+	}
+	return parseAttributes(m.Payload[genlHeaderLength:])
+}
+
+// encodeAttribute builds a netlink attribute, padded to four bytes
+func encodeAttribute(kind uint16, value []byte) []byte {
+	length := attrHeaderLength + len(value)
+	b := make([]byte, align(length))
+	binary.NativeEndian.PutUint16(b[0:2], uint16(length))
+	binary.NativeEndian.PutUint16(b[2:4], kind)
+	copy(b[attrHeaderLength:], value)
+	return b
+}
+
+func encodeUint32(kind uint16, v uint32) []byte {
+	value := make([]byte, 4)
+	binary.NativeEndian.PutUint32(value, v)
+	return encodeAttribute(kind, value)
+}
+
+// parseAttributes indexes a list of netlink attributes by type. Nested
+// attributes are left encoded for another parseAttributes call.
+func parseAttributes(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= attrHeaderLength {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		if length < attrHeaderLength || length > len(b) {
+			break
+		}
+		attrs[binary.NativeEndian.Uint16(b[2:4])&attrTypeMask] = b[attrHeaderLength:length]
+		if align(length) >= len(b) {
+			break
+		}
+		b = b[align(length):]
+	}
+	return attrs
+}
+
+// parseFamily reads the family ID and the ID of the scan multicast group
+// from a CTRL_CMD_GETFAMILY reply
+func parseFamily(attrs map[uint16][]byte) (family uint16, scanGroup uint32, err error) {
+	id := attrs[ctrlAttrFamilyID]
+	if len(id) < 2 {
+		return 0, 0, fmt.Errorf("%s family has no ID", familyName)
+	}
+	family = binary.NativeEndian.Uint16(id)
+
+	for _, group := range parseAttributes(attrs[ctrlAttrMcastGroups]) {
+		groupAttrs := parseAttributes(group)
+		if cString(groupAttrs[ctrlAttrMcastGrpName]) == scanMulticastGroup && len(groupAttrs[ctrlAttrMcastGrpID]) >= 4 {
+			scanGroup = binary.NativeEndian.Uint32(groupAttrs[ctrlAttrMcastGrpID])
+		}
+	}
+	if scanGroup == 0 {
+		return 0, 0, fmt.Errorf("%s family has no %s multicast group", familyName, scanMulticastGroup)
+	}
+	return family, scanGroup, nil
+}
+
+// iface is a wireless interface from NL80211_CMD_GET_INTERFACE
+type iface struct {
+	index int
+	name  string
+	kind  uint32
+}
+
+// parseInterface reads an NL80211_CMD_NEW_INTERFACE message
+func parseInterface(attrs map[uint16][]byte) (iface, bool) {
+	if len(attrs[attrIfindex]) < 4 {
+		return iface{}, false
+	}
+	result := iface{
+		index: int(binary.NativeEndian.Uint32(attrs[attrIfindex])),
+		name:  cString(attrs[attrIfname]),
+	}
+	if len(attrs[attrIftype]) >= 4 {
+		result.kind = binary.NativeEndian.Uint32(attrs[attrIftype])
+	}
+	return result, true
+}
+
+// parseBSS reads the NL80211_ATTR_BSS of a scan result received at now
+func parseBSS(attrs map[uint16][]byte, ifaceName string, now time.Time) (BSS, bool) {
+	nested, ok := attrs[attrBSS]
+	if !ok {
+		return BSS{}, false
+	}
+	bssAttrs := parseAttributes(nested)
+
+	if len(bssAttrs[bssBSSID]) != 6 {
+		return BSS{}, false
+	}
+	bss := BSS{
+		Interface: ifaceName,
+		BSSID:     strings.ToUpper(net.HardwareAddr(bssAttrs[bssBSSID]).String()),
+		LastSeen:  now,
+	}
+	if v := bssAttrs[bssFrequency]; len(v) >= 4 {
+		bss.Frequency = int(binary.NativeEndian.Uint32(v))
+	}
+	if v := bssAttrs[bssSignalMBm]; len(v) >= 4 {
+		bss.SignalMBm = int(int32(binary.NativeEndian.Uint32(v)))
+	}
+	if v := bssAttrs[bssCapability]; len(v) >= 2 {
+		bss.Capability = binary.NativeEndian.Uint16(v)
+	}
+	if v := bssAttrs[bssSeenMsAgo]; len(v) >= 4 {
+		bss.LastSeen = now.Add(-time.Duration(binary.NativeEndian.Uint32(v)) * time.Millisecond)
+	}
+	if v := bssAttrs[bssStatus]; len(v) >= 4 {
+		bss.Associated = binary.NativeEndian.Uint32(v) == bssStatusAssoc
+	}
+
+	// Probe response elements are more complete; fall back to the beacon's
+	ies := bssAttrs[bssIEs]
+	if len(ies) == 0 {
+		ies = bssAttrs[bssBeaconIEs]
+	}
+	bss.IEs = append([]byte(nil), ies...)
+	return bss, true
+}
+
+// cString trims the terminating NUL of a netlink string attribute
+func cString(b []byte) string {
+	return strings.TrimRight(string(b), "\x00")
+}
+
+// align rounds n up to the four byte netlink alignment
+func align(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,113 @@
+package nl80211
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// scanResult encodes an NL80211_CMD_NEW_SCAN_RESULTS message as the kernel
+// sends it in a scan dump
+func scanResult(seq uint32, bss []byte) []byte {
+	attrs := append(encodeUint32(attrIfindex, 3), encodeAttribute(attrBSS, bss)...)
+	return encodeRequest(0x1c, flagMulti, seq, cmdNewScanResults, attrs)
+}
+
+func TestParseScanDump(t *testing.T) {
+	ies := []byte{
+		ElementSSID, 9, 'S', 'm', 'i', 't', 'h', 'H', 'o', 'm', 'e',
+		1, 2, 0x82, 0x84, // supported rates
+		ElementRSN, 20, 1, 0, 0x00, 0x0f, 0xac, 4, 1, 0, 0x00, 0x0f, 0xac, 4, 1, 0, 0x00, 0x0f, 0xac, 2, 0, 0,
+		ElementVendor, 7, 0x00, 0x50, 0xf2, 4, 0x10, 0x4a, 0x00, // WPS
+	}
+	mbm := int32(-4800)
+	signal := make([]byte, 4)
+	binary.NativeEndian.PutUint32(signal, uint32(mbm))
+	capability := make([]byte, 2)
+	binary.NativeEndian.PutUint16(capability, 0x0411)
+
+	var bss []byte
+	bss = append(bss, encodeAttribute(bssBSSID, []byte{0x3c, 0x84, 0x6a, 0x12, 0x34, 0x56})...)
+	bss = append(bss, encodeUint32(bssFrequency, 2437)...)
+	bss = append(bss, encodeAttribute(bssCapability, capability)...)
+	bss = append(bss, encodeAttribute(bssSignalMBm, signal)...)
+	bss = append(bss, encodeUint32(bssSeenMsAgo, 1500)...)
+	bss = append(bss, encodeUint32(bssStatus, bssStatusAssoc)...)
+	bss = append(bss, encodeAttribute(bssBeaconIEs, []byte{ElementSSID, 0})...)
+	bss = append(bss, encodeAttribute(bssIEs, ies)...)
+
+	// Hidden network with only beacon elements and a zeroed SSID
+	var hidden []byte
+	hidden = append(hidden, encodeAttribute(bssBSSID, []byte{0x9a, 0xde, 0xd0, 0x11, 0x22, 0x33})...)
+	hidden = append(hidden, encodeUint32(bssFrequency, 5180)...)
+	hidden = append(hidden, encodeAttribute(bssBeaconIEs, []byte{ElementSSID, 3, 0, 0, 0})...)
+
+	var datagram []byte
+	datagram = append(datagram, scanResult(7, bss)...)
+	datagram = append(datagram, scanResult(7, hidden)...)
+	datagram = append(datagram, encodeRequest(nlmsgDone, flagMulti, 7, 0, nil)...)
+
+	messages, err := parseMessages(datagram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[2].Type != nlmsgDone {
+		t.Fatalf("messages = %+v", messages)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	got, ok := parseBSS(genlAttributes(messages[0]), "wlan0", now)
+	if !ok {
+		t.Fatal("parseBSS() failed")
+	}
+	if got.Interface != "wlan0" || got.BSSID != "3C:84:6A:12:34:56" || got.Frequency != 2437 || got.Channel() != 6 ||
+		got.SignalMBm != -4800 || !got.Privacy() || !got.Associated || !got.LastSeen.Equal(now.Add(-1500*time.Millisecond)) {
+		t.Errorf("BSS = %+v", got)
+	}
+	if got.SSID() != "SmithHome" {
+		t.Errorf("SSID() = %q", got.SSID())
+	}
+	if rsn := got.Element(ElementRSN); len(rsn) != 20 || rsn[11] != 4 {
+		t.Errorf("Element(RSN) = %x", rsn)
+	}
+	if wps := got.VendorElement([3]byte{0x00, 0x50, 0xf2}, 4); !bytes.Equal(wps, []byte{0x10, 0x4a, 0x00}) {
+		t.Errorf("VendorElement(WPS) = %x", wps)
+	}
+	if wpa := got.VendorElement([3]byte{0x00, 0x50, 0xf2}, 1); wpa != nil {
+		t.Errorf("VendorElement(WPA) = %x, want none", wpa)
+	}
+
+	got, ok = parseBSS(genlAttributes(messages[1]), "wlan0", now)
+	if !ok || got.SSID() != "" || got.Channel() != 36 || got.Privacy() || !got.LastSeen.Equal(now) {
+		t.Errorf("hidden BSS = %+v", got)
+	}
+}
+
+func TestParseFamily(t *testing.T) {
+	group := func(name string, id uint32) []byte {
+		attrs := append(encodeAttribute(ctrlAttrMcastGrpName, append([]byte(name), 0)), encodeUint32(ctrlAttrMcastGrpID, id)...)
+		return encodeAttribute(1, attrs)
+	}
+	id := make([]byte, 2)
+	binary.NativeEndian.PutUint16(id, 0x1c)
+
+	var attrs []byte
+	attrs = append(attrs, encodeAttribute(ctrlAttrFamilyID, id)...)
+	attrs = append(attrs, encodeAttribute(ctrlAttrFamilyName, append([]byte(familyName), 0))...)
+	attrs = append(attrs, encodeAttribute(ctrlAttrMcastGroups, append(group("config", 4), group("scan", 5)...))...)
+
+	family, scanGroup, err := parseFamily(parseAttributes(attrs))
+	if err != nil || family != 0x1c || scanGroup != 5 {
+		t.Errorf("parseFamily() = %#x, %d, %v", family, scanGroup, err)
+	}
+}
+
+func TestFrequencyToChannel(t *testing.T) {
+	tests := map[int]int{2412: 1, 2472: 13, 2484: 14, 5180: 36, 5825: 165, 5955: 1, 6115: 33, 58320: 1, 900: 0}
+	for mhz, want := range tests {
+		if got := FrequencyToChannel(mhz); got != want {
+			t.Errorf("FrequencyToChannel(%d) = %d, want %d", mhz, got, want)
+		}
+	}
+}
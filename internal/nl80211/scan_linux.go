@@ -0,0 +1,258 @@
+//go:build linux
+
+package nl80211
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Socket timeouts
+const (
+	requestTimeout = 5 * time.Second        // bounds a single request to the kernel
+	eventTimeout   = 100 * time.Millisecond // lets the scan wait notice its deadline
+)
+
+// Check reports whether the kernel offers nl80211
+func Check() error {
+	c, err := dial(requestTimeout)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	_, _, err = c.resolveFamily()
+	return err
+}
+
+// Scan triggers a scan on every wireless station interface, waits up to
+// timeout for it to finish and returns the access points the kernel knows,
+// one entry per BSSID. Triggering a scan needs CAP_NET_ADMIN; without it the
+// results of the last scan, for example one NetworkManager ran, are returned.
+func Scan(timeout time.Duration) ([]BSS, error) {
+	c, err := dial(requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	family, scanGroup, err := c.resolveFamily()
+	if err != nil {
+		return nil, err
+	}
+	stations, err := c.stationInterfaces(family)
+	if err != nil {
+		return nil, err
+	}
+	if len(stations) == 0 {
+		return nil, errors.New("no wireless station interfaces")
+	}
+
+	// Subscribe before triggering so no completion event is missed
+	events, err := dial(eventTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer events.close()
+	if err := syscall.SetsockoptInt(events.fd, solNetlink, netlinkAddMembership, int(scanGroup)); err != nil {
+		return nil, fmt.Errorf("failed to join nl80211 scan events: %v", err)
+	}
+
+	var triggerErr error
+	pending := make(map[int]bool)
+	for _, station := range stations {
+		err := c.triggerScan(family, station.index)
+		switch {
+		case err == nil, errors.Is(err, syscall.EBUSY):
+			// A busy interface is already scanning; its results are awaited too
+			pending[station.index] = true
+		case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+			// Read the cached results
+		default:
+			triggerErr = fmt.Errorf("failed to trigger scan on %s: %v", station.name, err)
+		}
+	}
+	events.waitForScans(pending, time.Now().Add(timeout))
+
+	latest := make(map[string]int)
+	var results []BSS
+	for _, station := range stations {
+		bsses, err := c.scanResults(family, station)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scan results of %s: %v", station.name, err)
+		}
+		for _, bss := range bsses {
+			// An access point seen by several interfaces is reported once
+			if i, ok := latest[bss.BSSID]; ok {
+				if bss.LastSeen.After(results[i].LastSeen) {
+					results[i] = bss
+				}
+				continue
+			}
+			latest[bss.BSSID] = len(results)
+			results = append(results, bss)
+		}
+	}
+	if len(results) == 0 && triggerErr != nil {
+		return nil, triggerErr
+	}
+	return results, nil
+}
+
+// conn is a generic netlink socket
+type conn struct {
+	fd  int
+	seq uint32
+}
+
+// dial opens a generic netlink socket whose reads time out after timeout
+func dial(timeout time.Duration) (*conn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, netlinkGeneric)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %v", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind netlink socket: %v", err)
+	}
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &conn{fd: fd}, nil
+}
+
+func (c *conn) close() {
+	syscall.Close(c.fd)
+}
+
+// request sends a request and collects the replies up to the closing ack,
+// NLMSG_DONE or error
+func (c *conn) request(family uint16, flags uint16, cmd uint8, attrs []byte) ([]message, error) {
+	c.seq++
+	req := encodeRequest(family, flags|flagRequest|flagAck, c.seq, cmd, attrs)
+	if err := syscall.Sendto(c.fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	var replies []message
+	buf := make([]byte, receiveBufferSize)
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return nil, err
+		}
+		messages, err := parseMessages(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range messages {
+			if m.Seq != c.seq {
+				continue // a late ack of an earlier request
+			}
+			switch m.Type {
+			case nlmsgDone:
+				return replies, nil
+			case nlmsgError:
+				if code := errorCode(m); code != 0 {
+					return nil, syscall.Errno(-code)
+				}
+				return replies, nil
+			default:
+				m.Payload = append([]byte(nil), m.Payload...) // buf is reused
+				replies = append(replies, m)
+			}
+		}
+	}
+}
+
+// resolveFamily looks up the nl80211 family ID and its scan event group
+func (c *conn) resolveFamily() (uint16, uint32, error) {
+	name := append([]byte(familyName), 0)
+	replies, err := c.request(genlIDCtrl, 0, ctrlCmdGetFamily, encodeAttribute(ctrlAttrFamilyName, name))
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return 0, 0, errors.New("nl80211 is not available (no cfg80211 wireless driver loaded)")
+		}
+		return 0, 0, fmt.Errorf("failed to resolve nl80211: %v", err)
+	}
+	if len(replies) == 0 {
+		return 0, 0, errors.New("failed to resolve nl80211: empty reply")
+	}
+	return parseFamily(genlAttributes(replies[0]))
+}
+
+// stationInterfaces lists the wireless interfaces in station mode; monitor
+// and access point interfaces cannot scan
+func (c *conn) stationInterfaces(family uint16) ([]iface, error) {
+	replies, err := c.request(family, flagDump, cmdGetInterface, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wireless interfaces: %v", err)
+	}
+
+	var stations []iface
+	for _, reply := range replies {
+		if station, ok := parseInterface(genlAttributes(reply)); ok && station.kind == iftypeStation {
+			stations = append(stations, station)
+		}
+	}
+	return stations, nil
+}
+
+// triggerScan starts an active scan for every SSID on an interface
+func (c *conn) triggerScan(family uint16, ifindex int) error {
+	wildcard := encodeAttribute(1, nil)
+	attrs := append(encodeUint32(attrIfindex, uint32(ifindex)), encodeAttribute(attrScanSSIDs, wildcard)...)
+	_, err := c.request(family, 0, cmdTriggerScan, attrs)
+	return err
+}
+
+// waitForScans reads scan events until every pending interface has finished
+// or aborted its scan, or until the deadline
+func (c *conn) waitForScans(pending map[int]bool, deadline time.Time) {
+	buf := make([]byte, receiveBufferSize)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return
+		}
+		messages, err := parseMessages(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range messages {
+			if cmd := genlCommand(m); cmd != cmdNewScanResults && cmd != cmdScanAborted {
+				continue
+			}
+			if station, ok := parseInterface(genlAttributes(m)); ok {
+				delete(pending, station.index)
+			}
+		}
+	}
+}
+
+// scanResults dumps the BSS entries the kernel holds for an interface
+func (c *conn) scanResults(family uint16, station iface) ([]BSS, error) {
+	replies, err := c.request(family, flagDump, cmdGetScan, encodeUint32(attrIfindex, uint32(station.index)))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var bsses []BSS
+	for _, reply := range replies {
+		if bss, ok := parseBSS(genlAttributes(reply), station.name, now); ok {
+			bsses = append(bsses, bss)
+		}
+	}
+	return bsses, nil
+}
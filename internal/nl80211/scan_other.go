@@ -0,0 +1,15 @@
+//go:build !linux
+
+package nl80211
+
+import "time"
+
+// Check is only implemented on Linux
+func Check() error {
+	return ErrUnsupported
+}
+
+// Scan is only implemented on Linux
+func Scan(timeout time.Duration) ([]BSS, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,226 @@
+// Package oui resolves the vendor of MAC and Bluetooth addresses from the
+// IEEE registration authority's assignments. A small registry of common
+// vendors is built in; the full registry can be installed next to it.
+package oui
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSource is where the IEEE publishes the MA-L (24-bit OUI) registry.
+// The MA-M and MA-S registries (mam.csv, oui36.csv) use the same format.
+const DefaultSource = "https://standards-oui.ieee.org/oui/oui.csv"
+
+// fetchTimeout bounds a registry download
+const fetchTimeout = 2 * time.Minute
+
+// Assignment lengths in hex digits: MA-S, MA-M and MA-L, longest first
+var prefixLengths = []int{9, 7, 6}
+
+//go:embed oui.csv
+var embedded []byte
+
+var (
+	embeddedOnce sync.Once
+	embeddedDB   *Database
+)
+
+// Database maps address prefixes to organization names
+type Database struct {
+	vendors map[string]string // upper-case hex prefix -> organization
+}
+
+// Embedded returns the built-in registry
+func Embedded() *Database {
+	embeddedOnce.Do(func() {
+		db, err := Parse(bytes.NewReader(embedded))
+		if err != nil {
+			panic(fmt.Sprintf("oui: invalid embedded registry: %v", err))
+		}
+		embeddedDB = db
+	})
+	return embeddedDB
+}
+
+// Open returns the built-in registry extended with the registry file at
+// path, if there is one. Entries in the file take precedence.
+func Open(path string) (*Database, error) {
+	db := &Database{vendors: make(map[string]string)}
+	for prefix, vendor := range Embedded().vendors {
+		db.vendors[prefix] = vendor
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	installed, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vendor registry %s: %v", path, err)
+	}
+	for prefix, vendor := range installed.vendors {
+		db.vendors[prefix] = vendor
+	}
+	return db, nil
+}
+
+// Parse reads a registry in the IEEE CSV format: registry, assignment (hex
+// prefix), organization name and address, with an optional header row
+func Parse(r io.Reader) (*Database, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	db := &Database{vendors: make(map[string]string)}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 || (line == 1 && strings.EqualFold(record[0], "Registry")) {
+			continue
+		}
+
+		prefix := strings.ToUpper(strings.TrimSpace(record[1]))
+		if !isPrefix(prefix) {
+			return nil, fmt.Errorf("line %d: invalid assignment %q", line, record[1])
+		}
+		db.vendors[prefix] = strings.TrimSpace(record[2])
+	}
+
+	if len(db.vendors) == 0 {
+		return nil, errors.New("no assignments found")
+	}
+	return db, nil
+}
+
+// Len returns the number of assignments in the registry
+func (db *Database) Len() int {
+	return len(db.vendors)
+}
+
+// Lookup returns the organization an address was assigned to, or "" if the
+// prefix is not registered or the address is locally administered
+func (db *Database) Lookup(address string) string {
+	digits := hexDigits(address)
+	if len(digits) < 6 || IsLocallyAdministered(address) {
+		return ""
+	}
+	for _, length := range prefixLengths {
+		if len(digits) < length {
+			continue
+		}
+		if vendor, ok := db.vendors[digits[:length]]; ok {
+			return vendor
+		}
+	}
+	return ""
+}
+
+// IsLocallyAdministered reports whether an address has the locally
+// administered bit set, as randomized (private) Wi-Fi and Bluetooth
+// addresses and virtual interfaces do. Such addresses carry no vendor.
+func IsLocallyAdministered(address string) bool {
+	digits := hexDigits(address)
+	if len(digits) < 2 {
+		return false
+	}
+	first, _ := strconv.ParseUint(digits[:2], 16, 8)
+	return first&0x02 != 0
+}
+
+// Label names the vendor of an address for display: "(random)" for locally
+// administered addresses and "Unknown" for unregistered ones
+func Label(vendor string, locallyAdministered bool) string {
+	switch {
+	case locallyAdministered:
+		return "(random)"
+	case vendor == "":
+		return "Unknown"
+	}
+	return vendor
+}
+
+// Fetch reads a registry from an http(s) URL or a local file
+func Fetch(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Install validates a registry and saves it to path, returning the number
+// of assignments it holds
+func Install(path string, data []byte) (int, error) {
+	db, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("invalid vendor registry: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to replace vendor registry: %v", err)
+	}
+	return db.Len(), nil
+}
+
+// hexDigits strips the separators from an address such as
+// AA:BB:CC:DD:EE:FF, aa-bb-cc-dd-ee-ff or aabb.ccdd.eeff
+func hexDigits(address string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(address) {
+		switch {
+		case r >= '0' && r <= '9', r >= 'A' && r <= 'F':
+			b.WriteRune(r)
+		case r == ':' || r == '-' || r == '.':
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
+
+func isPrefix(prefix string) bool {
+	for _, length := range prefixLengths {
+		if len(prefix) == length {
+			return hexDigits(prefix) == prefix
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package oui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	db := Embedded()
+	tests := map[string]string{
+		"b8:27:eb:12:34:56": "Raspberry Pi Foundation",
+		"00-50-56-C0-00-08": "VMware, Inc.",
+		"0017.8801.0203":    "Philips Lighting BV",
+		"12:34:56:78:9A:BC": "", // locally administered
+		"FC:FF:FF:00:00:01": "", // not in the built-in registry
+		"ATTACK_DEVICE_01":  "",
+	}
+	for address, want := range tests {
+		if got := db.Lookup(address); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestIsLocallyAdministered(t *testing.T) {
+	for address, want := range map[string]bool{
+		"DA:A1:19:00:00:01": true,
+		"52:54:00:12:34:56": true,
+		"B8:27:EB:00:00:01": false,
+		"":                  false,
+	} {
+		if got := IsLocallyAdministered(address); got != want {
+			t.Errorf("IsLocallyAdministered(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for _, tt := range []struct {
+		vendor string
+		local  bool
+		want   string
+	}{
+		{"Raspberry Pi Foundation", false, "Raspberry Pi Foundation"},
+		{"", false, "Unknown"},
+		{"", true, "(random)"},
+	} {
+		if got := Label(tt.vendor, tt.local); got != tt.want {
+			t.Errorf("Label(%q, %v) = %q, want %q", tt.vendor, tt.local, got, tt.want)
+		}
+	}
+}
+
+func TestInstallAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oui.csv")
+
+	// MA-M and MA-S assignments are more specific than the MA-L prefix
+	registry := strings.Join([]string{
+		"Registry,Assignment,Organization Name,Organization Address",
+		`MA-L,FCFFFF,"Example Devices, Inc.",Somewhere`,
+		"MA-M,FCFFFF1,Example Sensors,Somewhere",
+		"MA-S,B827EB123,Example Boards,Somewhere",
+	}, "\n")
+	count, err := Install(path, []byte(registry))
+	if err != nil || count != 3 {
+		t.Fatalf("Install() = %d, %v", count, err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for address, want := range map[string]string{
+		"FC:FF:FF:00:00:01": "Example Devices, Inc.",
+		"FC:FF:FF:10:00:01": "Example Sensors",
+		"B8:27:EB:12:34:56": "Example Boards",
+		"B8:27:EB:00:00:01": "Raspberry Pi Foundation",
+	} {
+		if got := db.Lookup(address); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", address, got, want)
+		}
+	}
+
+	if _, err := Install(path, []byte("<html>not a registry</html>")); err == nil {
+		t.Error("Install() accepted an invalid registry")
+	}
+	if data, _ := os.ReadFile(path); string(data) != registry {
+		t.Error("invalid registry replaced the installed one")
+	}
+}
@@ -0,0 +1,345 @@
+// Package portscan finds open TCP and UDP ports with plain sockets, probing
+// many hosts concurrently at a bounded rate and reading service banners.
+package portscan
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Options
+const (
+	DefaultTimeout       = time.Second
+	DefaultBannerTimeout = 2 * time.Second
+	DefaultConcurrency   = 100
+	DefaultRate          = 500 // probes per second
+
+	// DefaultTCPPorts are the ports the scanner has always checked
+	DefaultTCPPorts = "21-23,25,53,80,110,143,443,445,993,995,3389"
+	// DefaultUDPPorts are UDP services that answer a well-formed probe
+	DefaultUDPPorts = "53,123,161"
+)
+
+// maxBannerLength bounds the banner kept for a port
+const maxBannerLength = 80
+
+// Options controls a scan
+type Options struct {
+	TCPPorts      []int
+	UDPPorts      []int
+	Timeout       time.Duration // connect timeout, and UDP reply timeout
+	BannerTimeout time.Duration // how long to wait for a TCP service to speak
+	Concurrency   int           // probes in flight at once
+	Rate          int           // probes started per second
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.BannerTimeout <= 0 {
+		o.BannerTimeout = DefaultBannerTimeout
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	if o.Rate <= 0 {
+		o.Rate = DefaultRate
+	}
+	return o
+}
+
+// Result is an open port
+type Result struct {
+	Port     int
+	Protocol string // "tcp" or "udp"
+	Service  string // well-known service name, "unknown" if there is none
+	Banner   string // first line the service sent, or the HTTP Server header
+}
+
+// ParsePorts parses a port list such as "22,80,8000-8100"
+func ParsePorts(spec string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			first, last = part[:i], part[i+1:]
+		}
+		low, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+		high, err := parsePort(last)
+		if err != nil {
+			return nil, err
+		}
+		if low > high {
+			return nil, fmt.Errorf("invalid port range %q", part)
+		}
+
+		for port := low; port <= high; port++ {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// FormatPorts writes ports in the form ParsePorts reads, joining runs into ranges
+func FormatPorts(ports []int) string {
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// Scan probes every port of every host and returns the open ports by host,
+// ordered by protocol and port. Hosts without open ports are left out.
+func Scan(hosts []string, opts Options) map[string][]Result {
+	opts = opts.withDefaults()
+
+	type probe struct {
+		host     string
+		port     int
+		protocol string
+	}
+
+	results := make(map[string][]Result)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	probes := make(chan probe)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range probes {
+				var result Result
+				var open bool
+				if p.protocol == "udp" {
+					result, open = probeUDP(p.host, p.port, opts)
+				} else {
+					result, open = probeTCP(p.host, p.port, opts)
+				}
+				if open {
+					mu.Lock()
+					results[p.host] = append(results[p.host], result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	interval := time.Second / time.Duration(opts.Rate)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for _, host := range hosts {
+		for _, port := range opts.TCPPorts {
+			<-ticker.C
+			probes <- probe{host: host, port: port, protocol: "tcp"}
+		}
+		for _, port := range opts.UDPPorts {
+			<-ticker.C
+			probes <- probe{host: host, port: port, protocol: "udp"}
+		}
+	}
+	close(probes)
+	wg.Wait()
+
+	for _, open := range results {
+		sort.Slice(open, func(i, j int) bool {
+			if open[i].Protocol != open[j].Protocol {
+				return open[i].Protocol < open[j].Protocol
+			}
+			return open[i].Port < open[j].Port
+		})
+	}
+	return results
+}
+
+// probeTCP connects to a port and reads what the service announces
+func probeTCP(host string, port int, opts Options) (Result, bool) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), opts.Timeout)
+	if err != nil {
+		return Result{}, false
+	}
+	defer conn.Close()
+
+	result := Result{Port: port, Protocol: "tcp", Service: ServiceName(port, "tcp")}
+	result.Banner = grabBanner(conn, host, port, opts.BannerTimeout)
+	if strings.HasPrefix(result.Banner, "SSH-") {
+		result.Service = "ssh"
+	}
+	return result, true
+}
+
+// grabBanner waits for the service to speak first, as SSH, FTP and mail
+// servers do, and otherwise asks web servers for their headers
+func grabBanner(conn net.Conn, host string, port int, timeout time.Duration) string {
+	buf := make([]byte, 1024)
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	n, _ := conn.Read(buf)
+	if n == 0 && isHTTPPort(port) {
+		request := fmt.Sprintf("HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", host)
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		if _, err := conn.Write([]byte(request)); err == nil {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+			n, _ = conn.Read(buf)
+		}
+	}
+	return bannerText(buf[:n])
+}
+
+// bannerText extracts a printable one-line banner from a service greeting
+func bannerText(data []byte) string {
+	if bytes.HasPrefix(data, []byte("HTTP/")) {
+		for _, line := range strings.Split(string(data), "\n") {
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Server") {
+				return clean(value)
+			}
+		}
+	}
+
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return clean(string(line))
+}
+
+// clean drops control and non-ASCII characters and shortens the text
+func clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if len(s) > maxBannerLength {
+		s = s[:maxBannerLength]
+	}
+	return s
+}
+
+// probeUDP sends a probe the service understands and waits for any reply. A
+// port that stays silent is not reported, since it cannot be told apart from
+// a filtered one.
+func probeUDP(host string, port int, opts Options) (Result, bool) {
+	conn, err := net.Dial("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return Result{}, false
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+	if _, err := conn.Write(udpPayload(port)); err != nil {
+		return Result{}, false
+	}
+	buf := make([]byte, 1500)
+	if n, err := conn.Read(buf); err != nil || n == 0 {
+		return Result{}, false
+	}
+	return Result{Port: port, Protocol: "udp", Service: ServiceName(port, "udp")}, true
+}
+
+// udpPayload returns a request the service on port answers
+func udpPayload(port int) []byte {
+	switch port {
+	case 53:
+		// Query for the root name servers
+		return []byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01}
+	case 123:
+		// NTP version 3 client request
+		payload := make([]byte, 48)
+		payload[0] = 0x1b
+		return payload
+	case 161:
+		// SNMPv1 get-request for sysDescr with community "public"
+		return []byte{
+			0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+			0xa0, 0x19, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+			0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+		}
+	case 1900:
+		return []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n")
+	default:
+		return []byte{0}
+	}
+}
+
+func isHTTPPort(port int) bool {
+	switch port {
+	case 80, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8888:
+		return true
+	}
+	return false
+}
+
+// Well-known service names, as nmap reports them
+var (
+	tcpServices = map[int]string{
+		21: "ftp", 22: "ssh", 23: "telnet", 25: "smtp", 53: "domain", 80: "http",
+		110: "pop3", 135: "msrpc", 139: "netbios-ssn", 143: "imap", 443: "https",
+		445: "microsoft-ds", 548: "afp", 587: "submission", 631: "ipp", 993: "imaps",
+		995: "pop3s", 1433: "ms-sql-s", 1883: "mqtt", 3306: "mysql", 3389: "ms-wbt-server",
+		5432: "postgresql", 5900: "vnc", 6379: "redis", 8080: "http-proxy", 8443: "https-alt",
+		9100: "jetdirect",
+	}
+	udpServices = map[int]string{
+		53: "domain", 67: "dhcps", 69: "tftp", 123: "ntp", 137: "netbios-ns",
+		161: "snmp", 500: "isakmp", 1900: "upnp", 5353: "mdns",
+	}
+)
+
+// ServiceName returns the well-known service on a port
+func ServiceName(port int, protocol string) string {
+	services := tcpServices
+	if protocol == "udp" {
+		services = udpServices
+	}
+	if name, ok := services[port]; ok {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,124 @@
+package portscan
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts("443, 21-23,22,3389")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{21, 22, 23, 443, 3389}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ParsePorts() = %v, want %v", ports, want)
+	}
+	if got := FormatPorts(ports); got != "21-23,443,3389" {
+		t.Errorf("FormatPorts() = %q", got)
+	}
+
+	for _, spec := range []string{"0", "65536", "80-22", "ssh", "1-"} {
+		if _, err := ParsePorts(spec); err == nil {
+			t.Errorf("ParsePorts(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestScanFindsOpenPortsAndBanners(t *testing.T) {
+	greeter := listenTCP(t, "SSH-2.0-OpenSSH_9.6 Test\r\n")
+	silent := listenTCP(t, "")
+	closed := listenTCP(t, "")
+	closed.Close()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			udp.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	results := Scan([]string{"127.0.0.1"}, Options{
+		TCPPorts:      []int{portOf(closed.Addr()), portOf(silent.Addr()), portOf(greeter.Addr())},
+		UDPPorts:      []int{portOf(udp.LocalAddr())},
+		BannerTimeout: 200 * time.Millisecond,
+		Concurrency:   2,
+		Rate:          1000,
+	})
+
+	open := results["127.0.0.1"]
+	if len(open) != 3 {
+		t.Fatalf("open ports = %+v, want two TCP and one UDP", open)
+	}
+	for _, result := range open {
+		switch result.Port {
+		case portOf(greeter.Addr()):
+			if result.Service != "ssh" || result.Banner != "SSH-2.0-OpenSSH_9.6 Test" {
+				t.Errorf("greeter = %+v", result)
+			}
+		case portOf(silent.Addr()):
+			if result.Protocol != "tcp" || result.Banner != "" {
+				t.Errorf("silent = %+v", result)
+			}
+		case portOf(udp.LocalAddr()):
+			if result.Protocol != "udp" {
+				t.Errorf("udp = %+v", result)
+			}
+		default:
+			t.Errorf("unexpected open port %+v", result)
+		}
+	}
+}
+
+func TestBannerText(t *testing.T) {
+	tests := map[string]string{
+		"HTTP/1.1 200 OK\r\nDate: today\r\nserver: nginx/1.24.0\r\n\r\n": "nginx/1.24.0",
+		"HTTP/1.0 404 Not Found\r\n\r\n":                                 "HTTP/1.0 404 Not Found",
+		"220 ftp.example.com FTP ready\r\nmore":                          "220 ftp.example.com FTP ready",
+		"\x00\x01binary\xff":                                             "binary",
+	}
+	for input, want := range tests {
+		if got := bannerText([]byte(input)); got != want {
+			t.Errorf("bannerText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// listenTCP accepts connections on a local port and greets each with banner
+func listenTCP(t *testing.T, banner string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(banner))
+			time.AfterFunc(time.Second, func() { conn.Close() })
+		}
+	}()
+	return listener
+}
+
+func portOf(addr net.Addr) int {
+	_, port, _ := net.SplitHostPort(addr.String())
+	n, _ := strconv.Atoi(port)
+	return n
+}
@@ -0,0 +1,13 @@
+// Package rawsock holds helpers shared by the packages that open AF_PACKET
+// sockets to send and capture raw frames.
+package rawsock
+
+import "encoding/binary"
+
+// Htons converts a 16-bit value, such as an EtherType passed to socket(2) or
+// bind(2), from host to network byte order
+func Htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
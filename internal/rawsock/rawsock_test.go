@@ -0,0 +1,15 @@
+package rawsock
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestHtons(t *testing.T) {
+	// Whatever the host order, the value is laid out big-endian in memory
+	var b [2]byte
+	binary.NativeEndian.PutUint16(b[:], Htons(0x0806))
+	if b != [2]byte{0x08, 0x06} {
+		t.Errorf("Htons(0x0806) is laid out as % x, want 08 06", b)
+	}
+}
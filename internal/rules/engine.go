@@ -0,0 +1,176 @@
+package rules
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// defaultRules is written to the rules file when it does not exist
+//
+//go:embed default_rules.json
+var defaultRules []byte
+
+// Engine holds the active rule set and reloads it when the rules file changes.
+// A file that fails to parse is reported and the previous rules stay active.
+type Engine struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	rules    []Rule
+	onReload func(rules int, err error)
+	mu       sync.RWMutex
+}
+
+// Default returns an engine with the built-in rules and no backing file
+func Default() *Engine {
+	rules, err := Parse(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("built-in detection rules are invalid: %v", err))
+	}
+	return &Engine{rules: rules}
+}
+
+// Load reads the rules file at path, creating it with the built-in rules if
+// it does not exist
+func Load(path string) (*Engine, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, defaultRules, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write default rules: %v", err)
+		}
+	}
+
+	e := &Engine{path: path}
+	if _, err := e.Refresh(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Parse decodes and validates a rules document
+func Parse(data []byte) ([]Rule, error) {
+	var ruleSet RuleSet
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %v", err)
+	}
+	if err := ruleSet.compile(); err != nil {
+		return nil, err
+	}
+	return ruleSet.Rules, nil
+}
+
+// SetReloadHandler registers fn to be called after every reload of the rules
+// file with the number of rules loaded, or the error that kept the old rules
+func (e *Engine) SetReloadHandler(fn func(rules int, err error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onReload = fn
+}
+
+// Refresh reloads the rules file if it changed since it was last read,
+// reporting whether new rules were loaded
+func (e *Engine) Refresh() (bool, error) {
+	if e == nil || e.path == "" {
+		return false, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, e.reloaded(0, fmt.Errorf("failed to read rules file: %v", err))
+	}
+	if info.ModTime().Equal(e.modTime) && info.Size() == e.size {
+		return false, nil
+	}
+
+	// Remember the version even if it is broken, so it is reported only once
+	e.modTime, e.size = info.ModTime(), info.Size()
+
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, e.reloaded(0, fmt.Errorf("failed to read rules file: %v", err))
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return false, e.reloaded(0, fmt.Errorf("%s: %v", e.path, err))
+	}
+
+	e.rules = rules
+	return true, e.reloaded(len(rules), nil)
+}
+
+// Close stops reloading the rules file. The active rules stay in effect.
+func (e *Engine) Close() error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.path = ""
+	e.onReload = nil
+	return nil
+}
+
+func (e *Engine) reloaded(rules int, err error) error {
+	if e.onReload != nil {
+		e.onReload(rules, err)
+	}
+	return err
+}
+
+// Rules returns the active rules
+func (e *Engine) Rules() []Rule {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]Rule, len(e.rules))
+	copy(rules, e.rules)
+	return rules
+}
+
+// Evaluate picks up changes to the rules file and returns the attacks raised
+// by the enabled rules of source for each record
+func (e *Engine) Evaluate(source string, records []Fields) []models.Attack {
+	if e == nil {
+		return nil
+	}
+	e.Refresh()
+
+	var attacks []models.Attack
+	for _, rule := range e.Rules() {
+		if rule.Disabled || rule.Source != source {
+			continue
+		}
+		for _, record := range records {
+			matched, ok := rule.Match(record)
+			if !ok {
+				continue
+			}
+			attack, err := rule.Attack(matched)
+			if err != nil {
+				continue
+			}
+			attacks = append(attacks, attack)
+		}
+	}
+	return attacks
+}
@@ -0,0 +1,303 @@
+// Package rules evaluates declarative detection rules against the devices
+// found by the scanners. Rules are kept in a JSON file so that detections can
+// be tuned without recompiling.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// Sources a rule can apply to. Each scanner evaluates the rules of its sources.
+const (
+	SourceBluetooth = "bluetooth" // one record per Bluetooth device
+	SourceWiFi      = "wifi"      // one record per WiFi access point
+	SourceNetwork   = "network"   // one record per network device
+	SourcePort      = "port"      // one record per port of a network device
+)
+
+// MatchField holds the list value that matched a contains_any, prefix_any or
+// in condition, for use in templates
+const MatchField = "match"
+
+// Operators supported in conditions. String comparisons ignore case.
+const (
+	OpEq          = "eq"
+	OpNe          = "ne"
+	OpGt          = "gt"
+	OpGte         = "gte"
+	OpLt          = "lt"
+	OpLte         = "lte"
+	OpContains    = "contains"
+	OpContainsAny = "contains_any"
+	OpPrefixAny   = "prefix_any"
+	OpIn          = "in"
+	OpRegex       = "regex"
+)
+
+// Fields holds the values of a device record, keyed by field name. Values
+// are strings, ints or bools; absent fields never match a condition.
+type Fields map[string]interface{}
+
+// Condition compares one field of a record with a value
+type Condition struct {
+	Field  string        `json:"field"`
+	Op     string        `json:"op"`
+	Value  interface{}   `json:"value,omitempty"`
+	Values []interface{} `json:"values,omitempty"` // for contains_any, prefix_any and in
+
+	pattern *regexp.Regexp
+}
+
+// Rule raises an attack for every record of its source that meets all of
+// its conditions
+type Rule struct {
+	Name        string      `json:"name"`
+	Source      string      `json:"source"`
+	Type        string      `json:"type"`
+	Severity    string      `json:"severity"`
+	When        []Condition `json:"when"`
+	Description string      `json:"description"` // text/template over the record fields
+	Target      string      `json:"target"`      // text/template over the record fields
+	Disabled    bool        `json:"disabled,omitempty"`
+
+	severity    models.Severity
+	description *template.Template
+	target      *template.Template
+}
+
+// RuleSet is the content of a rules file
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// compile validates the rule set and prepares its templates and patterns
+func (rs *RuleSet) compile() error {
+	names := make(map[string]bool)
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d (%s): %v", i+1, rule.Name, err)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %d: duplicate name %q", i+1, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	switch r.Source {
+	case SourceBluetooth, SourceWiFi, SourceNetwork, SourcePort:
+	default:
+		return fmt.Errorf("unknown source %q", r.Source)
+	}
+	if r.Type == "" {
+		return fmt.Errorf("missing attack type")
+	}
+	if len(r.When) == 0 {
+		return fmt.Errorf("no conditions")
+	}
+
+	severity, err := models.ParseSeverity(r.Severity)
+	if err != nil {
+		return err
+	}
+	r.severity = severity
+
+	for i := range r.When {
+		if err := r.When[i].compile(); err != nil {
+			return fmt.Errorf("condition %d: %v", i+1, err)
+		}
+	}
+
+	if r.description, err = parseTemplate("description", r.Description); err != nil {
+		return err
+	}
+	if r.target, err = parseTemplate("target", r.Target); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseTemplate(name string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("missing %s template", name)
+	}
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+func (c *Condition) compile() error {
+	if c.Field == "" {
+		return fmt.Errorf("missing field")
+	}
+
+	switch c.Op {
+	case OpEq, OpNe, OpContains:
+		if c.Value == nil {
+			return fmt.Errorf("%s needs a value", c.Op)
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, ok := toNumber(c.Value); !ok {
+			return fmt.Errorf("%s needs a numeric value", c.Op)
+		}
+	case OpContainsAny, OpPrefixAny, OpIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%s needs values", c.Op)
+		}
+	case OpRegex:
+		pattern, err := regexp.Compile("(?i)" + fmt.Sprint(c.Value))
+		if err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		c.pattern = pattern
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+	return nil
+}
+
+// Match reports whether the record meets all conditions of the rule. The
+// returned copy of the record has MatchField set if a list condition matched.
+func (r *Rule) Match(record Fields) (Fields, bool) {
+	var match string
+	for i := range r.When {
+		hit, matched := r.When[i].matches(record)
+		if !hit {
+			return nil, false
+		}
+		if matched != "" {
+			match = matched
+		}
+	}
+
+	result := make(Fields, len(record)+1)
+	for field, value := range record {
+		result[field] = value
+	}
+	if match != "" {
+		result[MatchField] = match
+	}
+	return result, true
+}
+
+// Attack builds the attack raised by the rule for a matching record
+func (r *Rule) Attack(record Fields) (models.Attack, error) {
+	var description, target strings.Builder
+	if err := r.description.Execute(&description, map[string]interface{}(record)); err != nil {
+		return models.Attack{}, fmt.Errorf("rule %s: %v", r.Name, err)
+	}
+	if err := r.target.Execute(&target, map[string]interface{}(record)); err != nil {
+		return models.Attack{}, fmt.Errorf("rule %s: %v", r.Name, err)
+	}
+
+	return models.Attack{
+		Type:        r.Type,
+		Severity:    r.severity,
+		Description: description.String(),
+		Target:      target.String(),
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// matches evaluates the condition, also returning the list value that
+// matched for contains_any, prefix_any and in
+func (c *Condition) matches(record Fields) (bool, string) {
+	value, ok := record[c.Field]
+	if !ok {
+		return false, ""
+	}
+	text := strings.ToLower(fmt.Sprint(value))
+
+	switch c.Op {
+	case OpEq:
+		return equal(value, c.Value), ""
+	case OpNe:
+		return !equal(value, c.Value), ""
+	case OpGt, OpGte, OpLt, OpLte:
+		return compareNumbers(c.Op, value, c.Value), ""
+	case OpContains:
+		return strings.Contains(text, strings.ToLower(fmt.Sprint(c.Value))), ""
+	case OpContainsAny, OpPrefixAny, OpIn:
+		for _, candidate := range c.Values {
+			want := strings.ToLower(fmt.Sprint(candidate))
+			var hit bool
+			switch c.Op {
+			case OpContainsAny:
+				hit = strings.Contains(text, want)
+			case OpPrefixAny:
+				hit = strings.HasPrefix(text, want)
+			default:
+				hit = equal(value, candidate)
+			}
+			if hit {
+				return true, fmt.Sprint(candidate)
+			}
+		}
+		return false, ""
+	case OpRegex:
+		return c.pattern.MatchString(fmt.Sprint(value)), ""
+	}
+	return false, ""
+}
+
+// equal compares numerically when both sides are numbers, otherwise as
+// case-insensitive strings
+func equal(a interface{}, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	return strings.EqualFold(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareNumbers(op string, a interface{}, b interface{}) bool {
+	x, ok := toNumber(a)
+	if !ok {
+		return false
+	}
+	y, _ := toNumber(b)
+
+	switch op {
+	case OpGt:
+		return x > y
+	case OpGte:
+		return x >= y
+	case OpLt:
+		return x < y
+	case OpLte:
+		return x <= y
+	}
+	return false
+}
+
+// toNumber converts ints, JSON numbers and numeric strings to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestConditionOperators(t *testing.T) {
+	record := Fields{"name": "Evil Headset", "rssi": -15, "port": 3389, "address": "AA:BB:CC:DD:EE:FF"}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{name: "eq string ignores case", condition: Condition{Field: "name", Op: OpEq, Value: "evil headset"}, want: true},
+		{name: "eq number", condition: Condition{Field: "port", Op: OpEq, Value: 3389.0}, want: true},
+		{name: "ne", condition: Condition{Field: "port", Op: OpNe, Value: 3389.0}, want: false},
+		{name: "gt", condition: Condition{Field: "rssi", Op: OpGt, Value: -20.0}, want: true},
+		{name: "lte", condition: Condition{Field: "rssi", Op: OpLte, Value: -20.0}, want: false},
+		{name: "contains", condition: Condition{Field: "name", Op: OpContains, Value: "EVIL"}, want: true},
+		{name: "contains_any", condition: Condition{Field: "name", Op: OpContainsAny, Values: []interface{}{"hack", "evil"}}, want: true},
+		{name: "prefix_any", condition: Condition{Field: "address", Op: OpPrefixAny, Values: []interface{}{"00:", "aa:"}}, want: true},
+		{name: "in", condition: Condition{Field: "port", Op: OpIn, Values: []interface{}{21.0, 23.0}}, want: false},
+		{name: "regex", condition: Condition{Field: "name", Op: OpRegex, Value: "^evil\\s"}, want: true},
+		{name: "missing field never matches", condition: Condition{Field: "ssid", Op: OpNe, Value: "x"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.condition.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := tt.condition.matches(record); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRulesMatchBuiltInHeuristics(t *testing.T) {
+	engine := Default()
+
+	attacks := engine.Evaluate(SourceBluetooth, []Fields{
+		{"address": "11:22:33:44:55:66", "name": "Android Proxy", "rssi": -10},
+	})
+	var types []string
+	for _, attack := range attacks {
+		types = append(types, attack.Type)
+	}
+	want := []string{"KNOB_ATTACK", "BLUEBORNE_VULNERABILITY", "BLUETOOTH_PROXIMITY", "BLUETOOTH_MITM"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("attack types = %v, want %v", types, want)
+	}
+	if got := attacks[3].Description; got != "Potential Man-in-the-Middle device: Android Proxy (11:22:33:44:55:66) - appears to be proxy" {
+		t.Errorf("description = %q", got)
+	}
+
+	attacks = engine.Evaluate(SourcePort, []Fields{
+		{"ip": "192.168.1.20", "port": 3389, "state": "open"},
+		{"ip": "192.168.1.20", "port": 22, "state": "open"},
+	})
+	if len(attacks) != 1 || attacks[0].Severity != models.SeverityMedium ||
+		attacks[0].Description != "Suspicious open port detected: 192.168.1.20:3389 (RDP)" {
+		t.Errorf("port attacks = %+v", attacks)
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{name: "unknown field", rules: `{"rules": [], "extra": 1}`, wantErr: "unknown field"},
+		{name: "unknown source", rules: rule(`"source": "zigbee"`), wantErr: "unknown source"},
+		{name: "bad severity", rules: rule(`"severity": "CRITICAL"`), wantErr: "unknown severity"},
+		{name: "unknown operator", rules: rule(`"when": [{"field": "rssi", "op": "near"}]`), wantErr: "unknown operator"},
+		{name: "non-numeric comparison", rules: rule(`"when": [{"field": "rssi", "op": "gt", "value": "close"}]`), wantErr: "numeric value"},
+		{name: "bad template", rules: rule(`"description": "{{.name"`), wantErr: "invalid description template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.rules))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEngineReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	engine, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(engine.Rules()), len(Default().Rules()); got != want {
+		t.Fatalf("created file has %d rules, want %d", got, want)
+	}
+
+	var reloads []error
+	engine.SetReloadHandler(func(rules int, err error) { reloads = append(reloads, err) })
+
+	writeRules(t, path, rule(`"when": [{"field": "ssid", "op": "eq", "value": "CorpGuest"}]`))
+	record := []Fields{{"ssid": "CorpGuest"}}
+	if attacks := engine.Evaluate(SourceWiFi, record); len(attacks) != 1 || attacks[0].Description != "Test rule for CorpGuest" {
+		t.Fatalf("attacks after reload = %+v", attacks)
+	}
+
+	// A broken edit keeps the previous rules
+	writeRules(t, path, `{"rules": [`)
+	if attacks := engine.Evaluate(SourceWiFi, record); len(attacks) != 1 {
+		t.Errorf("attacks after broken edit = %+v, want previous rules", attacks)
+	}
+	if len(reloads) != 2 || reloads[0] != nil || reloads[1] == nil {
+		t.Errorf("reload results = %v, want success then error", reloads)
+	}
+}
+
+// rule returns a rules document with one valid wifi rule, with override
+// replacing one of its fields
+func rule(override string) string {
+	fields := map[string]string{
+		"name":        `"name": "test"`,
+		"source":      `"source": "wifi"`,
+		"type":        `"type": "TEST"`,
+		"severity":    `"severity": "LOW"`,
+		"when":        `"when": [{"field": "ssid", "op": "contains", "value": "test"}]`,
+		"description": `"description": "Test rule for {{.ssid}}"`,
+		"target":      `"target": "{{.ssid}}"`,
+	}
+	key := strings.Trim(strings.SplitN(override, ":", 2)[0], `" `)
+	fields[key] = override
+
+	parts := make([]string, 0, len(fields))
+	for _, name := range []string{"name", "source", "type", "severity", "when", "description", "target"} {
+		parts = append(parts, fields[name])
+	}
+	return `{"rules": [{` + strings.Join(parts, ", ") + `}]}`
+}
+
+// writeRules replaces the rules file with a modification time the engine
+// sees as new, even on filesystems with coarse timestamps
+func writeRules(t *testing.T, path string, content string) {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package runner
+
+import "sync"
+
+// DryRunner records the commands that would change the host instead of
+// running them. Available, Output and CombinedOutput are passed to the wrapped
+// runner so that read-only queries such as iwconfig still work.
+type DryRunner struct {
+	runner   CommandRunner
+	commands []string
+	mu       sync.Mutex
+}
+
+// NewDryRunner wraps r so that Run and Start are only recorded
+func NewDryRunner(r CommandRunner) *DryRunner {
+	return &DryRunner{runner: r}
+}
+
+// Wrapped returns the runner used for read-only commands
+func (d *DryRunner) Wrapped() CommandRunner {
+	return d.runner
+}
+
+// Take returns the commands recorded since the last call and forgets them
+func (d *DryRunner) Take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	commands := d.commands
+	d.commands = nil
+	return commands
+}
+
+// Available implements CommandRunner
+func (d *DryRunner) Available(name string) bool {
+	return d.runner.Available(name)
+}
+
+// Run implements CommandRunner by recording the command
+func (d *DryRunner) Run(name string, args ...string) error {
+	d.record(name, args)
+	return nil
+}
+
+// Start implements CommandRunner by recording the command
+func (d *DryRunner) Start(name string, args ...string) error {
+	d.record(name, args)
+	return nil
+}
+
+// Output implements CommandRunner
+func (d *DryRunner) Output(name string, args ...string) ([]byte, error) {
+	return d.runner.Output(name, args...)
+}
+
+// CombinedOutput implements CommandRunner
+func (d *DryRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return d.runner.CombinedOutput(name, args...)
+}
+
+func (d *DryRunner) record(name string, args []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.commands = append(d.commands, CommandLine(name, args...))
+}
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrNoFixture is returned by a strict ReplayRunner for unrecorded commands
+var ErrNoFixture = errors.New("no recorded fixture for command")
+
+// Fixture is the recorded result of a single command
+type Fixture struct {
+	Output []byte
+	Err    error
+}
+
+// ReplayRunner serves recorded command output instead of running commands.
+// Commands are matched on their full command line (see CommandLine). Commands
+// without a fixture succeed with empty output unless Strict is set.
+type ReplayRunner struct {
+	Strict    bool
+	fixtures  map[string]Fixture
+	available map[string]bool
+	calls     []string
+	mu        sync.Mutex
+}
+
+// NewReplayRunner creates a replay runner with no fixtures
+func NewReplayRunner() *ReplayRunner {
+	return &ReplayRunner{
+		fixtures:  make(map[string]Fixture),
+		available: make(map[string]bool),
+	}
+}
+
+// SetAvailable marks commands as installed for Available
+func (r *ReplayRunner) SetAvailable(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		r.available[name] = true
+	}
+}
+
+// AddOutput records the output of a command line
+func (r *ReplayRunner) AddOutput(commandLine string, output string) {
+	r.AddFixture(commandLine, Fixture{Output: []byte(output)})
+}
+
+// AddError records a failing command line
+func (r *ReplayRunner) AddError(commandLine string, err error) {
+	r.AddFixture(commandLine, Fixture{Err: err})
+}
+
+// AddFile records the output of a command line from a fixture file
+func (r *ReplayRunner) AddFile(commandLine string, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture %s: %v", path, err)
+	}
+	r.AddFixture(commandLine, Fixture{Output: data})
+	return nil
+}
+
+// AddFixture records the result of a command line
+func (r *ReplayRunner) AddFixture(commandLine string, fixture Fixture) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixtures[commandLine] = fixture
+}
+
+// Calls returns the command lines executed so far, in order
+func (r *ReplayRunner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.calls...)
+}
+
+// Reset forgets the recorded calls, keeping fixtures
+func (r *ReplayRunner) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// Available implements CommandRunner
+func (r *ReplayRunner) Available(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.available[name]
+}
+
+// Run implements CommandRunner
+func (r *ReplayRunner) Run(name string, args ...string) error {
+	_, err := r.replay(name, args)
+	return err
+}
+
+// Start implements CommandRunner
+func (r *ReplayRunner) Start(name string, args ...string) error {
+	_, err := r.replay(name, args)
+	return err
+}
+
+// Output implements CommandRunner
+func (r *ReplayRunner) Output(name string, args ...string) ([]byte, error) {
+	return r.replay(name, args)
+}
+
+// CombinedOutput implements CommandRunner
+func (r *ReplayRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return r.replay(name, args)
+}
+
+func (r *ReplayRunner) replay(name string, args []string) ([]byte, error) {
+	commandLine := CommandLine(name, args...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, commandLine)
+
+	fixture, exists := r.fixtures[commandLine]
+	if !exists {
+		if r.Strict {
+			return nil, fmt.Errorf("%w: %s", ErrNoFixture, commandLine)
+		}
+		return nil, nil
+	}
+	return fixture.Output, fixture.Err
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReplayRunner(t *testing.T) {
+	replay := NewReplayRunner()
+	replay.SetAvailable("nmap")
+	replay.AddOutput("nmap -sn 10.0.0.0/24", "Nmap done")
+	replay.AddError("nmap -sn 10.0.1.0/24", errors.New("exit status 1"))
+
+	if !replay.Available("nmap") || replay.Available("fping") {
+		t.Error("Available() does not reflect SetAvailable")
+	}
+
+	output, err := replay.CombinedOutput("nmap", "-sn", "10.0.0.0/24")
+	if err != nil || string(output) != "Nmap done" {
+		t.Errorf("CombinedOutput() = %q, %v", output, err)
+	}
+	if _, err := replay.Output("nmap", "-sn", "10.0.1.0/24"); err == nil {
+		t.Error("expected recorded error")
+	}
+	if err := replay.Run("true"); err != nil {
+		t.Errorf("unrecorded command in lenient mode: %v", err)
+	}
+
+	replay.Strict = true
+	if err := replay.Run("true"); !errors.Is(err, ErrNoFixture) {
+		t.Errorf("unrecorded command in strict mode error = %v, want %v", err, ErrNoFixture)
+	}
+
+	want := []string{"nmap -sn 10.0.0.0/24", "nmap -sn 10.0.1.0/24", "true", "true"}
+	if got := replay.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner runs external tools on behalf of the scanners and the blocker.
+// It allows the real commands to be swapped for recorded fixtures in tests.
+type CommandRunner interface {
+	// Available reports whether the named command can be found in PATH
+	Available(name string) bool
+	// Run executes a command and waits for it to finish
+	Run(name string, args ...string) error
+	// Start starts a command without waiting for it to finish
+	Start(name string, args ...string) error
+	// Output executes a command and returns its standard output
+	Output(name string, args ...string) ([]byte, error)
+	// CombinedOutput executes a command and returns its standard output and error
+	CombinedOutput(name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands on the host using os/exec
+type ExecRunner struct{}
+
+// NewExecRunner creates a runner that executes real commands
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+// Available implements CommandRunner
+func (r *ExecRunner) Available(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Run implements CommandRunner
+func (r *ExecRunner) Run(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
+// Start implements CommandRunner
+func (r *ExecRunner) Start(name string, args ...string) error {
+	return exec.Command(name, args...).Start()
+}
+
+// Output implements CommandRunner
+func (r *ExecRunner) Output(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+// CombinedOutput implements CommandRunner
+func (r *ExecRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// CommandLine joins a command and its arguments the way fixtures are keyed
+func CommandLine(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
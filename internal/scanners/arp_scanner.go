@@ -0,0 +1,198 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+// Defaults for the ARP binding tracker
+const (
+	// DefaultARPBindingWindow is how long a binding is remembered. An address
+	// that changes MAC after being silent for longer is treated as reassigned.
+	DefaultARPBindingWindow = time.Hour
+	// DefaultARPMaxIPsPerMAC is how many addresses one MAC may answer for
+	DefaultARPMaxIPsPerMAC = 3
+)
+
+// ARPScanner tracks IP-to-MAC bindings over time, from the network scanner's
+// ARP sweeps and the kernel ARP table, and reports ARP cache poisoning
+type ARPScanner struct {
+	network     *NetworkScanner
+	arpTable    func() ([]netinfo.ARPEntry, error)
+	gateways    func() ([]net.IP, error)
+	window      time.Duration
+	maxIPs      int
+	bindings    map[string]arpBinding // by IP
+	gatewayMACs map[string]string     // by gateway IP, kept for the lifetime of the scanner
+	reported    map[string]int        // MAC to the address count last reported
+	mu          sync.Mutex
+}
+
+type arpBinding struct {
+	mac      string
+	lastSeen time.Time
+}
+
+// NewARPScanner creates an ARP spoofing detector. Bindings answered to the
+// sweeps of network are included when network is not nil.
+func NewARPScanner(network *NetworkScanner) *ARPScanner {
+	return &ARPScanner{
+		network:     network,
+		arpTable:    netinfo.ARPTable,
+		gateways:    netinfo.DefaultGateways,
+		window:      DefaultARPBindingWindow,
+		maxIPs:      DefaultARPMaxIPsPerMAC,
+		bindings:    make(map[string]arpBinding),
+		gatewayMACs: make(map[string]string),
+		reported:    make(map[string]int),
+	}
+}
+
+// SetMaxIPsPerMAC sets how many addresses one MAC may answer for before it
+// is reported. Values below 1 keep the default.
+func (s *ARPScanner) SetMaxIPsPerMAC(max int) {
+	if max > 0 {
+		s.maxIPs = max
+	}
+}
+
+// Name implements Scanner
+func (s *ARPScanner) Name() string {
+	return "arp"
+}
+
+// Scan implements Scanner by collecting the current IP-to-MAC bindings
+func (s *ARPScanner) Scan() ([]interface{}, error) {
+	var bindings []models.ARPBinding
+	if s.network != nil {
+		bindings = s.network.SweptBindings()
+	}
+
+	entries, err := s.arpTable()
+	if err != nil && len(bindings) == 0 {
+		return nil, fmt.Errorf("failed to read ARP table: %v", err)
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		bindings = append(bindings, models.ARPBinding{
+			IP:        entry.IP.String(),
+			MAC:       strings.ToUpper(entry.MAC.String()),
+			Interface: entry.Device,
+			Source:    "arp_table",
+			SeenAt:    now,
+		})
+	}
+
+	return toInterfaces(bindings), nil
+}
+
+// Detect implements Scanner by comparing the bindings with those seen before
+func (s *ARPScanner) Detect(devices []interface{}) []models.Attack {
+	return s.observe(fromInterfaces[models.ARPBinding](devices), time.Now())
+}
+
+// Health implements Scanner
+func (s *ARPScanner) Health() models.ScannerHealth {
+	health := models.ScannerHealth{Name: s.Name(), Available: true}
+	if _, err := os.Stat(netinfo.ARPFile); err != nil {
+		health.Available = s.network != nil
+		health.Message = fmt.Sprintf("%s is not readable; only ARP sweep results are checked", netinfo.ARPFile)
+	}
+	return health
+}
+
+// observe records bindings seen at now and reports flipped bindings, a
+// changed gateway MAC and MACs answering for too many addresses
+func (s *ARPScanner) observe(bindings []models.ARPBinding, now time.Time) []models.Attack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gateways := make(map[string]bool)
+	if ips, err := s.gateways(); err == nil {
+		for _, ip := range ips {
+			gateways[ip.String()] = true
+		}
+	}
+
+	var attacks []models.Attack
+	for _, binding := range bindings {
+		if binding.IP == "" || binding.MAC == "" {
+			continue
+		}
+
+		if gateways[binding.IP] {
+			if previous := s.gatewayMACs[binding.IP]; previous != "" && previous != binding.MAC {
+				attacks = append(attacks, models.Attack{
+					Type:        "ARP_SPOOFING",
+					Severity:    models.SeverityHigh,
+					Description: fmt.Sprintf("Gateway %s changed MAC from %s to %s (%s)", binding.IP, previous, binding.MAC, binding.Source),
+					Target:      binding.MAC,
+					Timestamp:   now,
+				})
+			}
+			s.gatewayMACs[binding.IP] = binding.MAC
+		} else if previous, ok := s.bindings[binding.IP]; ok && previous.mac != binding.MAC && now.Sub(previous.lastSeen) <= s.window {
+			attacks = append(attacks, models.Attack{
+				Type:        "ARP_SPOOFING",
+				Severity:    models.SeverityHigh,
+				Description: fmt.Sprintf("ARP binding for %s flipped from %s to %s (%s)", binding.IP, previous.mac, binding.MAC, binding.Source),
+				Target:      binding.MAC,
+				Timestamp:   now,
+			})
+		}
+
+		s.bindings[binding.IP] = arpBinding{mac: binding.MAC, lastSeen: now}
+	}
+
+	return append(attacks, s.detectSharedMACs(now)...)
+}
+
+// detectSharedMACs reports MACs that currently answer for more addresses
+// than allowed, once per increase in the number of addresses
+func (s *ARPScanner) detectSharedMACs(now time.Time) []models.Attack {
+	ipsByMAC := make(map[string][]string)
+	for ip, binding := range s.bindings {
+		if now.Sub(binding.lastSeen) > s.window {
+			delete(s.bindings, ip)
+			continue
+		}
+		ipsByMAC[binding.mac] = append(ipsByMAC[binding.mac], ip)
+	}
+
+	var macs []string
+	for mac := range ipsByMAC {
+		macs = append(macs, mac)
+	}
+	sort.Strings(macs)
+
+	var attacks []models.Attack
+	for mac := range s.reported {
+		if len(ipsByMAC[mac]) <= s.maxIPs {
+			delete(s.reported, mac)
+		}
+	}
+	for _, mac := range macs {
+		ips := ipsByMAC[mac]
+		if len(ips) <= s.maxIPs || len(ips) <= s.reported[mac] {
+			continue
+		}
+		s.reported[mac] = len(ips)
+		sort.Strings(ips)
+		attacks = append(attacks, models.Attack{
+			Type:        "ARP_SPOOFING",
+			Severity:    models.SeverityMedium,
+			Description: fmt.Sprintf("MAC %s answers for %d addresses: %s", mac, len(ips), strings.Join(ips, ", ")),
+			Target:      mac,
+			Timestamp:   now,
+		})
+	}
+	return attacks
+}
@@ -0,0 +1,137 @@
+package scanners
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/arpscan"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+func TestARPScannerDetectsSpoofing(t *testing.T) {
+	const (
+		gateway  = "192.168.1.1"
+		router   = "AA:AA:AA:00:00:01"
+		laptop   = "BB:BB:BB:00:00:02"
+		attacker = "EE:EE:EE:00:00:66"
+	)
+	binding := func(ip, mac string) models.ARPBinding {
+		return models.ARPBinding{IP: ip, MAC: mac, Source: "arp_table"}
+	}
+
+	tests := []struct {
+		name  string
+		scans [][]models.ARPBinding
+		gap   time.Duration // time between scans
+		want  []string      // descriptions raised by the last scan
+	}{
+		{
+			name:  "first sight is learned silently",
+			scans: [][]models.ARPBinding{{binding(gateway, router), binding("192.168.1.20", laptop)}},
+		},
+		{
+			name: "binding flip",
+			scans: [][]models.ARPBinding{
+				{binding("192.168.1.20", laptop)},
+				{binding("192.168.1.20", attacker)},
+			},
+			gap:  time.Minute,
+			want: []string{"ARP binding for 192.168.1.20 flipped from " + laptop + " to " + attacker + " (arp_table)"},
+		},
+		{
+			name: "reassignment after the binding expired",
+			scans: [][]models.ARPBinding{
+				{binding("192.168.1.20", laptop)},
+				{binding("192.168.1.20", attacker)},
+			},
+			gap: 2 * DefaultARPBindingWindow,
+		},
+		{
+			name: "gateway MAC change is reported even after a long gap",
+			scans: [][]models.ARPBinding{
+				{binding(gateway, router)},
+				{binding(gateway, attacker)},
+			},
+			gap:  2 * DefaultARPBindingWindow,
+			want: []string{"Gateway 192.168.1.1 changed MAC from " + router + " to " + attacker + " (arp_table)"},
+		},
+		{
+			name: "one MAC claiming many addresses",
+			scans: [][]models.ARPBinding{
+				{binding("192.168.1.20", attacker), binding("192.168.1.21", attacker), binding("192.168.1.22", attacker)},
+				{binding("192.168.1.23", attacker)},
+			},
+			gap:  time.Minute,
+			want: []string{"MAC " + attacker + " answers for 4 addresses: 192.168.1.20, 192.168.1.21, 192.168.1.22, 192.168.1.23"},
+		},
+		{
+			name: "many addresses are reported once",
+			scans: [][]models.ARPBinding{
+				{binding("192.168.1.20", attacker), binding("192.168.1.21", attacker), binding("192.168.1.22", attacker), binding("192.168.1.23", attacker)},
+				{binding("192.168.1.20", attacker)},
+			},
+			gap: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewARPScanner(nil)
+			s.gateways = func() ([]net.IP, error) { return []net.IP{net.ParseIP(gateway)}, nil }
+
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			var attacks []models.Attack
+			for _, scan := range tt.scans {
+				attacks = s.observe(scan, now)
+				now = now.Add(tt.gap)
+			}
+
+			var got []string
+			for _, attack := range attacks {
+				if attack.Type != "ARP_SPOOFING" || attack.Target != attacker {
+					t.Errorf("attack = %+v, want ARP_SPOOFING against %s", attack, attacker)
+				}
+				got = append(got, attack.Description)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("descriptions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestARPScannerCombinesSweepAndTable(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24")
+	ns.arpTable = func() ([]netinfo.ARPEntry, error) { return nil, nil }
+	ns.arpSweep = func(subnet netinfo.Subnet) ([]arpscan.Reply, error) {
+		return []arpscan.Reply{{IP: net.ParseIP("192.168.1.20").To4(), MAC: mustParseMAC(t, "ee:ee:ee:00:00:66")}}, nil
+	}
+	if _, _, err := ns.ScanNetwork(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewARPScanner(ns)
+	s.gateways = func() ([]net.IP, error) { return nil, nil }
+	s.arpTable = func() ([]netinfo.ARPEntry, error) {
+		return []netinfo.ARPEntry{{IP: net.ParseIP("192.168.1.20"), MAC: mustParseMAC(t, "bb:bb:bb:00:00:02"), Device: "eth0"}}, nil
+	}
+
+	devices, err := s.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindings := fromInterfaces[models.ARPBinding](devices)
+	if len(bindings) != 2 || bindings[0].Source != "arp_sweep" || bindings[1].Source != "arp_table" {
+		t.Fatalf("bindings = %+v, want sweep then table", bindings)
+	}
+
+	// The sweep and the kernel disagree about who owns the address
+	attacks := s.Detect(devices)
+	if len(attacks) != 1 || attacks[0].Target != "BB:BB:BB:00:00:02" {
+		t.Errorf("attacks = %+v, want one conflicting binding", attacks)
+	}
+}
@@ -12,11 +12,18 @@ import (
 	"time"
 
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/oui"
+	"github.com/boboTheFoff/shheissee-go/internal/rules"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
 )
 
 // BluetoothScanner handles Bluetooth device discovery and attack detection
 type BluetoothScanner struct {
 	knownDevices map[string]bool
+	runner       runner.CommandRunner
+	rules        *rules.Engine
+	scanDuration time.Duration
+	vendors      *oui.Database
 }
 
 // NewBluetoothScanner creates a new Bluetooth scanner
@@ -27,12 +34,55 @@ func NewBluetoothScanner(knownDevices []models.BluetoothDevice) *BluetoothScanne
 	}
 	return &BluetoothScanner{
 		knownDevices: knownMap,
+		runner:       runner.NewExecRunner(),
+		rules:        rules.Default(),
+		scanDuration: 2 * time.Second,
+		vendors:      oui.Embedded(),
 	}
 }
 
+// SetRunner replaces the runner used to execute external tools
+func (bs *BluetoothScanner) SetRunner(r runner.CommandRunner) {
+	bs.runner = r
+}
+
+// SetRules replaces the detection rules
+func (bs *BluetoothScanner) SetRules(engine *rules.Engine) {
+	bs.rules = engine
+}
+
+// SetVendors replaces the registry used to name the vendor of addresses
+func (bs *BluetoothScanner) SetVendors(db *oui.Database) {
+	bs.vendors = db
+}
+
+// Name implements Scanner
+func (bs *BluetoothScanner) Name() string {
+	return "bluetooth"
+}
+
+// Scan implements Scanner
+func (bs *BluetoothScanner) Scan() ([]interface{}, error) {
+	devices, err := bs.ScanBluetoothDevices()
+	if err != nil {
+		return nil, err
+	}
+	return toInterfaces(devices), nil
+}
+
+// Detect implements Scanner
+func (bs *BluetoothScanner) Detect(devices []interface{}) []models.Attack {
+	return bs.DetectBluetoothAttacks(fromInterfaces[models.BluetoothDevice](devices))
+}
+
+// Health implements Scanner
+func (bs *BluetoothScanner) Health() models.ScannerHealth {
+	return toolHealth(bs.runner, bs.Name(), "bluetoothctl")
+}
+
 // ScanBluetoothDevices discovers nearby Bluetooth devices
 func (bs *BluetoothScanner) ScanBluetoothDevices() ([]models.BluetoothDevice, error) {
-	if !isCommandAvailable("bluetoothctl") {
+	if !bs.runner.Available("bluetoothctl") {
 		// Try other methods or return empty
 		return []models.BluetoothDevice{}, fmt.Errorf("bluetoothctl not available")
 	}
@@ -47,25 +97,27 @@ func (bs *BluetoothScanner) ScanBluetoothDevices() ([]models.BluetoothDevice, er
 		}
 	}
 
+	for i := range devices {
+		devices[i].Vendor = bs.vendors.Lookup(devices[i].Address)
+		devices[i].LocallyAdministered = oui.IsLocallyAdministered(devices[i].Address)
+	}
+
 	return devices, nil
 }
 
 // scanWithBluetoothctl uses bluetoothctl to scan for devices
 func (bs *BluetoothScanner) scanWithBluetoothctl() ([]models.BluetoothDevice, error) {
 	// Start scan
-	scanCmd := exec.Command("bluetoothctl", "scan", "on")
-	scanCmd.Start()
+	bs.runner.Start("bluetoothctl", "scan", "on")
 
 	// Wait for scanning to start
-	time.Sleep(2 * time.Second)
+	time.Sleep(bs.scanDuration)
 
 	// Stop scan and list devices
-	stopScanCmd := exec.Command("bluetoothctl", "scan", "off")
-	stopScanCmd.Run()
+	bs.runner.Run("bluetoothctl", "scan", "off")
 
 	// Get device list
-	listCmd := exec.Command("bluetoothctl", "devices")
-	output, err := listCmd.CombinedOutput()
+	output, err := bs.runner.CombinedOutput("bluetoothctl", "devices")
 	if err != nil {
 		return nil, err
 	}
@@ -75,13 +127,12 @@ func (bs *BluetoothScanner) scanWithBluetoothctl() ([]models.BluetoothDevice, er
 
 // scanWithHcitool uses hcitool as alternative
 func (bs *BluetoothScanner) scanWithHcitool() ([]models.BluetoothDevice, error) {
-	if !isCommandAvailable("hcitool") {
+	if !bs.runner.Available("hcitool") {
 		return nil, fmt.Errorf("hcitool not available")
 	}
 
 	// Scan for devices
-	cmd := exec.Command("hcitool", "scan", "--flush")
-	output, err := cmd.CombinedOutput()
+	output, err := bs.runner.CombinedOutput("hcitool", "scan", "--flush")
 	if err != nil {
 		return nil, err
 	}
@@ -162,21 +213,12 @@ func (bs *BluetoothScanner) parseHcitoolOutput(output string) []models.Bluetooth
 
 // DetectBluetoothAttacks analyzes Bluetooth devices for attack patterns
 func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDevice) []models.Attack {
-	var attacks []models.Attack
-
-	// KNOB Attack Detection - Very close proximity devices
-	for _, device := range devices {
-		rssi := device.RSSI
-		if rssi > -20 { // Very close devices
-			attacks = append(attacks, models.Attack{
-				Type:        "KNOB_ATTACK",
-				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Potential KNOB attack: Device extremely close (%s, RSSI: %d)", device.Name, rssi),
-				Target:      device.Address,
-				Timestamp:   time.Now(),
-			})
-		}
+	// Per-device checks (proximity, suspicious names, ...) come from the rules file
+	records := make([]rules.Fields, len(devices))
+	for i, device := range devices {
+		records[i] = bluetoothFields(device)
 	}
+	attacks := bs.rules.Evaluate(rules.SourceBluetooth, records)
 
 	// BIAS Attack Detection - Duplicate device names
 	nameCounts := make(map[string][]string)
@@ -209,74 +251,6 @@ func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDev
 		})
 	}
 
-	// BLE Relay Attack Detection - Weak signals
-	for _, device := range devices {
-		if device.RSSI < -80 { // Very weak signal
-			attacks = append(attacks, models.Attack{
-				Type:        "BLE_RELAY_ATTACK",
-				Severity:    models.SeverityMedium,
-				Description: fmt.Sprintf("Potential BLE relay attack: Weak signal device (%s, RSSI: %d)", device.Name, device.RSSI),
-				Target:      device.Address,
-				Timestamp:   time.Now(),
-			})
-		}
-	}
-
-	// BlueBorne Vulnerability Detection
-	vulnerablePatterns := map[string]string{
-		"android": "Android device - check for BlueBorne vulnerabilities",
-		"ios":     "iOS device - check for BlueBorne vulnerabilities",
-		"linux":   "Linux device - check for BlueBorne vulnerabilities",
-		"windows": "Windows device - check for BlueBorne vulnerabilities",
-	}
-
-	for _, device := range devices {
-		nameLower := strings.ToLower(device.Name)
-		for pattern, desc := range vulnerablePatterns {
-			if strings.Contains(nameLower, pattern) {
-				attacks = append(attacks, models.Attack{
-					Type:        "BLUEBORNE_VULNERABILITY",
-					Severity:    models.SeverityHigh,
-					Description: fmt.Sprintf("Potential BlueBorne vulnerable device: %s (%s) - %s", device.Name, device.Address, desc),
-					Target:      device.Address,
-					Timestamp:   time.Now(),
-				})
-				break
-			}
-		}
-	}
-
-	// Proximity Attack Detection
-	for _, device := range devices {
-		if device.RSSI > -30 { // Too close
-			attacks = append(attacks, models.Attack{
-				Type:        "BLUETOOTH_PROXIMITY",
-				Severity:    models.SeverityMedium,
-				Description: fmt.Sprintf("Device too close (possible attack): %s (%s, RSSI: %d)", device.Name, device.Address, device.RSSI),
-				Target:      device.Address,
-				Timestamp:   time.Now(),
-			})
-		}
-	}
-
-	// Spoofing Detection
-	suspiciousNames := []string{"attack", "hack", "exploit", "test", "spoof", "evil", "malware", "virus"}
-	for _, device := range devices {
-		nameLower := strings.ToLower(device.Name)
-		for _, suspicious := range suspiciousNames {
-			if strings.Contains(nameLower, suspicious) {
-				attacks = append(attacks, models.Attack{
-					Type:        "BLUETOOTH_SPOOFING",
-					Severity:    models.SeverityHigh,
-					Description: fmt.Sprintf("Suspicious Bluetooth device name: %s (%s)", device.Name, device.Address),
-					Target:      device.Address,
-					Timestamp:   time.Now(),
-				})
-				break
-			}
-		}
-	}
-
 	// BLE Flooding Detection
 	bleDevices := 0
 	for _, device := range devices {
@@ -296,30 +270,6 @@ func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDev
 		})
 	}
 
-	// Man-in-the-Middle Detection
-	mitmPatterns := map[string]string{
-		"proxy":    "proxy",
-		"gateway":  "gateway",
-		"bridge":   "bridge",
-		"intercept": "intercept",
-	}
-
-	for _, device := range devices {
-		nameLower := strings.ToLower(device.Name)
-		for pattern, desc := range mitmPatterns {
-			if strings.Contains(nameLower, pattern) {
-				attacks = append(attacks, models.Attack{
-					Type:        "BLUETOOTH_MITM",
-					Severity:    models.SeverityHigh,
-					Description: fmt.Sprintf("Potential Man-in-the-Middle device: %s (%s) - appears to be %s", device.Name, device.Address, desc),
-					Target:      device.Address,
-					Timestamp:   time.Now(),
-				})
-				break
-			}
-		}
-	}
-
 	// Unknown Device Detection
 	for _, device := range devices {
 		if !bs.knownDevices[device.Address] {
@@ -336,9 +286,25 @@ func (bs *BluetoothScanner) DetectBluetoothAttacks(devices []models.BluetoothDev
 	return attacks
 }
 
+// bluetoothFields exposes a device to the detection rules. An RSSI of zero
+// means the scan did not report one, so the field is left out.
+func bluetoothFields(device models.BluetoothDevice) rules.Fields {
+	fields := rules.Fields{
+		"address":              device.Address,
+		"name":                 device.Name,
+		"vendor":               device.Vendor,
+		"locally_administered": device.LocallyAdministered,
+		"status":               device.Status,
+	}
+	if device.RSSI != 0 {
+		fields["rssi"] = device.RSSI
+	}
+	return fields
+}
+
 // MonitorBluetoothConnections monitors Bluetooth connection attempts
 func (bs *BluetoothScanner) MonitorBluetoothConnections() (<-chan models.Attack, error) {
-	if !isCommandAvailable("bluetoothctl") {
+	if !bs.runner.Available("bluetoothctl") {
 		return nil, fmt.Errorf("bluetoothctl not available for connection monitoring")
 	}
 
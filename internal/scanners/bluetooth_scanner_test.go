@@ -0,0 +1,84 @@
+package scanners
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+)
+
+func TestParseBluetoothctlOutput(t *testing.T) {
+	known := []models.BluetoothDevice{{Address: "AA:BB:CC:DD:EE:FF"}}
+
+	tests := []struct {
+		name   string
+		output string
+		want   []models.BluetoothDevice
+	}{
+		{
+			name:   "no devices",
+			output: "",
+			want:   nil,
+		},
+		{
+			name:   "known and unknown devices",
+			output: "Device AA:BB:CC:DD:EE:FF Pixel 7\nDevice 11:22:33:44:55:66 JBL Flip 5\n",
+			want: []models.BluetoothDevice{
+				{Address: "AA:BB:CC:DD:EE:FF", Name: "Pixel 7", Status: "Known"},
+				{Address: "11:22:33:44:55:66", Name: "JBL Flip 5", Status: "Unknown"},
+			},
+		},
+		{
+			name:   "ignores controller and prompt lines",
+			output: "Controller 00:1A:7D:DA:71:13 host [default]\n[bluetooth]# \nDevice 11:22:33:44:55:66 JBL Flip 5\n",
+			want: []models.BluetoothDevice{
+				{Address: "11:22:33:44:55:66", Name: "JBL Flip 5", Status: "Unknown"},
+			},
+		},
+		{
+			name:   "device without a name",
+			output: "Device 66:55:44:33:22:11\n",
+			want:   nil,
+		},
+	}
+
+	bs := NewBluetoothScanner(known)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bs.parseBluetoothctlOutput(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBluetoothctlOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanBluetoothDevicesWithRecordedBluetoothctl(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("bluetoothctl")
+	if err := replay.AddFile("bluetoothctl devices", "testdata/bluetoothctl_devices.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := NewBluetoothScanner(nil)
+	bs.SetRunner(replay)
+	bs.scanDuration = 0
+
+	devices, err := bs.ScanBluetoothDevices()
+	if err != nil {
+		t.Fatalf("ScanBluetoothDevices() error = %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("got %d devices, want 3", len(devices))
+	}
+	// AA:BB:CC:DD:EE:FF has the locally administered bit set
+	if !devices[0].LocallyAdministered || devices[1].LocallyAdministered {
+		t.Errorf("locally administered = %v, %v", devices[0].LocallyAdministered, devices[1].LocallyAdministered)
+	}
+
+	wantCalls := []string{"bluetoothctl scan on", "bluetoothctl scan off", "bluetoothctl devices"}
+	if got := replay.Calls(); !reflect.DeepEqual(got, wantCalls) {
+		t.Errorf("calls = %v, want %v", got, wantCalls)
+	}
+}
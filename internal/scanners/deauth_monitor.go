@@ -0,0 +1,140 @@
+package scanners
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/dot11"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// DeauthMonitor counts deauthentication and disassociation frames per access
+// point and per client over a sliding window and reports floods. Windows are
+// measured on the frames' capture times, so a pcap file replays the same way
+// as a live capture. Each flood is reported once per window.
+type DeauthMonitor struct {
+	window       time.Duration
+	maxPerBSSID  int
+	maxPerClient int
+	frames       map[string][]time.Time // "bssid/<MAC>" or "client/<MAC>" -> frame times
+	ssids        map[string]string      // BSSID -> SSID learned from beacons and probe responses
+	alerted      map[string]time.Time
+	mu           sync.Mutex
+}
+
+// NewDeauthMonitor creates a monitor that raises an attack once a single
+// access point sees maxPerBSSID, or a single client maxPerClient, frames
+// within window
+func NewDeauthMonitor(window time.Duration, maxPerBSSID, maxPerClient int) *DeauthMonitor {
+	return &DeauthMonitor{
+		window:       window,
+		maxPerBSSID:  maxPerBSSID,
+		maxPerClient: maxPerClient,
+		frames:       make(map[string][]time.Time),
+		ssids:        make(map[string]string),
+		alerted:      make(map[string]time.Time),
+	}
+}
+
+// Observe adds captured frames, in capture order, and returns the floods
+// they complete
+func (m *DeauthMonitor) Observe(frames []dot11.Frame) []models.Attack {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var attacks []models.Attack
+	var latest time.Time
+	for _, frame := range frames {
+		latest = frame.Time
+
+		switch frame.Subtype {
+		case dot11.Beacon, dot11.ProbeResponse:
+			if frame.SSID != "" {
+				m.ssids[frame.BSSID] = frame.SSID
+			}
+			continue
+		case dot11.Deauthentication, dot11.Disassociation:
+		default:
+			continue
+		}
+
+		// Either side may send the frame; the client is the other party
+		client := frame.Source
+		if client == frame.BSSID {
+			client = frame.Destination
+		}
+
+		if frame.BSSID != dot11.Broadcast {
+			key := "bssid/" + frame.BSSID
+			if count := m.count(key, frame.Time); count >= m.maxPerBSSID && m.shouldAlert(key, frame.Time) {
+				attacks = append(attacks, models.Attack{
+					Type:     "WIFI_DEAUTH_ATTACK",
+					Severity: models.SeverityHigh,
+					Description: fmt.Sprintf("Deauthentication flood against %s: %d deauth/disassoc frames within %s",
+						m.apName(frame.BSSID), count, m.window),
+					Target:    frame.BSSID,
+					Timestamp: frame.Time,
+				})
+			}
+		}
+		if client != dot11.Broadcast {
+			key := "client/" + client
+			if count := m.count(key, frame.Time); count >= m.maxPerClient && m.shouldAlert(key, frame.Time) {
+				attacks = append(attacks, models.Attack{
+					Type:     "WIFI_DEAUTH_ATTACK",
+					Severity: models.SeverityHigh,
+					Description: fmt.Sprintf("Deauthentication flood against client %s of %s: %d deauth/disassoc frames within %s",
+						client, m.apName(frame.BSSID), count, m.window),
+					Target:    client,
+					Timestamp: frame.Time,
+				})
+			}
+		}
+	}
+
+	m.expire(latest)
+	return attacks
+}
+
+// count adds a frame at t to key and returns the frames within the window
+func (m *DeauthMonitor) count(key string, t time.Time) int {
+	times := append(m.frames[key], t)
+	start := 0
+	for start < len(times) && t.Sub(times[start]) >= m.window {
+		start++
+	}
+	m.frames[key] = times[start:]
+	return len(m.frames[key])
+}
+
+// shouldAlert reports whether key has not raised an attack within the window
+func (m *DeauthMonitor) shouldAlert(key string, t time.Time) bool {
+	if last, ok := m.alerted[key]; ok && t.Sub(last) < m.window {
+		return false
+	}
+	m.alerted[key] = t
+	return true
+}
+
+// expire forgets frames and alerts that fell out of the window
+func (m *DeauthMonitor) expire(now time.Time) {
+	for key, times := range m.frames {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= m.window {
+			delete(m.frames, key)
+		}
+	}
+	for key, last := range m.alerted {
+		if now.Sub(last) >= m.window {
+			delete(m.alerted, key)
+		}
+	}
+}
+
+// apName names an access point by BSSID and, if known, SSID
+func (m *DeauthMonitor) apName(bssid string) string {
+	if ssid := m.ssids[bssid]; ssid != "" {
+		return fmt.Sprintf("%s (%s)", bssid, ssid)
+	}
+	return bssid
+}
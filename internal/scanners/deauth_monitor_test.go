@@ -0,0 +1,63 @@
+package scanners
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/dot11"
+)
+
+func TestDeauthMonitorDetectsFloods(t *testing.T) {
+	frames, err := dot11.ReadPcapFile("testdata/deauth_flood.pcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewDeauthMonitor(10*time.Second, 30, 10)
+	attacks := m.Observe(frames)
+
+	var got []string
+	for _, attack := range attacks {
+		got = append(got, attack.Description)
+	}
+	want := []string{
+		"Deauthentication flood against client AA:BB:CC:00:00:01 of 3C:84:6A:12:34:56 (SmithHome): 10 deauth/disassoc frames within 10s",
+		"Deauthentication flood against 3C:84:6A:12:34:56 (SmithHome): 30 deauth/disassoc frames within 10s",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("attacks = %q, want %q", got, want)
+	}
+	if !attacks[0].Timestamp.Equal(time.Unix(1714564801, 900000000)) {
+		t.Errorf("timestamp = %v", attacks[0].Timestamp)
+	}
+
+	// The same frames a window later raise the floods again
+	later := make([]dot11.Frame, len(frames))
+	for i, frame := range frames {
+		frame.Time = frame.Time.Add(time.Minute)
+		later[i] = frame
+	}
+	if attacks := m.Observe(later); len(attacks) != 2 {
+		t.Errorf("second window attacks = %+v", attacks)
+	}
+}
+
+func TestDeauthMonitorSlidingWindow(t *testing.T) {
+	m := NewDeauthMonitor(time.Second, 3, 100)
+	base := time.Unix(1714564800, 0)
+	deauth := func(offset time.Duration) dot11.Frame {
+		return dot11.Frame{Time: base.Add(offset), Subtype: dot11.Deauthentication,
+			Source: "3C:84:6A:12:34:56", BSSID: "3C:84:6A:12:34:56", Destination: dot11.Broadcast}
+	}
+
+	// Three frames spread over more than a second never fill the window
+	slow := []dot11.Frame{deauth(0), deauth(600 * time.Millisecond), deauth(1200 * time.Millisecond), deauth(1800 * time.Millisecond)}
+	if attacks := m.Observe(slow); len(attacks) != 0 {
+		t.Errorf("slow frames raised %+v", attacks)
+	}
+	// A burst does, once
+	burst := []dot11.Frame{deauth(5 * time.Second), deauth(5100 * time.Millisecond), deauth(5200 * time.Millisecond), deauth(5300 * time.Millisecond)}
+	if attacks := m.Observe(burst); len(attacks) != 1 || attacks[0].Target != "3C:84:6A:12:34:56" {
+		t.Errorf("burst raised %+v", attacks)
+	}
+}
@@ -0,0 +1,177 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/dhcpprobe"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+// DHCPScanner broadcasts DHCP discovery probes on the scanned IPv4 subnets
+// and reports offers from servers that are not authorized
+type DHCPScanner struct {
+	authorized map[string]bool // server IPs and upper-case MACs
+	subnets    func() ([]netinfo.Subnet, error)
+	probe      func(iface string) ([]dhcpprobe.Offer, error)
+	check      func() error
+	seen       string // servers last reported while none are authorized
+	mu         sync.Mutex
+}
+
+// NewDHCPScanner creates a rogue DHCP server detector probing the subnets
+// selected for network discovery. Authorized servers are IP or MAC addresses.
+func NewDHCPScanner(network *NetworkScanner, authorized []string) *DHCPScanner {
+	authorizedMap := make(map[string]bool)
+	for _, server := range authorized {
+		if mac, err := net.ParseMAC(server); err == nil {
+			server = strings.ToUpper(mac.String())
+		}
+		authorizedMap[server] = true
+	}
+	return &DHCPScanner{
+		authorized: authorizedMap,
+		subnets:    network.Subnets,
+		probe: func(iface string) ([]dhcpprobe.Offer, error) {
+			return dhcpprobe.Probe(iface, dhcpprobe.Options{})
+		},
+		check: dhcpprobe.Check,
+	}
+}
+
+// Name implements Scanner
+func (s *DHCPScanner) Name() string {
+	return "dhcp"
+}
+
+// Scan implements Scanner by collecting the offers made on each interface
+// with a scanned IPv4 subnet
+func (s *DHCPScanner) Scan() ([]interface{}, error) {
+	subnets, err := s.subnets()
+	if err != nil {
+		return nil, err
+	}
+
+	var offers []models.DHCPOffer
+	var errs []string
+	probed := make(map[string]bool)
+	for _, subnet := range subnets {
+		if !subnet.IsIPv4() || probed[subnet.Interface] {
+			continue
+		}
+		probed[subnet.Interface] = true
+
+		received, err := s.probe(subnet.Interface)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", subnet.Interface, err))
+		}
+		for _, offer := range received {
+			offers = append(offers, offerModel(subnet.Interface, offer))
+		}
+	}
+
+	if len(errs) > 0 && len(errs) == len(probed) {
+		return nil, fmt.Errorf("DHCP probe failed on %s", strings.Join(errs, "; "))
+	}
+	return toInterfaces(offers), nil
+}
+
+// Detect implements Scanner by flagging offers from unauthorized servers.
+// Until servers are authorized, the legitimate one cannot be told apart, so
+// only the servers seen are reported.
+func (s *DHCPScanner) Detect(devices []interface{}) []models.Attack {
+	offers := fromInterfaces[models.DHCPOffer](devices)
+	if len(s.authorized) == 0 {
+		return s.reportUnconfigured(offers)
+	}
+
+	var attacks []models.Attack
+	for _, offer := range offers {
+		if s.authorized[offer.Server] || (offer.ServerMAC != "" && s.authorized[offer.ServerMAC]) {
+			continue
+		}
+		attacks = append(attacks, models.Attack{
+			Type:     "ROGUE_DHCP",
+			Severity: models.SeverityHigh,
+			Description: fmt.Sprintf("Rogue DHCP server %s (%s) on %s offering %s with gateway %s and DNS %s",
+				offer.Server, offer.ServerMAC, offer.Interface, offer.OfferedIP, listOrNone(offer.Gateways), listOrNone(offer.DNS)),
+			Target:    offer.Server,
+			Timestamp: time.Now(),
+		})
+	}
+
+	return attacks
+}
+
+// reportUnconfigured raises one LOW attack naming the servers that made
+// offers, again only when they change
+func (s *DHCPScanner) reportUnconfigured(offers []models.DHCPOffer) []models.Attack {
+	var servers []string
+	listed := make(map[string]bool)
+	for _, offer := range offers {
+		server := fmt.Sprintf("%s (%s) on %s", offer.Server, offer.ServerMAC, offer.Interface)
+		if !listed[server] {
+			listed[server] = true
+			servers = append(servers, server)
+		}
+	}
+	sort.Strings(servers)
+	seen := strings.Join(servers, ", ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(servers) == 0 || seen == s.seen {
+		return nil
+	}
+	s.seen = seen
+
+	return []models.Attack{{
+		Type:        "DHCP_UNCONFIGURED",
+		Severity:    models.SeverityLow,
+		Description: fmt.Sprintf("No authorized DHCP servers configured; offers received from %s", seen),
+		Target:      "dhcp",
+		Timestamp:   time.Now(),
+	}}
+}
+
+// Health implements Scanner
+func (s *DHCPScanner) Health() models.ScannerHealth {
+	health := models.ScannerHealth{Name: s.Name(), Available: true}
+	if err := s.check(); err != nil {
+		health.Available = false
+		health.Message = fmt.Sprintf("DHCP probing needs CAP_NET_RAW: %v", err)
+	}
+	return health
+}
+
+// offerModel converts a received offer to the scan result model
+func offerModel(iface string, offer dhcpprobe.Offer) models.DHCPOffer {
+	result := models.DHCPOffer{
+		Server:     offer.Server.String(),
+		ServerMAC:  strings.ToUpper(offer.ServerMAC.String()),
+		Interface:  iface,
+		DomainName: offer.DomainName,
+	}
+	if offer.OfferedIP != nil && !offer.OfferedIP.IsUnspecified() {
+		result.OfferedIP = offer.OfferedIP.String()
+	}
+	for _, gateway := range offer.Gateways {
+		result.Gateways = append(result.Gateways, gateway.String())
+	}
+	for _, dns := range offer.DNS {
+		result.DNS = append(result.DNS, dns.String())
+	}
+	return result
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,106 @@
+package scanners
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/dhcpprobe"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestDHCPScannerFlagsUnauthorizedServers(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24", "eth0 fd00::/64", "wlan0 10.0.0.0/24")
+
+	s := NewDHCPScanner(ns, []string{"192.168.1.1", "aa:aa:aa:00:00:01"})
+	var probed []string
+	s.probe = func(iface string) ([]dhcpprobe.Offer, error) {
+		probed = append(probed, iface)
+		if iface != "eth0" {
+			return nil, nil
+		}
+		return []dhcpprobe.Offer{
+			{Server: net.ParseIP("192.168.1.1"), ServerMAC: mustParseMAC(t, "aa:aa:aa:00:00:01"), OfferedIP: net.ParseIP("192.168.1.50")},
+			// A server known only by MAC that changed its address
+			{Server: net.ParseIP("192.168.1.2"), ServerMAC: mustParseMAC(t, "aa:aa:aa:00:00:01")},
+			{
+				Server:    net.ParseIP("192.168.1.66"),
+				ServerMAC: mustParseMAC(t, "ee:ee:ee:00:00:66"),
+				OfferedIP: net.ParseIP("192.168.1.51"),
+				Gateways:  []net.IP{net.ParseIP("192.168.1.66")},
+				DNS:       []net.IP{net.ParseIP("192.168.1.66"), net.ParseIP("8.8.8.8")},
+			},
+		}, nil
+	}
+
+	devices, err := s.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(probed) != 2 || probed[0] != "eth0" || probed[1] != "wlan0" {
+		t.Errorf("probed interfaces = %v, want eth0 and wlan0 once each", probed)
+	}
+	if offers := fromInterfaces[models.DHCPOffer](devices); len(offers) != 3 || offers[2].Interface != "eth0" {
+		t.Fatalf("offers = %+v", offers)
+	}
+
+	attacks := s.Detect(devices)
+	if len(attacks) != 1 {
+		t.Fatalf("attacks = %+v, want one rogue server", attacks)
+	}
+	want := "Rogue DHCP server 192.168.1.66 (EE:EE:EE:00:00:66) on eth0 offering 192.168.1.51 with gateway 192.168.1.66 and DNS 192.168.1.66, 8.8.8.8"
+	if attacks[0].Type != "ROGUE_DHCP" || attacks[0].Target != "192.168.1.66" || attacks[0].Description != want {
+		t.Errorf("attack = %+v", attacks[0])
+	}
+}
+
+func TestDHCPScannerWithoutAuthorizedServers(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24")
+
+	s := NewDHCPScanner(ns, nil)
+	offers := []dhcpprobe.Offer{{Server: net.ParseIP("192.168.1.1"), ServerMAC: mustParseMAC(t, "aa:aa:aa:00:00:01")}}
+	s.probe = func(string) ([]dhcpprobe.Offer, error) { return offers, nil }
+
+	detect := func() []models.Attack {
+		devices, err := s.Scan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Detect(devices)
+	}
+
+	attacks := detect()
+	want := "No authorized DHCP servers configured; offers received from 192.168.1.1 (AA:AA:AA:00:00:01) on eth0"
+	if len(attacks) != 1 || attacks[0].Type != "DHCP_UNCONFIGURED" || attacks[0].Severity != models.SeverityLow || attacks[0].Description != want {
+		t.Fatalf("attacks = %+v, want one LOW DHCP_UNCONFIGURED", attacks)
+	}
+
+	// The same servers are not reported again, a new one is
+	if attacks := detect(); len(attacks) != 0 {
+		t.Errorf("attacks = %+v, want none for the same servers", attacks)
+	}
+	offers = append(offers, dhcpprobe.Offer{Server: net.ParseIP("192.168.1.66"), ServerMAC: mustParseMAC(t, "ee:ee:ee:00:00:66")})
+	if attacks := detect(); len(attacks) != 1 || !strings.Contains(attacks[0].Description, "192.168.1.66") {
+		t.Errorf("attacks = %+v, want one naming the new server", attacks)
+	}
+}
+
+func TestDHCPScannerReportsFailedProbes(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24")
+
+	s := NewDHCPScanner(ns, nil)
+	s.probe = func(string) ([]dhcpprobe.Offer, error) { return nil, errors.New("operation not permitted") }
+
+	if _, err := s.Scan(); err == nil || err.Error() != "DHCP probe failed on eth0: operation not permitted" {
+		t.Errorf("Scan() error = %v", err)
+	}
+
+	s.check = func() error { return dhcpprobe.ErrUnsupported }
+	if health := s.Health(); health.Available {
+		t.Errorf("Health() = %+v, want unavailable", health)
+	}
+}
@@ -0,0 +1,325 @@
+package scanners
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+// SystemResolver names the host's own resolver in DNS answers
+const SystemResolver = "system"
+
+// dnsLookupTimeout bounds each canary lookup
+const dnsLookupTimeout = 3 * time.Second
+
+// lookupFunc resolves a host name to its addresses
+type lookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+// DNSScanner resolves canary host names through the system resolver and
+// through pinned resolvers and reports answers that were tampered with
+type DNSScanner struct {
+	canaries    []string
+	pinned      []string // resolver addresses as host:port
+	system      lookupFunc
+	lookup      func(server string) lookupFunc
+	nameservers func() ([]net.IP, error)
+	nxName      func() string
+	lastServers []string
+	mu          sync.Mutex
+}
+
+// NewDNSScanner creates a DNS hijack detector. Pinned resolvers are IP
+// addresses with an optional port, such as 1.1.1.1 or 127.0.0.1:5353.
+func NewDNSScanner(canaries []string, pinned []string) (*DNSScanner, error) {
+	var servers []string
+	for _, resolver := range pinned {
+		server, err := resolverAddress(resolver)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+
+	return &DNSScanner{
+		canaries:    canaries,
+		pinned:      servers,
+		system:      systemLookup,
+		lookup:      pinnedLookup,
+		nameservers: netinfo.Nameservers,
+		nxName:      randomName,
+	}, nil
+}
+
+// Name implements Scanner
+func (s *DNSScanner) Name() string {
+	return "dns"
+}
+
+// Scan implements Scanner by resolving every canary and a name that does not
+// exist through each resolver, and reading the configured name servers
+func (s *DNSScanner) Scan() ([]interface{}, error) {
+	report := models.DNSReport{}
+
+	// Without a readable resolv.conf (as on Android) only the answers are checked
+	if servers, err := s.nameservers(); err == nil {
+		report.Nameservers = []string{}
+		for _, server := range servers {
+			report.Nameservers = append(report.Nameservers, server.String())
+		}
+	}
+
+	type query struct {
+		host    string
+		nxCheck bool
+	}
+	queries := make([]query, 0, len(s.canaries)+1)
+	for _, host := range s.canaries {
+		queries = append(queries, query{host: host})
+	}
+	queries = append(queries, query{host: s.nxName(), nxCheck: true})
+
+	resolvers := append([]string{SystemResolver}, s.pinned...)
+	report.Answers = make([]models.DNSAnswer, len(queries)*len(resolvers))
+
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		for j, resolver := range resolvers {
+			lookup := s.system
+			if resolver != SystemResolver {
+				lookup = s.lookup(resolver)
+			}
+
+			report.Answers[i*len(resolvers)+j] = models.DNSAnswer{Resolver: resolver, Host: q.host, NXCheck: q.nxCheck}
+
+			wg.Add(1)
+			go func(answer *models.DNSAnswer, lookup lookupFunc, host string) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+				defer cancel()
+
+				// A rooted name is not expanded with the search domains
+				ips, err := lookup(ctx, strings.TrimSuffix(host, ".")+".")
+				var dnsErr *net.DNSError
+				switch {
+				case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+					answer.NXDomain = true
+				case err != nil:
+					answer.Error = err.Error()
+				}
+				for _, ip := range ips {
+					answer.Addresses = append(answer.Addresses, ip.String())
+				}
+				sort.Strings(answer.Addresses)
+			}(&report.Answers[i*len(resolvers)+j], lookup, q.host)
+		}
+	}
+	wg.Wait()
+
+	return toInterfaces([]models.DNSReport{report}), nil
+}
+
+// Detect implements Scanner by comparing the system resolver's answers with
+// the pinned resolvers' and the name servers with those of the last scan
+func (s *DNSScanner) Detect(devices []interface{}) []models.Attack {
+	var attacks []models.Attack
+
+	for _, report := range fromInterfaces[models.DNSReport](devices) {
+		attacks = append(attacks, s.detectNameserverChange(report.Nameservers)...)
+
+		byHost := make(map[string][]models.DNSAnswer)
+		var hosts []string
+		for _, answer := range report.Answers {
+			if _, ok := byHost[answer.Host]; !ok {
+				hosts = append(hosts, answer.Host)
+			}
+			byHost[answer.Host] = append(byHost[answer.Host], answer)
+		}
+		for _, host := range hosts {
+			attacks = append(attacks, compareAnswers(byHost[host])...)
+		}
+	}
+
+	return attacks
+}
+
+// Health implements Scanner
+func (s *DNSScanner) Health() models.ScannerHealth {
+	return models.ScannerHealth{Name: s.Name(), Available: true}
+}
+
+// detectNameserverChange reports a change of the resolv.conf name servers
+// since the last scan. Nil servers mean resolv.conf could not be read.
+func (s *DNSScanner) detectNameserverChange(servers []string) []models.Attack {
+	if servers == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.lastServers
+	s.lastServers = servers
+	if previous == nil || strings.Join(previous, ",") == strings.Join(servers, ",") {
+		return nil
+	}
+
+	// Point at the first added server, the likeliest culprit
+	target := netinfo.ResolvConfFile
+	for _, server := range servers {
+		if !containsString(previous, server) {
+			target = server
+			break
+		}
+	}
+
+	return []models.Attack{{
+		Type:     "DNS_HIJACK",
+		Severity: models.SeverityMedium,
+		Description: fmt.Sprintf("Name servers in %s changed from %s to %s",
+			netinfo.ResolvConfFile, listOrNone(previous), listOrNone(servers)),
+		Target:    target,
+		Timestamp: time.Now(),
+	}}
+}
+
+// compareAnswers checks the system resolver's answer for one host against
+// the pinned resolvers that answered
+func compareAnswers(answers []models.DNSAnswer) []models.Attack {
+	var system *models.DNSAnswer
+	var pinned []models.DNSAnswer
+	for i := range answers {
+		switch {
+		case answers[i].Resolver == SystemResolver:
+			system = &answers[i]
+		case answers[i].Error == "":
+			pinned = append(pinned, answers[i])
+		}
+	}
+	if system == nil || system.Error != "" {
+		return nil
+	}
+
+	if system.NXCheck {
+		// The name does not exist unless a pinned resolver says otherwise
+		if system.NXDomain || len(system.Addresses) == 0 {
+			return nil
+		}
+		for _, answer := range pinned {
+			if !answer.NXDomain {
+				return nil
+			}
+		}
+		return []models.Attack{{
+			Type:        "DNS_HIJACK",
+			Severity:    models.SeverityHigh,
+			Description: fmt.Sprintf("System resolver rewrote NXDOMAIN for %s to %s", system.Host, strings.Join(system.Addresses, ", ")),
+			Target:      system.Addresses[0],
+			Timestamp:   time.Now(),
+		}}
+	}
+
+	if len(pinned) == 0 {
+		return nil
+	}
+	var expected []string
+	for _, answer := range pinned {
+		if answer.NXDomain {
+			expected = append(expected, answer.Resolver+": NXDOMAIN")
+		} else {
+			expected = append(expected, answer.Resolver+": "+listOrNone(answer.Addresses))
+		}
+		if answer.NXDomain == system.NXDomain && overlaps(answer.Addresses, system.Addresses) {
+			return nil
+		}
+	}
+
+	got := "NXDOMAIN"
+	target := system.Host
+	if !system.NXDomain {
+		got = listOrNone(system.Addresses)
+		if len(system.Addresses) > 0 {
+			target = system.Addresses[0]
+		}
+	}
+	return []models.Attack{{
+		Type:     "DNS_HIJACK",
+		Severity: models.SeverityHigh,
+		Description: fmt.Sprintf("System resolver answered %s for %s, pinned resolvers answered %s",
+			got, system.Host, strings.Join(expected, "; ")),
+		Target:    target,
+		Timestamp: time.Now(),
+	}}
+}
+
+// overlaps reports whether two answers share an address. Two empty answers
+// agree.
+func overlaps(a []string, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	for _, address := range a {
+		if containsString(b, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// resolverAddress normalizes a pinned resolver to host:port
+func resolverAddress(resolver string) (string, error) {
+	if ip := net.ParseIP(resolver); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil || net.ParseIP(host) == nil || port == "" {
+		return "", fmt.Errorf("invalid pinned resolver %q: want an IP address with an optional port", resolver)
+	}
+	return resolver, nil
+}
+
+// systemLookup resolves names like any other program on the host
+func systemLookup(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// pinnedLookup resolves names by querying server directly
+func pinnedLookup(server string) lookupFunc {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		return resolver.LookupIP(ctx, "ip", host)
+	}
+}
+
+// randomName returns a name under .com that is all but certain not to exist
+func randomName() string {
+	label := make([]byte, 10)
+	if _, err := rand.Read(label); err != nil {
+		return "shheissee-nxdomain-check.com"
+	}
+	return "nx-" + hex.EncodeToString(label) + ".com"
+}
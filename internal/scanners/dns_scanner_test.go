@@ -0,0 +1,183 @@
+package scanners
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestDNSScannerDetectsHijacking(t *testing.T) {
+	honest := startStubResolver(t, map[string]string{"canary.test.": "192.0.2.10"}, "")
+	tests := []struct {
+		name      string
+		records   map[string]string
+		wildcard  string
+		want      []string
+		wantError bool
+	}{
+		{
+			name:    "same answers",
+			records: map[string]string{"canary.test.": "192.0.2.10"},
+		},
+		{
+			name:    "canary answer differs",
+			records: map[string]string{"canary.test.": "203.0.113.66"},
+			want:    []string{"System resolver answered 203.0.113.66 for canary.test, pinned resolvers answered " + honest + ": 192.0.2.10"},
+		},
+		{
+			name:     "NXDOMAIN rewritten",
+			records:  map[string]string{"canary.test.": "192.0.2.10"},
+			wildcard: "203.0.113.80",
+			want:     []string{"System resolver rewrote NXDOMAIN for nx-check.test to 203.0.113.80"},
+		},
+		{
+			name: "canary blocked",
+			want: []string{"System resolver answered NXDOMAIN for canary.test, pinned resolvers answered " + honest + ": 192.0.2.10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewDNSScanner([]string{"canary.test"}, []string{honest})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The system resolver is a second stub that may tamper with answers
+			s.system = pinnedLookup(startStubResolver(t, tt.records, tt.wildcard))
+			s.nameservers = func() ([]net.IP, error) { return nil, nil }
+			s.nxName = func() string { return "nx-check.test" }
+
+			devices, err := s.Scan()
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			var got []string
+			for _, attack := range s.Detect(devices) {
+				if attack.Type != "DNS_HIJACK" {
+					t.Errorf("attack type = %s", attack.Type)
+				}
+				got = append(got, attack.Description)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("descriptions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNSScannerDetectsNameserverChange(t *testing.T) {
+	s, err := NewDNSScanner(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(servers ...string) []interface{} {
+		return toInterfaces([]models.DNSReport{{Nameservers: servers}})
+	}
+	if attacks := s.Detect(report("192.168.1.1")); len(attacks) != 0 {
+		t.Errorf("first scan attacks = %+v", attacks)
+	}
+	// An unreadable resolv.conf is not a change
+	if attacks := s.Detect(toInterfaces([]models.DNSReport{{}})); len(attacks) != 0 {
+		t.Errorf("unreadable resolv.conf attacks = %+v", attacks)
+	}
+
+	attacks := s.Detect(report("203.0.113.53", "192.168.1.1"))
+	if len(attacks) != 1 || attacks[0].Target != "203.0.113.53" ||
+		attacks[0].Description != "Name servers in /etc/resolv.conf changed from 192.168.1.1 to 203.0.113.53, 192.168.1.1" {
+		t.Errorf("attacks = %+v", attacks)
+	}
+}
+
+func TestResolverAddress(t *testing.T) {
+	for input, want := range map[string]string{
+		"1.1.1.1":         "1.1.1.1:53",
+		"127.0.0.1:5353":  "127.0.0.1:5353",
+		"2606:4700::1111": "[2606:4700::1111]:53",
+		"[::1]:5353":      "[::1]:5353",
+		"dns.google":      "",
+		"1.1.1.1:":        "",
+	} {
+		got, err := resolverAddress(input)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("resolverAddress(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+}
+
+// startStubResolver serves A records on a local UDP port. Names without a
+// record get wildcard, if set, or NXDOMAIN. It returns the server address.
+func startStubResolver(t *testing.T, records map[string]string, wildcard string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if reply := stubReply(buf[:n], records, wildcard); reply != nil {
+				conn.WriteTo(reply, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// stubReply answers a single-question query
+func stubReply(query []byte, records map[string]string, wildcard string) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// Read the question name
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		length := int(query[i])
+		if i+1+length > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[i+1:i+1+length]))
+		i += 1 + length
+	}
+	if i+5 > len(query) {
+		return nil
+	}
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+	qtype := binary.BigEndian.Uint16(query[i+1 : i+3])
+	question := query[12 : i+5]
+
+	address, ok := records[name]
+	if !ok {
+		address = wildcard
+	}
+
+	reply := make([]byte, 12, 12+len(question)+16)
+	copy(reply[0:2], query[0:2])
+	flags := uint16(0x8180) // response, recursion desired and available
+	if address == "" {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(reply[2:4], flags)
+	binary.BigEndian.PutUint16(reply[4:6], 1)
+	reply = append(reply, question...)
+
+	// Only A records are served; other types get an empty answer
+	if address != "" && qtype == 1 {
+		binary.BigEndian.PutUint16(reply[6:8], 1)
+		reply = append(reply, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+		reply = append(reply, net.ParseIP(address).To4()...)
+	}
+	return reply
+}
@@ -0,0 +1,119 @@
+package scanners
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// EvilTwinDetector checks the access points advertising a trusted SSID
+// against the trusted-AP registry. An access point is a possible evil twin
+// when its BSSID is not approved, or when an approved BSSID shows up on an
+// unexpected channel, with weaker security or with a signal far from the one
+// recorded when it was trusted, as a spoofed BSSID would.
+type EvilTwinDetector struct {
+	networks        func() ([]models.TrustedNetwork, error)
+	signalDeviation int
+	onError         func(error)
+	mu              sync.Mutex
+}
+
+// NewEvilTwinDetector creates a detector over the trusted networks that
+// networks returns on every check. A signal more than signalDeviation dB
+// from the trusted one is anomalous; 0 disables the signal check.
+func NewEvilTwinDetector(networks func() ([]models.TrustedNetwork, error), signalDeviation int) *EvilTwinDetector {
+	return &EvilTwinDetector{
+		networks:        networks,
+		signalDeviation: signalDeviation,
+	}
+}
+
+// SetErrorHandler registers fn to be called when the registry cannot be read
+func (d *EvilTwinDetector) SetErrorHandler(fn func(error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onError = fn
+}
+
+// Check returns the evil twins among the scanned access points
+func (d *EvilTwinDetector) Check(devices []models.WiFiDevice) []models.Attack {
+	networks, err := d.networks()
+	if err != nil {
+		d.mu.Lock()
+		onError := d.onError
+		d.mu.Unlock()
+		if onError != nil {
+			onError(err)
+		}
+		return nil
+	}
+
+	trusted := make(map[string]models.TrustedNetwork)
+	for _, network := range networks {
+		trusted[network.SSID] = network
+	}
+
+	var attacks []models.Attack
+	for _, device := range devices {
+		network, ok := trusted[device.SSID]
+		if !ok || device.SSID == "" {
+			continue
+		}
+		bssid := strings.ToUpper(device.Address)
+
+		if !approvedBSSID(network, bssid) {
+			attacks = append(attacks, evilTwinAttack(models.SeverityHigh, bssid,
+				"Unknown access point %s advertises trusted network '%s'", bssid, network.SSID))
+			continue
+		}
+
+		if len(network.Channels) > 0 && device.Channel != "" && !containsString(network.Channels, device.Channel) {
+			attacks = append(attacks, evilTwinAttack(models.SeverityMedium, bssid,
+				"Trusted access point %s of '%s' on unexpected channel %s (trusted: %s)",
+				bssid, network.SSID, device.Channel, strings.Join(network.Channels, ", ")))
+		}
+
+		if network.Security != nil && device.Security != nil && device.Security.Weaker(*network.Security) {
+			attacks = append(attacks, evilTwinAttack(models.SeverityHigh, bssid,
+				"Security downgrade on '%s' from %s: %s, trusted %s",
+				network.SSID, bssid, device.Security, network.Security))
+		}
+
+		if trustedSignal, ok := network.Signals[bssid]; ok && d.signalDeviation > 0 {
+			if signal, ok := device.SignalDBm(); ok && abs(signal-trustedSignal) > d.signalDeviation {
+				attacks = append(attacks, evilTwinAttack(models.SeverityMedium, bssid,
+					"Anomalous signal from trusted access point %s of '%s': %d dBm, trusted at %d dBm",
+					bssid, network.SSID, signal, trustedSignal))
+			}
+		}
+	}
+	return attacks
+}
+
+// approvedBSSID reports whether the network lists the BSSID or its vendor prefix
+func approvedBSSID(network models.TrustedNetwork, bssid string) bool {
+	if containsString(network.BSSIDs, bssid) {
+		return true
+	}
+	return len(bssid) >= 8 && containsString(network.OUIs, bssid[:8])
+}
+
+func evilTwinAttack(severity models.Severity, target, format string, args ...interface{}) models.Attack {
+	return models.Attack{
+		Type:        "EVIL_TWIN",
+		Severity:    severity,
+		Description: fmt.Sprintf(format, args...),
+		Target:      target,
+		Timestamp:   time.Now(),
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
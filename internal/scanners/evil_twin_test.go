@@ -0,0 +1,74 @@
+package scanners
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestEvilTwinDetector(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/iwlist_scan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := NewWiFiScanner().parseIwlistOutput(string(recorded))
+
+	wpa2 := &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2"}, GroupCipher: "CCMP",
+		PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"PSK"}}
+	devices = append(devices,
+		// A second, approved mesh node of the same network
+		models.WiFiDevice{Address: "3C:84:6A:12:34:57", SSID: "SmithHome", Signal: "-60 dBm", Channel: "6", Security: wpa2},
+		// The first node's BSSID cloned on another channel, much closer
+		models.WiFiDevice{Address: "3C:84:6A:12:34:56", SSID: "SmithHome", Signal: "-20 dBm", Channel: "11", Security: wpa2},
+		models.WiFiDevice{Address: "02:00:00:00:00:01", SSID: "SmithHome", Signal: "-30 dBm", Channel: "6",
+			Security: &models.WiFiSecurity{}},
+	)
+
+	trusted := []models.TrustedNetwork{
+		{
+			SSID:     "SmithHome",
+			BSSIDs:   []string{"3C:84:6A:12:34:56", "3C:84:6A:12:34:57"},
+			Channels: []string{"6"},
+			Security: wpa2,
+			Signals:  map[string]int{"3C:84:6A:12:34:56": -48},
+		},
+		{
+			SSID:     "Cafe, Bar",
+			OUIs:     []string{"3C:84:6A"},
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA3"}, AuthSuites: []string{"SAE"}},
+		},
+		{SSID: "Legacy", BSSIDs: []string{"00:14:6C:11:11:11"}},
+	}
+	d := NewEvilTwinDetector(func() ([]models.TrustedNetwork, error) { return trusted, nil }, 25)
+
+	var got []string
+	for _, attack := range d.Check(devices) {
+		got = append(got, attack.Severity.String()+" "+attack.Description)
+	}
+	want := []string{
+		"HIGH Unknown access point 00:14:6C:AB:CD:EF advertises trusted network 'Legacy'",
+		"HIGH Security downgrade on 'Cafe, Bar' from 3C:84:6A:77:88:99: WPA2 WPA3 (PSK SAE, CCMP), trusted WPA3 (SAE)",
+		"MEDIUM Trusted access point 3C:84:6A:12:34:56 of 'SmithHome' on unexpected channel 11 (trusted: 6)",
+		"MEDIUM Anomalous signal from trusted access point 3C:84:6A:12:34:56 of 'SmithHome': -20 dBm, trusted at -48 dBm",
+		"HIGH Unknown access point 02:00:00:00:00:01 advertises trusted network 'SmithHome'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestEvilTwinDetectorRegistryError(t *testing.T) {
+	d := NewEvilTwinDetector(func() ([]models.TrustedNetwork, error) { return nil, errors.New("corrupt") }, 25)
+	var reported error
+	d.SetErrorHandler(func(err error) { reported = err })
+
+	if attacks := d.Check([]models.WiFiDevice{{Address: "3C:84:6A:12:34:56", SSID: "SmithHome"}}); attacks != nil {
+		t.Errorf("Check() = %+v, want none", attacks)
+	}
+	if reported == nil {
+		t.Error("registry error not reported")
+	}
+}
@@ -6,53 +6,271 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/boboTheFoff/shheissee-go/internal/arpscan"
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+	"github.com/boboTheFoff/shheissee-go/internal/oui"
+	"github.com/boboTheFoff/shheissee-go/internal/portscan"
+	"github.com/boboTheFoff/shheissee-go/internal/rules"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+)
+
+// Port scan backends
+const (
+	PortBackendBuiltin = "builtin"
+	PortBackendNmap    = "nmap"
 )
 
 // NetworkScanner handles network device discovery and port scanning
 type NetworkScanner struct {
 	knownDevices map[string]bool
+	runner       runner.CommandRunner
+	rules        *rules.Engine
+	subnets      SubnetFilter
+	localSubnets func() ([]netinfo.Subnet, error)
+	arpSweep     func(netinfo.Subnet) ([]arpscan.Reply, error)
+	checkSweep   func() error
+	arpTable     func() ([]netinfo.ARPEntry, error)
+	swept        []models.ARPBinding
+	portBackend  string
+	portOptions  portscan.Options
+	portScan     func(hosts []string, opts portscan.Options) map[string][]portscan.Result
+	privileged   func() bool
+	baseline     *PortBaseline
+	vendors      *oui.Database
 }
 
 // NewNetworkScanner creates a new network scanner
 func NewNetworkScanner(knownDevices []string) *NetworkScanner {
+	// Known devices are IP or MAC addresses
 	knownMap := make(map[string]bool)
 	for _, device := range knownDevices {
+		if mac, err := net.ParseMAC(device); err == nil {
+			device = strings.ToUpper(mac.String())
+		}
 		knownMap[device] = true
 	}
+	tcpPorts, _ := portscan.ParsePorts(portscan.DefaultTCPPorts)
+	udpPorts, _ := portscan.ParsePorts(portscan.DefaultUDPPorts)
 	return &NetworkScanner{
 		knownDevices: knownMap,
+		runner:       runner.NewExecRunner(),
+		rules:        rules.Default(),
+		localSubnets: netinfo.LocalSubnets,
+		arpSweep: func(subnet netinfo.Subnet) ([]arpscan.Reply, error) {
+			return arpscan.Sweep(subnet.Interface, subnet.Network, arpscan.Options{})
+		},
+		checkSweep:  arpscan.Check,
+		arpTable:    netinfo.ARPTable,
+		portBackend: PortBackendBuiltin,
+		portOptions: portscan.Options{TCPPorts: tcpPorts, UDPPorts: udpPorts},
+		portScan:    portscan.Scan,
+		privileged:  func() bool { return os.Geteuid() == 0 },
+		vendors:     oui.Embedded(),
+	}
+}
+
+// SetRunner replaces the runner used to execute external tools
+func (ns *NetworkScanner) SetRunner(r runner.CommandRunner) {
+	ns.runner = r
+}
+
+// SetRules replaces the detection rules
+func (ns *NetworkScanner) SetRules(engine *rules.Engine) {
+	ns.rules = engine
+}
+
+// SetSubnetFilter limits discovery to the local subnets selected by filter
+func (ns *NetworkScanner) SetSubnetFilter(filter SubnetFilter) {
+	ns.subnets = filter
+}
+
+// SetPortScan selects the port scan backend and the ports it probes. The
+// nmap backend scans only the TCP ports.
+func (ns *NetworkScanner) SetPortScan(backend string, opts portscan.Options) error {
+	if backend != PortBackendBuiltin && backend != PortBackendNmap {
+		return fmt.Errorf("unknown port scan backend %q: want %s or %s", backend, PortBackendBuiltin, PortBackendNmap)
+	}
+	ns.portBackend = backend
+	ns.portOptions = opts
+	return nil
+}
+
+// SetVendors replaces the registry used to name the vendor of MAC addresses
+func (ns *NetworkScanner) SetVendors(db *oui.Database) {
+	ns.vendors = db
+}
+
+// SetPortBaseline enables reporting ports that opened or closed between scans
+func (ns *NetworkScanner) SetPortBaseline(baseline *PortBaseline) {
+	ns.baseline = baseline
+}
+
+// Subnets returns the local subnets that ScanNetwork scans
+func (ns *NetworkScanner) Subnets() ([]netinfo.Subnet, error) {
+	local, err := ns.localSubnets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local subnets: %v", err)
 	}
+	return ns.subnets.Select(local), nil
+}
+
+// SweptBindings returns the IP-to-MAC bindings answered to the ARP sweeps of
+// the last ScanNetwork
+func (ns *NetworkScanner) SweptBindings() []models.ARPBinding {
+	return append([]models.ARPBinding(nil), ns.swept...)
+}
+
+// Name implements Scanner
+func (ns *NetworkScanner) Name() string {
+	return "network"
 }
 
-// ScanNetwork discovers devices on the network using various methods
+// Scan implements Scanner by discovering devices and scanning their ports
+func (ns *NetworkScanner) Scan() ([]interface{}, error) {
+	devices, _, err := ns.ScanNetwork()
+	if err != nil {
+		return nil, err
+	}
+
+	devices, _, err = ns.ScanPorts(devices)
+	if err != nil {
+		return nil, err
+	}
+
+	return toInterfaces(devices), nil
+}
+
+// Detect implements Scanner by flagging unknown devices, evaluating the
+// detection rules and comparing open ports with the port baseline
+func (ns *NetworkScanner) Detect(devices []interface{}) []models.Attack {
+	networkDevices := fromInterfaces[models.NetworkDevice](devices)
+
+	attacks := ns.detectUnknownDevices(networkDevices)
+	for _, device := range networkDevices {
+		attacks = append(attacks, ns.evaluateRules(device)...)
+	}
+	if ns.baseline != nil {
+		attacks = append(attacks, ns.baseline.Detect(networkDevices)...)
+	}
+	return attacks
+}
+
+// Health implements Scanner. The ARP sweep discovers hosts without external
+// tools where it can open its raw socket. The port scan backend does not
+// matter: without a discovery method there are no hosts to scan.
+func (ns *NetworkScanner) Health() models.ScannerHealth {
+	if err := ns.checkSweep(); err == nil {
+		return models.ScannerHealth{Name: ns.Name(), Available: true, Message: "using ARP sweep"}
+	}
+	return toolHealth(ns.runner, ns.Name(), "nmap", "fping")
+}
+
+// ScanNetwork discovers devices on each selected local subnet using various
+// methods, recording the interface and subnet every device was found on
 func (ns *NetworkScanner) ScanNetwork() ([]models.NetworkDevice, []models.Attack, error) {
+	subnets, err := ns.Subnets()
+	if err != nil {
+		return nil, nil, err
+	}
+	ns.swept = nil
+
 	var devices []models.NetworkDevice
-	var attacks []models.Attack
+	seen := make(map[string]bool)
+	add := func(device models.NetworkDevice, subnet netinfo.Subnet) {
+		if seen[device.IP] || ns.subnets.Excludes(device.IP) {
+			return
+		}
+		seen[device.IP] = true
+		device.Interface = subnet.Interface
+		device.Subnet = subnet.Network.String()
+		devices = append(devices, device)
+	}
 
-	// Try different scanning methods in order of preference
-	deviceLists := []func() ([]models.NetworkDevice, error){
-		ns.scanWithNmap,
-		ns.scanWithPing,
-		ns.scanWithNetdiscover,
+	for _, subnet := range subnets {
+		// Try different scanning methods in order of preference
+		deviceLists := []func(netinfo.Subnet) ([]models.NetworkDevice, error){
+			ns.scanWithARP,
+			ns.scanWithNmap,
+			ns.scanWithPing,
+		}
+
+		for _, scanMethod := range deviceLists {
+			devList, err := scanMethod(subnet)
+			if err == nil && len(devList) > 0 {
+				for _, device := range devList {
+					add(device, subnet)
+				}
+				break
+			}
+		}
 	}
 
-	for _, scanMethod := range deviceLists {
-		devList, err := scanMethod()
-		if err == nil && len(devList) > 0 {
-			devices = devList
-			break
+	// The netdiscover script picks its own network, so its devices are
+	// matched to the selected subnets afterwards
+	if len(devices) == 0 {
+		if devList, err := ns.scanWithNetdiscover(); err == nil {
+			for _, device := range devList {
+				if subnet, ok := subnetOf(subnets, device.IP); ok {
+					add(device, subnet)
+				}
+			}
 		}
 	}
 
-	// Check for unknown devices
+	ns.fillMACsFromARPTable(devices)
+	for i := range devices {
+		if devices[i].MAC == "" {
+			continue
+		}
+		devices[i].LocallyAdministered = oui.IsLocallyAdministered(devices[i].MAC)
+		if vendor := ns.vendors.Lookup(devices[i].MAC); vendor != "" {
+			devices[i].Vendor = vendor
+		}
+	}
+
+	return devices, ns.detectUnknownDevices(devices), nil
+}
+
+// fillMACsFromARPTable adds MAC addresses the kernel learned while the
+// external tools swept the network
+func (ns *NetworkScanner) fillMACsFromARPTable(devices []models.NetworkDevice) {
+	entries, err := ns.arpTable()
+	if err != nil {
+		return
+	}
+
+	macs := make(map[string]string)
+	for _, entry := range entries {
+		macs[entry.IP.String()] = strings.ToUpper(entry.MAC.String())
+	}
+	for i := range devices {
+		if devices[i].MAC == "" {
+			devices[i].MAC = macs[devices[i].IP]
+		}
+	}
+}
+
+// subnetOf finds the subnet containing ip
+func subnetOf(subnets []netinfo.Subnet, ip string) (netinfo.Subnet, bool) {
+	parsed := net.ParseIP(ip)
+	for _, subnet := range subnets {
+		if parsed != nil && subnet.Network.Contains(parsed) {
+			return subnet, true
+		}
+	}
+	return netinfo.Subnet{}, false
+}
+
+// detectUnknownDevices flags devices that are not in the known devices list
+func (ns *NetworkScanner) detectUnknownDevices(devices []models.NetworkDevice) []models.Attack {
+	var attacks []models.Attack
+
 	for _, device := range devices {
-		if !ns.knownDevices[device.IP] {
+		if !ns.knownDevices[device.IP] && (device.MAC == "" || !ns.knownDevices[device.MAC]) {
 			attacks = append(attacks, models.Attack{
 				Type:        "UNKNOWN_DEVICE",
 				Severity:    models.SeverityHigh,
@@ -63,32 +281,91 @@ func (ns *NetworkScanner) ScanNetwork() ([]models.NetworkDevice, []models.Attack
 		}
 	}
 
-	return devices, attacks, nil
+	return attacks
 }
 
-// scanWithNmap uses nmap to scan for devices
-func (ns *NetworkScanner) scanWithNmap() ([]models.NetworkDevice, error) {
-	if !isCommandAvailable("nmap") {
+// scanWithARP sweeps an IPv4 subnet with ARP requests on a raw socket. It
+// needs CAP_NET_RAW, so it fails over to the external tools when unprivileged.
+func (ns *NetworkScanner) scanWithARP(subnet netinfo.Subnet) ([]models.NetworkDevice, error) {
+	if !subnet.IsIPv4() {
+		return nil, fmt.Errorf("ARP sweeps only IPv4 subnets")
+	}
+
+	replies, err := ns.arpSweep(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []models.NetworkDevice
+	now := time.Now()
+	for _, reply := range replies {
+		device := models.NetworkDevice{
+			IP:      reply.IP.String(),
+			MAC:     strings.ToUpper(reply.MAC.String()),
+			State:   "up",
+			Latency: reply.ResponseTime,
+		}
+		devices = append(devices, device)
+		if !ns.subnets.Excludes(device.IP) {
+			ns.swept = append(ns.swept, models.ARPBinding{
+				IP:        device.IP,
+				MAC:       device.MAC,
+				Interface: subnet.Interface,
+				Source:    "arp_sweep",
+				SeenAt:    now,
+			})
+		}
+	}
+	return devices, nil
+}
+
+// scanWithNmap uses nmap to scan a subnet for devices. IPv6 prefixes too
+// large to sweep are probed with a multicast echo on the subnet's interface.
+func (ns *NetworkScanner) scanWithNmap(subnet netinfo.Subnet) ([]models.NetworkDevice, error) {
+	if !ns.runner.Available("nmap") {
 		return nil, fmt.Errorf("nmap not available")
 	}
 
-	cmd := exec.Command("nmap", "-sn", "192.168.1.0/24")
-	output, err := cmd.CombinedOutput()
+	args := []string{"-sn", "-oX", "-", subnet.Network.String()}
+	if !subnet.IsIPv4() {
+		args = []string{"-6", "-sn", "-oX", "-", subnet.Network.String()}
+		if !sweepable(subnet) {
+			args = []string{"-6", "-sn", "-oX", "-", "-e", subnet.Interface, "--script", "targets-ipv6-multicast-echo", "--script-args", "newtargets"}
+		}
+	}
+
+	output, err := ns.runner.Output("nmap", args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return ns.parseNmapOutput(string(output)), nil
+	devices, err := parseNmapXML(output)
+	if err != nil {
+		return nil, err
+	}
+	if !sweepable(subnet) {
+		// Multicast echo also reports hosts on other prefixes of the link
+		var onSubnet []models.NetworkDevice
+		for _, device := range devices {
+			if subnet.Network.Contains(net.ParseIP(device.IP)) {
+				onSubnet = append(onSubnet, device)
+			}
+		}
+		devices = onSubnet
+	}
+	return devices, nil
 }
 
-// scanWithPing uses ping to discover devices
-func (ns *NetworkScanner) scanWithPing() ([]models.NetworkDevice, error) {
-	if !isCommandAvailable("fping") {
+// scanWithPing uses fping to discover devices on an IPv4 subnet
+func (ns *NetworkScanner) scanWithPing(subnet netinfo.Subnet) ([]models.NetworkDevice, error) {
+	if !ns.runner.Available("fping") {
 		return nil, fmt.Errorf("fping not available")
 	}
+	if !subnet.IsIPv4() {
+		return nil, fmt.Errorf("fping sweeps only IPv4 subnets")
+	}
 
-	cmd := exec.Command("fping", "-a", "-g", "192.168.1.0/24", "-r", "1")
-	output, err := cmd.CombinedOutput()
+	output, err := ns.runner.CombinedOutput("fping", "-a", "-g", subnet.Network.String(), "-r", "1")
 	if err != nil {
 		return nil, err
 	}
@@ -116,8 +393,7 @@ func (ns *NetworkScanner) scanWithNetdiscover() ([]models.NetworkDevice, error)
 		return nil, fmt.Errorf("netdiscover script not found")
 	}
 
-	cmd := exec.Command("/bin/bash", scriptPath)
-	output, err := cmd.CombinedOutput()
+	output, err := ns.runner.CombinedOutput("/bin/bash", scriptPath)
 	if err != nil {
 		return nil, err
 	}
@@ -125,8 +401,9 @@ func (ns *NetworkScanner) scanWithNetdiscover() ([]models.NetworkDevice, error)
 	return ns.parseNetdiscoverOutput(string(output)), nil
 }
 
-// parseNmapOutput parses nmap scan output
-func (ns *NetworkScanner) parseNmapOutput(output string) []models.NetworkDevice {
+// parseNetdiscoverOutput parses the "Nmap scan report for" lines the
+// netdiscover script prints
+func (ns *NetworkScanner) parseNetdiscoverOutput(output string) []models.NetworkDevice {
 	var devices []models.NetworkDevice
 
 	scanner := bufio.NewScanner(strings.NewReader(output))
@@ -134,11 +411,12 @@ func (ns *NetworkScanner) parseNmapOutput(output string) []models.NetworkDevice
 		line := scanner.Text()
 
 		if strings.Contains(line, "Nmap scan report for") {
-			ipRegex := regexp.MustCompile(`(\d+\.\d+\.\d+\.\d+)`)
-			matches := ipRegex.FindStringSubmatch(line)
-			if len(matches) > 1 {
+			// The address is the last field, in parentheses after a hostname
+			fields := strings.Fields(line)
+			address := strings.Trim(fields[len(fields)-1], "()")
+			if ip := net.ParseIP(address); ip != nil {
 				devices = append(devices, models.NetworkDevice{
-					IP:    matches[1],
+					IP:    ip.String(),
 					State: "up",
 				})
 			}
@@ -168,122 +446,129 @@ func (ns *NetworkScanner) parseFpingOutput(output string) []models.NetworkDevice
 	return devices
 }
 
-// parseNetdiscoverOutput parses netdiscover script output
-func (ns *NetworkScanner) parseNetdiscoverOutput(output string) []models.NetworkDevice {
-	return ns.parseNmapOutput(output) // Use same parser for now
-}
-
-// ScanPorts scans for open ports on discovered devices
+// ScanPorts scans for open ports on discovered devices. The nmap backend
+// falls back to the built-in scanner when nmap is not installed.
 func (ns *NetworkScanner) ScanPorts(devices []models.NetworkDevice) ([]models.NetworkDevice, []models.Attack, error) {
 	var attacks []models.Attack
 
+	hosts := make([]string, len(devices))
 	for i, device := range devices {
-		ports, err := ns.scanDevicePorts(device.IP)
+		hosts[i] = device.IP
+	}
+
+	if ns.portBackend == PortBackendNmap && ns.runner.Available("nmap") {
+		scanned, err := ns.scanPortsWithNmap(hosts)
 		if err != nil {
-			continue
+			return devices, nil, err
 		}
 
-		devices[i].Ports = ports
+		byIP := make(map[string]models.NetworkDevice)
+		for _, device := range scanned {
+			byIP[device.IP] = device
+		}
+		for i := range devices {
+			mergeNmapDevice(&devices[i], byIP[devices[i].IP])
+			attacks = append(attacks, ns.evaluateRules(devices[i])...)
+		}
+		return devices, attacks, nil
+	}
 
-		// Check for suspicious ports
-		for _, port := range ports {
-			suspiciousPorts := map[int]string{
-				21:  "FTP",
-				23:  "Telnet",
-				3389: "RDP",
-				445: "SMB",
-			}
+	open := ns.portScan(hosts, ns.portOptions)
 
-			if port.State == "open" {
-				if service, exists := suspiciousPorts[port.Number]; exists {
-					attacks = append(attacks, models.Attack{
-						Type:        "SUSPICIOUS_PORT",
-						Severity:    models.SeverityMedium,
-						Description: fmt.Sprintf("Suspicious open port detected: %s:%d (%s)", device.IP, port.Number, service),
-						Target:      device.IP,
-						Timestamp:   time.Now(),
-					})
-				}
-			}
+	// The banner takes the place of nmap's version, so rules and port
+	// records see the same service names from both backends
+	for i, device := range devices {
+		var ports []models.Port
+		for _, result := range open[device.IP] {
+			ports = append(ports, models.Port{
+				Number:   result.Port,
+				Protocol: result.Protocol,
+				Service:  result.Service,
+				Version:  result.Banner,
+				State:    "open",
+			})
 		}
+
+		devices[i].Ports = ports
+		attacks = append(attacks, ns.evaluateRules(devices[i])...)
 	}
 
 	return devices, attacks, nil
 }
 
-// scanDevicePorts scans ports on a specific device
-func (ns *NetworkScanner) scanDevicePorts(ip string) ([]models.Port, error) {
-	if !isCommandAvailable("nmap") {
-		return nil, fmt.Errorf("nmap not available for port scanning")
+// mergeNmapDevice takes the ports of an nmap scan and the details that
+// discovery did not find
+func mergeNmapDevice(device *models.NetworkDevice, scanned models.NetworkDevice) {
+	device.Ports = scanned.Ports
+	if device.MAC == "" {
+		device.MAC = scanned.MAC
 	}
-
-	cmd := exec.Command("nmap", "-p", "21,22,23,25,53,80,110,143,443,993,995,3389,445", "--open", ip)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, err
+	if device.Vendor == "" {
+		device.Vendor = scanned.Vendor
+	}
+	if device.Name == "" {
+		device.Name = scanned.Name
+	}
+	if device.OS == "" {
+		device.OS = scanned.OS
+	}
+	if device.Latency == 0 {
+		device.Latency = scanned.Latency
 	}
-
-	return ns.parsePortScanOutput(string(output)), nil
 }
 
-// parsePortScanOutput parses nmap port scan output
-func (ns *NetworkScanner) parsePortScanOutput(output string) []models.Port {
-	var ports []models.Port
-
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	inPortSection := false
-
-	for scanner.Scan() {
-		line := scanner.Text()
+// evaluateRules evaluates the device and port rules against a device
+func (ns *NetworkScanner) evaluateRules(device models.NetworkDevice) []models.Attack {
+	attacks := ns.rules.Evaluate(rules.SourceNetwork, []rules.Fields{networkFields(device)})
 
-		if strings.Contains(line, "PORT") && strings.Contains(line, "STATE") && strings.Contains(line, "SERVICE") {
-			inPortSection = true
-			continue
-		}
-
-		if inPortSection && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "MAC") {
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				ports = append(ports, models.Port{
-					Number: parsePortNumber(parts[0]),
-					Protocol: parseProtocol(parts[0]),
-					State:   parts[1],
-					Service: parts[2],
-				})
-			}
-		}
-
-		if strings.Contains(line, "Nmap done") {
-			break
-		}
+	records := make([]rules.Fields, len(device.Ports))
+	for i, port := range device.Ports {
+		records[i] = portFields(device, port)
 	}
-
-	return ports
+	return append(attacks, ns.rules.Evaluate(rules.SourcePort, records)...)
 }
 
-// Helper functions
+// networkFields exposes a device to the detection rules
+func networkFields(device models.NetworkDevice) rules.Fields {
+	return rules.Fields{
+		"ip":                   device.IP,
+		"mac":                  device.MAC,
+		"name":                 device.Name,
+		"vendor":               device.Vendor,
+		"locally_administered": device.LocallyAdministered,
+		"os":                   device.OS,
+		"state":                device.State,
+	}
+}
 
-func isCommandAvailable(cmd string) bool {
-	_, err := exec.LookPath(cmd)
-	return err == nil
+// portFields exposes one port of a device to the detection rules
+func portFields(device models.NetworkDevice, port models.Port) rules.Fields {
+	fields := networkFields(device)
+	fields["port"] = port.Number
+	fields["protocol"] = port.Protocol
+	fields["service"] = port.Service
+	fields["version"] = port.Version
+	fields["state"] = port.State
+	return fields
 }
 
-func parsePortNumber(portStr string) int {
-	parts := strings.Split(portStr, "/")
-	if len(parts) > 0 {
-		if portNum, err := fmt.Sscanf(parts[0], "%d"); err == nil {
-			return portNum
-		}
+// scanPortsWithNmap scans the devices' TCP ports with nmap's version
+// detection, and its OS detection when running as root
+func (ns *NetworkScanner) scanPortsWithNmap(ips []string) ([]models.NetworkDevice, error) {
+	if len(ns.portOptions.TCPPorts) == 0 || len(ips) == 0 {
+		return nil, nil
 	}
-	return 0
-}
 
-func parseProtocol(portStr string) string {
-	parts := strings.Split(portStr, "/")
-	if len(parts) > 1 {
-		return parts[1]
+	args := []string{"-sV", "-p", portscan.FormatPorts(ns.portOptions.TCPPorts), "--open", "-oX", "-"}
+	if ns.privileged() {
+		args = append(args, "-O", "--osscan-limit")
 	}
-	return "tcp"
+	output, err := ns.runner.Output("nmap", append(args, ips...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNmapXML(output)
 }
 
 // LoadKnownDevices loads known network devices from file
@@ -0,0 +1,506 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/arpscan"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+	"github.com/boboTheFoff/shheissee-go/internal/portscan"
+	"github.com/boboTheFoff/shheissee-go/internal/rules"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+)
+
+func TestParseNetdiscoverOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []models.NetworkDevice
+	}{
+		{
+			name:   "empty output",
+			output: "",
+			want:   nil,
+		},
+		{
+			name:   "host with hostname",
+			output: "Nmap scan report for router.lan (192.168.1.1)\nHost is up (0.0021s latency).\n",
+			want:   []models.NetworkDevice{{IP: "192.168.1.1", State: "up"}},
+		},
+		{
+			name:   "bare address",
+			output: "Nmap scan report for 10.0.0.7\nHost is up.\n",
+			want:   []models.NetworkDevice{{IP: "10.0.0.7", State: "up"}},
+		},
+		{
+			name:   "IPv6 address",
+			output: "Nmap scan report for nas.lan (2001:db8:1::25)\nHost is up.\n",
+			want:   []models.NetworkDevice{{IP: "2001:db8:1::25", State: "up"}},
+		},
+		{
+			name:   "no hosts up",
+			output: "Starting Nmap 7.94\nNmap done: 256 IP addresses (0 hosts up) scanned in 2.01 seconds\n",
+			want:   nil,
+		},
+	}
+
+	ns := NewNetworkScanner(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ns.parseNetdiscoverOutput(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNetdiscoverOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNmapXML(t *testing.T) {
+	tests := []struct {
+		file string
+		want []models.NetworkDevice
+	}{
+		{
+			file: "testdata/nmap_ping_sweep.xml",
+			want: []models.NetworkDevice{
+				{IP: "192.168.1.1", MAC: "3C:84:6A:12:34:56", Name: "router.lan", Vendor: "TP-Link Technologies", State: "up", Latency: 2100 * time.Microsecond},
+				{IP: "192.168.1.20", MAC: "B8:27:EB:AA:BB:CC", Vendor: "Raspberry Pi Foundation", State: "up", Latency: 10250 * time.Microsecond},
+				{IP: "192.168.1.42", Name: "laptop.lan", State: "up"},
+			},
+		},
+		{
+			file: "testdata/nmap_port_scan.xml",
+			want: []models.NetworkDevice{
+				{
+					IP: "192.168.1.20", MAC: "B8:27:EB:AA:BB:CC", Name: "pi.lan", Vendor: "Raspberry Pi Foundation",
+					OS: "Linux 4.15 - 5.8", State: "up", Latency: 3100 * time.Microsecond,
+					Ports: []models.Port{
+						{Number: 22, Protocol: "tcp", Service: "ssh", Version: "OpenSSH 9.2p1 Debian 2+deb12u2 (protocol 2.0)", State: "open"},
+						{Number: 3389, Protocol: "tcp", Service: "ms-wbt-server", State: "open"},
+					},
+				},
+				{IP: "192.168.1.30", MAC: "00:11:32:01:02:03", Vendor: "Synology Incorporated", State: "up", Latency: 1250 * time.Microsecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseNmapXML(data)
+			if err != nil {
+				t.Fatalf("parseNmapXML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNmapXML() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseNmapXML([]byte("Starting Nmap 7.94")); err == nil {
+		t.Error("parseNmapXML() accepted text output")
+	}
+}
+
+func TestScanNetworkWithRecordedNmap(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("nmap")
+	if err := replay.AddFile("nmap -sn -oX - 192.168.1.0/24", "testdata/nmap_ping_sweep.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := NewNetworkScanner([]string{"192.168.1.1"})
+	ns.SetRunner(replay)
+	ns.localSubnets = staticSubnets(t, "wlan0 192.168.1.0/24")
+	withoutARP(ns)
+
+	devices, attacks, err := ns.ScanNetwork()
+	if err != nil {
+		t.Fatalf("ScanNetwork() error = %v", err)
+	}
+
+	var ips []string
+	for _, device := range devices {
+		ips = append(ips, device.IP)
+		if device.Interface != "wlan0" || device.Subnet != "192.168.1.0/24" {
+			t.Errorf("device %s found on %s %s, want wlan0 192.168.1.0/24", device.IP, device.Interface, device.Subnet)
+		}
+	}
+	wantIPs := []string{"192.168.1.1", "192.168.1.20", "192.168.1.42"}
+	if !reflect.DeepEqual(ips, wantIPs) {
+		t.Errorf("device IPs = %v, want %v", ips, wantIPs)
+	}
+
+	var targets []string
+	for _, attack := range attacks {
+		if attack.Type != "UNKNOWN_DEVICE" {
+			t.Errorf("unexpected attack type %s", attack.Type)
+		}
+		targets = append(targets, attack.Target)
+	}
+	wantTargets := []string{"192.168.1.20", "192.168.1.42"}
+	if !reflect.DeepEqual(targets, wantTargets) {
+		t.Errorf("attack targets = %v, want %v", targets, wantTargets)
+	}
+}
+
+func TestDetectFlagsSuspiciousPortsFromRules(t *testing.T) {
+	ns := NewNetworkScanner([]string{"192.168.1.20"})
+
+	device := models.NetworkDevice{
+		IP: "192.168.1.20",
+		Ports: []models.Port{
+			{Number: 22, Protocol: "tcp", State: "open", Service: "ssh"},
+			{Number: 23, Protocol: "tcp", State: "open", Service: "telnet"},
+			{Number: 445, Protocol: "tcp", State: "filtered", Service: "microsoft-ds"},
+		},
+	}
+
+	attacks := ns.Detect([]interface{}{device})
+	if len(attacks) != 1 {
+		t.Fatalf("got %d attacks, want 1: %+v", len(attacks), attacks)
+	}
+	if got, want := attacks[0].Description, "Suspicious open port detected: 192.168.1.20:23 (Telnet)"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+}
+
+func TestDetectUnknownVendorFromRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	rulesFile := `{"rules": [{
+		"name": "unknown-vendor",
+		"source": "network",
+		"type": "UNKNOWN_VENDOR",
+		"severity": "LOW",
+		"when": [
+			{"field": "mac", "op": "ne", "value": ""},
+			{"field": "vendor", "op": "eq", "value": ""},
+			{"field": "locally_administered", "op": "eq", "value": false}
+		],
+		"description": "Device with unregistered vendor: {{.ip}} ({{.mac}})",
+		"target": "{{.ip}}"
+	}]}`
+	if err := os.WriteFile(path, []byte(rulesFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := rules.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := NewNetworkScanner([]string{"192.168.1.20", "192.168.1.21", "192.168.1.22"})
+	ns.SetRules(engine)
+
+	devices := []interface{}{
+		models.NetworkDevice{IP: "192.168.1.20", MAC: "B8:27:EB:AA:BB:CC", Vendor: "Raspberry Pi Foundation"},
+		models.NetworkDevice{IP: "192.168.1.21", MAC: "DA:A1:19:00:00:01", LocallyAdministered: true},
+		models.NetworkDevice{IP: "192.168.1.22", MAC: "FC:FF:FF:00:00:01"},
+	}
+	attacks := ns.Detect(devices)
+	if len(attacks) != 1 || attacks[0].Description != "Device with unregistered vendor: 192.168.1.22 (FC:FF:FF:00:00:01)" {
+		t.Errorf("attacks = %+v", attacks)
+	}
+}
+
+func TestScanPortsWithBuiltinScanner(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	var scanned []string
+	ns.portScan = func(hosts []string, opts portscan.Options) map[string][]portscan.Result {
+		scanned = hosts
+		return map[string][]portscan.Result{
+			"192.168.1.20": {
+				{Port: 22, Protocol: "tcp", Service: "ssh", Banner: "SSH-2.0-OpenSSH_9.6"},
+				{Port: 23, Protocol: "tcp", Service: "telnet"},
+			},
+		}
+	}
+
+	devices, attacks, err := ns.ScanPorts([]models.NetworkDevice{{IP: "192.168.1.1"}, {IP: "192.168.1.20"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scanned, []string{"192.168.1.1", "192.168.1.20"}) {
+		t.Errorf("scanned hosts = %v", scanned)
+	}
+	want := []models.Port{
+		{Number: 22, Protocol: "tcp", Service: "ssh", Version: "SSH-2.0-OpenSSH_9.6", State: "open"},
+		{Number: 23, Protocol: "tcp", Service: "telnet", State: "open"},
+	}
+	if devices[0].Ports != nil || !reflect.DeepEqual(devices[1].Ports, want) {
+		t.Errorf("ports = %+v, %+v", devices[0].Ports, devices[1].Ports)
+	}
+	if len(attacks) != 1 || attacks[0].Description != "Suspicious open port detected: 192.168.1.20:23 (Telnet)" {
+		t.Errorf("attacks = %+v", attacks)
+	}
+}
+
+func TestScanPortsWithNmapBackend(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("nmap")
+	if err := replay.AddFile("nmap -sV -p 22-23,3389 --open -oX - -O --osscan-limit 192.168.1.20 192.168.1.30", "testdata/nmap_port_scan.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := NewNetworkScanner(nil)
+	ns.SetRunner(replay)
+	ns.privileged = func() bool { return true }
+	if err := ns.SetPortScan(PortBackendNmap, portscan.Options{TCPPorts: []int{22, 23, 3389}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Details found by discovery are kept, the rest is taken from nmap
+	devices, attacks, err := ns.ScanPorts([]models.NetworkDevice{
+		{IP: "192.168.1.20", Name: "raspberrypi"},
+		{IP: "192.168.1.30"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Port{
+		{Number: 22, Protocol: "tcp", Service: "ssh", Version: "OpenSSH 9.2p1 Debian 2+deb12u2 (protocol 2.0)", State: "open"},
+		{Number: 3389, Protocol: "tcp", Service: "ms-wbt-server", State: "open"},
+	}
+	if !reflect.DeepEqual(devices[0].Ports, want) {
+		t.Errorf("ports = %+v, want %+v", devices[0].Ports, want)
+	}
+	if devices[0].Name != "raspberrypi" || devices[0].MAC != "B8:27:EB:AA:BB:CC" || devices[0].OS != "Linux 4.15 - 5.8" {
+		t.Errorf("device = %+v", devices[0])
+	}
+	if devices[1].Ports != nil || devices[1].Vendor != "Synology Incorporated" {
+		t.Errorf("device = %+v", devices[1])
+	}
+	if len(attacks) != 1 || !strings.Contains(attacks[0].Description, ":3389 (RDP)") {
+		t.Errorf("attacks = %+v", attacks)
+	}
+
+	if err := ns.SetPortScan("masscan", portscan.Options{}); err == nil {
+		t.Error("SetPortScan() accepted an unknown backend")
+	}
+}
+
+func TestScanNetworkScansEachSelectedSubnet(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("nmap")
+	replay.AddOutput("nmap -sn -oX - 10.20.0.0/24", nmapXML("10.20.0.5"))
+	replay.AddOutput("nmap -6 -sn -oX - -e eth0 --script targets-ipv6-multicast-echo --script-args newtargets",
+		nmapXML("2001:db8:1::25", "2001:db8:2::7"))
+
+	ns := NewNetworkScanner(nil)
+	ns.SetRunner(replay)
+	ns.localSubnets = staticSubnets(t, "eth0 10.20.0.0/16", "eth0 2001:db8:1::/64", "docker0 172.17.0.0/16")
+	withoutARP(ns)
+
+	filter, err := NewSubnetFilter([]string{"10.20.0.0/24", "2001:db8::/32"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.SetSubnetFilter(filter)
+
+	devices, _, err := ns.ScanNetwork()
+	if err != nil {
+		t.Fatalf("ScanNetwork() error = %v", err)
+	}
+
+	want := []models.NetworkDevice{
+		{IP: "10.20.0.5", State: "up", Interface: "eth0", Subnet: "10.20.0.0/24"},
+		{IP: "2001:db8:1::25", State: "up", Interface: "eth0", Subnet: "2001:db8:1::/64"},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("devices = %+v, want %+v", devices, want)
+	}
+}
+
+func TestSubnetFilterSelect(t *testing.T) {
+	local := []string{"eth0 10.20.0.0/16", "wlan0 192.168.1.0/24", "eth0 2001:db8:1::/64"}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name: "all local subnets by default",
+			want: local,
+		},
+		{
+			name:    "include narrows a larger subnet",
+			include: []string{"10.20.5.0/24"},
+			want:    []string{"eth0 10.20.5.0/24"},
+		},
+		{
+			name:    "include keeps subnets inside it",
+			include: []string{"192.168.0.0/16", "2001:db8::/32"},
+			want:    []string{"wlan0 192.168.1.0/24", "eth0 2001:db8:1::/64"},
+		},
+		{
+			name:    "exclude drops subnets inside it",
+			exclude: []string{"192.168.1.0/24", "2001:db8::/32"},
+			want:    []string{"eth0 10.20.0.0/16"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewSubnetFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subnets, _ := staticSubnets(t, local...)()
+
+			var got []string
+			for _, subnet := range filter.Select(subnets) {
+				got = append(got, subnet.Interface+" "+subnet.Network.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSubnetFilter([]string{"10.0.0.0"}, nil); err == nil {
+		t.Error("NewSubnetFilter() accepted an address without a prefix length")
+	}
+}
+
+// staticSubnets returns a subnet lister for "interface cidr" entries
+func staticSubnets(t *testing.T, entries ...string) func() ([]netinfo.Subnet, error) {
+	t.Helper()
+
+	var subnets []netinfo.Subnet
+	for _, entry := range entries {
+		fields := strings.Fields(entry)
+		_, network, err := net.ParseCIDR(fields[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		subnets = append(subnets, netinfo.Subnet{Interface: fields[0], Network: network})
+	}
+	return func() ([]netinfo.Subnet, error) { return subnets, nil }
+}
+
+func TestScanNetworkPrefersARPSweep(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("nmap")
+
+	ns := NewNetworkScanner([]string{"b8:27:eb:aa:bb:cc"})
+	ns.SetRunner(replay)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24")
+	ns.arpTable = func() ([]netinfo.ARPEntry, error) { return nil, nil }
+	ns.arpSweep = func(subnet netinfo.Subnet) ([]arpscan.Reply, error) {
+		return []arpscan.Reply{
+			{IP: net.ParseIP("192.168.1.20").To4(), MAC: mustParseMAC(t, "b8:27:eb:aa:bb:cc"), ResponseTime: 2 * time.Millisecond},
+			{IP: net.ParseIP("192.168.1.42").To4(), MAC: mustParseMAC(t, "3c:84:6a:12:34:56"), ResponseTime: 5 * time.Millisecond},
+		}, nil
+	}
+
+	devices, attacks, err := ns.ScanNetwork()
+	if err != nil {
+		t.Fatalf("ScanNetwork() error = %v", err)
+	}
+
+	want := []models.NetworkDevice{
+		{IP: "192.168.1.20", MAC: "B8:27:EB:AA:BB:CC", Vendor: "Raspberry Pi Foundation", State: "up", Interface: "eth0", Subnet: "192.168.1.0/24", Latency: 2 * time.Millisecond},
+		{IP: "192.168.1.42", MAC: "3C:84:6A:12:34:56", State: "up", Interface: "eth0", Subnet: "192.168.1.0/24", Latency: 5 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("devices = %+v, want %+v", devices, want)
+	}
+	if calls := replay.Calls(); len(calls) != 0 {
+		t.Errorf("external tools used despite ARP replies: %v", calls)
+	}
+
+	// The device known by MAC is not reported
+	if len(attacks) != 1 || attacks[0].Target != "192.168.1.42" {
+		t.Errorf("attacks = %+v, want only 192.168.1.42 unknown", attacks)
+	}
+}
+
+func TestNetworkHealthWithoutTools(t *testing.T) {
+	ns := NewNetworkScanner(nil)
+	ns.SetRunner(runner.NewReplayRunner())
+
+	ns.checkSweep = func() error { return nil }
+	if health := ns.Health(); !health.Available || health.Message != "using ARP sweep" {
+		t.Errorf("Health() = %+v, want available through the ARP sweep", health)
+	}
+
+	// The built-in port scanner finds nothing to scan without discovery
+	ns.checkSweep = func() error { return arpscan.ErrUnsupported }
+	if health := ns.Health(); health.Available {
+		t.Errorf("Health() = %+v, want unavailable", health)
+	}
+}
+
+func TestScanNetworkFillsMACsFromARPTable(t *testing.T) {
+	replay := runner.NewReplayRunner()
+	replay.SetAvailable("nmap")
+	if err := replay.AddFile("nmap -sn -oX - 192.168.1.0/24", "testdata/nmap_ping_sweep.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := NewNetworkScanner(nil)
+	ns.SetRunner(replay)
+	ns.localSubnets = staticSubnets(t, "eth0 192.168.1.0/24")
+	withoutARP(ns)
+	ns.arpTable = func() ([]netinfo.ARPEntry, error) {
+		return []netinfo.ARPEntry{
+			{IP: net.ParseIP("192.168.1.1"), MAC: mustParseMAC(t, "02:00:00:00:00:01"), Device: "eth0"},
+			{IP: net.ParseIP("192.168.1.42"), MAC: mustParseMAC(t, "60:f8:1d:42:42:42"), Device: "eth0"},
+		}, nil
+	}
+
+	devices, _, err := ns.ScanNetwork()
+	if err != nil {
+		t.Fatalf("ScanNetwork() error = %v", err)
+	}
+
+	macs := make(map[string]string)
+	for _, device := range devices {
+		macs[device.IP] = device.MAC
+	}
+	if macs["192.168.1.42"] != "60:F8:1D:42:42:42" || macs["192.168.1.1"] != "3C:84:6A:12:34:56" {
+		t.Errorf("MACs = %v", macs)
+	}
+}
+
+// withoutARP makes the scanner fall back to the external tools and ignore
+// the host's ARP table
+func withoutARP(ns *NetworkScanner) {
+	ns.arpSweep = func(netinfo.Subnet) ([]arpscan.Reply, error) { return nil, arpscan.ErrUnsupported }
+	ns.arpTable = func() ([]netinfo.ARPEntry, error) { return nil, nil }
+}
+
+// nmapXML returns nmap XML output reporting the addresses as up
+func nmapXML(addresses ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<nmaprun scanner=\"nmap\">\n")
+	for _, address := range addresses {
+		addrType := "ipv4"
+		if strings.Contains(address, ":") {
+			addrType = "ipv6"
+		}
+		fmt.Fprintf(&b, "<host><status state=\"up\"/><address addr=%q addrtype=%q/></host>\n", address, addrType)
+	}
+	b.WriteString("</nmaprun>\n")
+	return b.String()
+}
+
+func mustParseMAC(t *testing.T, s string) net.HardwareAddr {
+	t.Helper()
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mac
+}
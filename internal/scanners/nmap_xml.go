@@ -0,0 +1,113 @@
+package scanners
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// nmapRun is the part of nmap's XML output (-oX) the scanner reads
+type nmapRun struct {
+	Hosts []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+		Vendor   string `xml:"vendor,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   int    `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name      string `xml:"name,attr"`
+			Product   string `xml:"product,attr"`
+			Version   string `xml:"version,attr"`
+			ExtraInfo string `xml:"extrainfo,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+	OSMatches []struct {
+		Name     string `xml:"name,attr"`
+		Accuracy string `xml:"accuracy,attr"`
+	} `xml:"os>osmatch"`
+	Times struct {
+		SRTT string `xml:"srtt,attr"` // microseconds
+	} `xml:"times"`
+}
+
+// parseNmapXML decodes nmap's XML output into the hosts that are up
+func parseNmapXML(data []byte) ([]models.NetworkDevice, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse nmap XML output: %v", err)
+	}
+
+	var devices []models.NetworkDevice
+	for _, host := range run.Hosts {
+		if host.Status.State != "up" {
+			continue
+		}
+
+		device := models.NetworkDevice{State: host.Status.State}
+		for _, address := range host.Addresses {
+			switch address.AddrType {
+			case "ipv4", "ipv6":
+				device.IP = address.Addr
+			case "mac":
+				device.MAC = strings.ToUpper(address.Addr)
+				device.Vendor = address.Vendor
+			}
+		}
+		if device.IP == "" {
+			continue
+		}
+
+		if len(host.Hostnames) > 0 {
+			device.Name = host.Hostnames[0].Name
+		}
+		// Matches are listed best first
+		if len(host.OSMatches) > 0 {
+			device.OS = host.OSMatches[0].Name
+		}
+		if srtt, err := strconv.Atoi(host.Times.SRTT); err == nil {
+			device.Latency = time.Duration(srtt) * time.Microsecond
+		}
+
+		for _, port := range host.Ports {
+			service := port.Service.Name
+			if service == "" {
+				service = "unknown"
+			}
+			version := strings.TrimSpace(port.Service.Product + " " + port.Service.Version)
+			if port.Service.ExtraInfo != "" {
+				version = strings.TrimSpace(fmt.Sprintf("%s (%s)", version, port.Service.ExtraInfo))
+			}
+
+			device.Ports = append(device.Ports, models.Port{
+				Number:   port.PortID,
+				Protocol: port.Protocol,
+				Service:  service,
+				Version:  version,
+				State:    port.State.State,
+			})
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
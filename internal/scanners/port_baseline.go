@@ -0,0 +1,280 @@
+package scanners
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/portscan"
+)
+
+// PortProfiles lists the ports each known device is expected to have open,
+// keyed by IP or MAC address. Ports are written as "22/tcp", "53/udp" or
+// "8000-8100/tcp"; without a protocol TCP is meant.
+type PortProfiles map[string][]string
+
+// PortBaseline remembers the open ports of every device across scans, and
+// across restarts through a JSON file, and reports the ports that opened or
+// closed. Ports in a device's profile may open without an alert.
+type PortBaseline struct {
+	path     string
+	accepted map[string]map[string]bool // device IP or MAC -> port names
+	ports    map[string][]string        // device MAC, or IP until the MAC is known -> open ports
+	onError  func(error)
+	mu       sync.Mutex
+}
+
+// NewPortBaseline loads the baseline stored at path and the accepted port profiles
+func NewPortBaseline(path string, profiles PortProfiles) (*PortBaseline, error) {
+	b := &PortBaseline{
+		path:     path,
+		accepted: make(map[string]map[string]bool),
+		ports:    make(map[string][]string),
+		onError:  func(error) {},
+	}
+
+	for device, specs := range profiles {
+		accepted := make(map[string]bool)
+		for _, spec := range specs {
+			names, err := parsePortSpec(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port profile for %s: %v", device, err)
+			}
+			for _, name := range names {
+				accepted[name] = true
+			}
+		}
+		b.accepted[normalizeDeviceAddress(device)] = accepted
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &b.ports); err != nil {
+			return nil, fmt.Errorf("failed to parse port baseline %s: %v", path, err)
+		}
+	}
+
+	return b, nil
+}
+
+// SetErrorHandler registers fn to be called when the baseline cannot be saved
+func (b *PortBaseline) SetErrorHandler(fn func(error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onError = fn
+}
+
+// Detect compares the open ports of each scanned device with its baseline
+// and then makes them the new baseline. A device's first scan only sets its
+// baseline, and devices missing from the scan keep theirs. So do devices
+// without open ports, as a timed out or rate limited port scan looks the same.
+func (b *PortBaseline) Detect(devices []models.NetworkDevice) []models.Attack {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var attacks []models.Attack
+	changed := false
+
+	for _, device := range devices {
+		current := openPortNames(device)
+		if len(current) == 0 {
+			continue
+		}
+		key := b.deviceKey(device)
+
+		previous, known := b.ports[key]
+		if !known || strings.Join(previous, ",") != strings.Join(current, ",") {
+			b.ports[key] = current
+			changed = true
+		}
+		if !known {
+			continue
+		}
+
+		for _, name := range current {
+			if containsString(previous, name) || b.isAccepted(device, name) {
+				continue
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "NEW_OPEN_PORT",
+				Severity:    models.SeverityMedium,
+				Description: fmt.Sprintf("New open port %s on %s", describePort(device, name), deviceLabel(device)),
+				Target:      device.IP,
+				Timestamp:   time.Now(),
+			})
+		}
+		for _, name := range previous {
+			if containsString(current, name) {
+				continue
+			}
+			attacks = append(attacks, models.Attack{
+				Type:        "PORT_CLOSED",
+				Severity:    models.SeverityLow,
+				Description: fmt.Sprintf("Port %s closed on %s", name, deviceLabel(device)),
+				Target:      device.IP,
+				Timestamp:   time.Now(),
+			})
+		}
+	}
+
+	if changed {
+		if err := b.save(); err != nil {
+			b.onError(err)
+		}
+	}
+	return attacks
+}
+
+// deviceKey returns the baseline key of a device, carrying over a baseline
+// recorded under its IP address before the MAC address was known
+func (b *PortBaseline) deviceKey(device models.NetworkDevice) string {
+	if device.MAC == "" {
+		return device.IP
+	}
+	key := normalizeDeviceAddress(device.MAC)
+	if _, ok := b.ports[key]; !ok {
+		if ports, ok := b.ports[device.IP]; ok {
+			delete(b.ports, device.IP)
+			b.ports[key] = ports
+		}
+	}
+	return key
+}
+
+// isAccepted reports whether a port is in the profile of the device's IP or MAC
+func (b *PortBaseline) isAccepted(device models.NetworkDevice, name string) bool {
+	if b.accepted[device.IP][name] {
+		return true
+	}
+	return device.MAC != "" && b.accepted[normalizeDeviceAddress(device.MAC)][name]
+}
+
+// save replaces the baseline file atomically
+func (b *PortBaseline) save() error {
+	if b.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(b.ports, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := b.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, b.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace port baseline: %v", err)
+	}
+	return nil
+}
+
+// openPortNames returns the sorted open ports of a device, e.g. "22/tcp"
+func openPortNames(device models.NetworkDevice) []string {
+	names := []string{}
+	for _, port := range device.Ports {
+		if port.State == "" || port.State == "open" {
+			names = append(names, fmt.Sprintf("%d/%s", port.Number, port.Protocol))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describePort adds the service and version of an open port to its name,
+// e.g. "22/tcp ssh SSH-2.0-OpenSSH_9.6"
+func describePort(device models.NetworkDevice, name string) string {
+	for _, port := range device.Ports {
+		if fmt.Sprintf("%d/%s", port.Number, port.Protocol) != name {
+			continue
+		}
+		description := name
+		if port.Service != "" && port.Service != "unknown" {
+			description += " " + port.Service
+		}
+		if port.Version != "" {
+			description += " " + port.Version
+		}
+		return description
+	}
+	return name
+}
+
+// deviceLabel names a device by its IP address and, if known, its MAC and name
+func deviceLabel(device models.NetworkDevice) string {
+	var details []string
+	for _, detail := range []string{device.MAC, device.Name} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		return device.IP
+	}
+	return fmt.Sprintf("%s (%s)", device.IP, strings.Join(details, ", "))
+}
+
+// parsePortSpec expands a profile entry such as "8000-8100/tcp" into port names
+func parsePortSpec(spec string) ([]string, error) {
+	ports, protocol := spec, "tcp"
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		ports, protocol = spec[:i], strings.ToLower(strings.TrimSpace(spec[i+1:]))
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("invalid protocol in %q", spec)
+	}
+
+	numbers, err := portscan.ParsePorts(ports)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(numbers))
+	for i, number := range numbers {
+		names[i] = fmt.Sprintf("%d/%s", number, protocol)
+	}
+	return names, nil
+}
+
+// normalizeDeviceAddress writes MAC addresses in upper case and leaves IP
+// addresses as they are
+func normalizeDeviceAddress(address string) string {
+	if mac, err := net.ParseMAC(address); err == nil {
+		return strings.ToUpper(mac.String())
+	}
+	return address
+}
+
+// LoadPortProfiles loads the accepted port profiles from file
+func LoadPortProfiles(filename string) (PortProfiles, error) {
+	profiles := PortProfiles{}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Create empty file
+			data, _ := json.MarshalIndent(profiles, "", "  ")
+			_ = os.WriteFile(filename, data, 0644)
+			return profiles, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse port profiles %s: %v", filename, err)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,102 @@
+package scanners
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestPortBaselineDetectsPortChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "port_baseline.json")
+	profiles := PortProfiles{"aa:bb:cc:dd:ee:01": {"80/tcp", "8000-8100"}}
+
+	b, err := NewPortBaseline(path, profiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	device := func(mac string, ports ...models.Port) models.NetworkDevice {
+		return models.NetworkDevice{IP: "192.168.1.50", MAC: mac, Name: "plug.lan", Ports: ports}
+	}
+	ssh := models.Port{Number: 22, Protocol: "tcp", Service: "ssh", Version: "OpenSSH 9.6", State: "open"}
+	web := models.Port{Number: 80, Protocol: "tcp", Service: "http", State: "open"}
+	alt := models.Port{Number: 8080, Protocol: "tcp", Service: "http-proxy", State: "open"}
+	dns := models.Port{Number: 53, Protocol: "udp", Service: "domain", State: "open"}
+
+	// First sighting, before the MAC is known, only sets the baseline
+	if attacks := b.Detect([]models.NetworkDevice{device("", dns)}); len(attacks) != 0 {
+		t.Errorf("first scan attacks = %+v", attacks)
+	}
+
+	// The baseline survives a restart and follows the device to its MAC
+	b, err = NewPortBaseline(path, profiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacks := b.Detect([]models.NetworkDevice{device("AA:BB:CC:DD:EE:01", ssh, web, alt)})
+
+	var got []string
+	for _, attack := range attacks {
+		if attack.Target != "192.168.1.50" {
+			t.Errorf("target = %s", attack.Target)
+		}
+		got = append(got, attack.Type+": "+attack.Description)
+	}
+	want := []string{
+		"NEW_OPEN_PORT: New open port 22/tcp ssh OpenSSH 9.6 on 192.168.1.50 (AA:BB:CC:DD:EE:01, plug.lan)",
+		"PORT_CLOSED: Port 53/udp closed on 192.168.1.50 (AA:BB:CC:DD:EE:01, plug.lan)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("attacks = %q, want %q", got, want)
+	}
+
+	// Unchanged ports raise nothing
+	if attacks := b.Detect([]models.NetworkDevice{device("AA:BB:CC:DD:EE:01", ssh, web, alt)}); len(attacks) != 0 {
+		t.Errorf("unchanged scan attacks = %+v", attacks)
+	}
+}
+
+func TestPortBaselineKeepsBaselineWithoutOpenPorts(t *testing.T) {
+	b, err := NewPortBaseline(filepath.Join(t.TempDir(), "port_baseline.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ssh := models.Port{Number: 22, Protocol: "tcp", Service: "ssh", State: "open"}
+	web := models.Port{Number: 80, Protocol: "tcp", Service: "http", State: "open"}
+	scanned := models.NetworkDevice{IP: "192.168.1.50", MAC: "AA:BB:CC:DD:EE:01", Ports: []models.Port{ssh, web}}
+	unanswered := models.NetworkDevice{IP: "192.168.1.50", MAC: "AA:BB:CC:DD:EE:01"}
+
+	if attacks := b.Detect([]models.NetworkDevice{scanned}); len(attacks) != 0 {
+		t.Errorf("first scan attacks = %+v", attacks)
+	}
+	// A port scan that found nothing neither closes nor forgets the ports
+	if attacks := b.Detect([]models.NetworkDevice{unanswered}); len(attacks) != 0 {
+		t.Errorf("empty scan attacks = %+v, want none", attacks)
+	}
+	if attacks := b.Detect([]models.NetworkDevice{scanned}); len(attacks) != 0 {
+		t.Errorf("attacks after the ports came back = %+v, want none", attacks)
+	}
+
+	// A device first seen without open ports gets no baseline yet
+	fresh := models.NetworkDevice{IP: "192.168.1.60"}
+	b.Detect([]models.NetworkDevice{fresh})
+	fresh.Ports = []models.Port{ssh}
+	if attacks := b.Detect([]models.NetworkDevice{fresh}); len(attacks) != 0 {
+		t.Errorf("attacks = %+v, want the first ports to set the baseline", attacks)
+	}
+}
+
+func TestParsePortSpec(t *testing.T) {
+	names, err := parsePortSpec("5353-5354/UDP")
+	if err != nil || strings.Join(names, ",") != "5353/udp,5354/udp" {
+		t.Errorf("parsePortSpec() = %v, %v", names, err)
+	}
+	for _, spec := range []string{"22/sctp", "ssh", "0/tcp"} {
+		if _, err := parsePortSpec(spec); err == nil {
+			t.Errorf("parsePortSpec(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package scanners
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+)
+
+// Scanner is a sensor that can be driven by the detector pipeline.
+// Devices are passed around as []interface{} so that each sensor can use its
+// own device model (see models.ScanResult).
+type Scanner interface {
+	// Name returns a short identifier used in logs and scan results
+	Name() string
+	// Scan collects devices from the sensor
+	Scan() ([]interface{}, error)
+	// Detect analyzes devices returned by Scan and reports attacks
+	Detect(devices []interface{}) []models.Attack
+	// Health reports whether the sensor is able to run on this host
+	Health() models.ScannerHealth
+}
+
+// Registry holds the scanners run by the detector, in registration order
+type Registry struct {
+	scanners []Scanner
+	mu       sync.RWMutex
+}
+
+// NewRegistry creates an empty scanner registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a scanner to the registry. Scanner names must be unique.
+func (r *Registry) Register(scanner Scanner) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.scanners {
+		if existing.Name() == scanner.Name() {
+			return fmt.Errorf("scanner %s is already registered", scanner.Name())
+		}
+	}
+
+	r.scanners = append(r.scanners, scanner)
+	return nil
+}
+
+// Unregister removes the named scanner, reporting whether it was registered
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.scanners {
+		if existing.Name() == name {
+			r.scanners = append(r.scanners[:i], r.scanners[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the named scanner
+func (r *Registry) Get(name string) (Scanner, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, existing := range r.scanners {
+		if existing.Name() == name {
+			return existing, true
+		}
+	}
+	return nil, false
+}
+
+// Scanners returns a snapshot of the registered scanners
+func (r *Registry) Scanners() []Scanner {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scanners := make([]Scanner, len(r.scanners))
+	copy(scanners, r.scanners)
+	return scanners
+}
+
+// Health returns the health of every registered scanner
+func (r *Registry) Health() []models.ScannerHealth {
+	scanners := r.Scanners()
+	health := make([]models.ScannerHealth, len(scanners))
+	for i, scanner := range scanners {
+		health[i] = scanner.Health()
+	}
+	return health
+}
+
+// toInterfaces converts a typed device slice for use in the Scanner interface
+func toInterfaces[T any](devices []T) []interface{} {
+	result := make([]interface{}, len(devices))
+	for i, device := range devices {
+		result[i] = device
+	}
+	return result
+}
+
+// fromInterfaces extracts devices of type T, ignoring anything else
+func fromInterfaces[T any](devices []interface{}) []T {
+	var result []T
+	for _, device := range devices {
+		if typed, ok := device.(T); ok {
+			result = append(result, typed)
+		}
+	}
+	return result
+}
+
+// toolHealth builds a health report from the first available tool in tools
+func toolHealth(r runner.CommandRunner, name string, tools ...string) models.ScannerHealth {
+	for _, tool := range tools {
+		if r.Available(tool) {
+			return models.ScannerHealth{
+				Name:      name,
+				Available: true,
+				Message:   fmt.Sprintf("using %s", tool),
+			}
+		}
+	}
+	return models.ScannerHealth{
+		Name:      name,
+		Available: false,
+		Message:   fmt.Sprintf("none of %v available", tools),
+	}
+}
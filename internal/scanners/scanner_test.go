@@ -0,0 +1,74 @@
+package scanners
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// namedScanner is a scanner that only has a name
+type namedScanner string
+
+func (s namedScanner) Name() string                                 { return string(s) }
+func (s namedScanner) Scan() ([]interface{}, error)                 { return nil, nil }
+func (s namedScanner) Detect(devices []interface{}) []models.Attack { return nil }
+func (s namedScanner) Health() models.ScannerHealth {
+	return models.ScannerHealth{Name: string(s), Available: true}
+}
+
+func registeredNames(r *Registry) []string {
+	var names []string
+	for _, scanner := range r.Scanners() {
+		names = append(names, scanner.Name())
+	}
+	return names
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"network", "arp", "wifi"} {
+		if err := r.Register(namedScanner(name)); err != nil {
+			t.Fatalf("Register(%s) error = %v", name, err)
+		}
+	}
+
+	// Scanners run in registration order
+	if got, want := registeredNames(r), []string{"network", "arp", "wifi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanners = %v, want %v", got, want)
+	}
+	if err := r.Register(namedScanner("arp")); err == nil {
+		t.Error("Register() accepted a duplicate name")
+	}
+
+	if scanner, ok := r.Get("arp"); !ok || scanner.Name() != "arp" {
+		t.Errorf("Get(arp) = %v, %v", scanner, ok)
+	}
+	if _, ok := r.Get("bluetooth"); ok {
+		t.Error("Get(bluetooth) found an unregistered scanner")
+	}
+
+	// A snapshot does not follow later changes to the registry
+	snapshot := r.Scanners()
+	if !r.Unregister("arp") {
+		t.Error("Unregister(arp) = false, want true")
+	}
+	if r.Unregister("arp") {
+		t.Error("second Unregister(arp) = true, want false")
+	}
+	if got, want := registeredNames(r), []string{"network", "wifi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanners after Unregister = %v, want %v", got, want)
+	}
+	if len(snapshot) != 3 || snapshot[1].Name() != "arp" {
+		t.Errorf("snapshot changed to %v", snapshot)
+	}
+
+	// The name is free again once unregistered, and goes to the end
+	if err := r.Register(namedScanner("arp")); err != nil {
+		t.Fatalf("Register(arp) after Unregister error = %v", err)
+	}
+	health := r.Health()
+	if len(health) != 3 || health[2].Name != "arp" || !health[2].Available {
+		t.Errorf("Health() = %+v", health)
+	}
+}
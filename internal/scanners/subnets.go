@@ -0,0 +1,114 @@
+package scanners
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
+)
+
+// maxSweepHostBits is the largest IPv6 host part swept address by address.
+// Larger prefixes (such as a /64) are discovered with multicast echo instead.
+const maxSweepHostBits = 16
+
+// SubnetFilter selects which of the host's subnets are scanned
+type SubnetFilter struct {
+	include []*net.IPNet
+	exclude []*net.IPNet
+}
+
+// NewSubnetFilter parses include and exclude CIDR lists. With an empty
+// include list every local subnet is selected.
+func NewSubnetFilter(include []string, exclude []string) (SubnetFilter, error) {
+	var filter SubnetFilter
+	var err error
+
+	if filter.include, err = parseCIDRs(include); err != nil {
+		return filter, fmt.Errorf("invalid include network: %v", err)
+	}
+	if filter.exclude, err = parseCIDRs(exclude); err != nil {
+		return filter, fmt.Errorf("invalid exclude network: %v", err)
+	}
+	return filter, nil
+}
+
+// Select returns the subnets to scan. A subnet is kept if it lies inside an
+// include network; an include network inside a larger local subnet narrows
+// the scan to that network. Subnets inside an exclude network are dropped.
+func (f SubnetFilter) Select(subnets []netinfo.Subnet) []netinfo.Subnet {
+	var selected []netinfo.Subnet
+	seen := make(map[string]bool)
+
+	add := func(subnet netinfo.Subnet) {
+		key := subnet.Interface + " " + subnet.Network.String()
+		if seen[key] || f.excludesNetwork(subnet.Network) {
+			return
+		}
+		seen[key] = true
+		selected = append(selected, subnet)
+	}
+
+	for _, subnet := range subnets {
+		if len(f.include) == 0 {
+			add(subnet)
+			continue
+		}
+		for _, include := range f.include {
+			switch {
+			case containsNetwork(include, subnet.Network):
+				add(subnet)
+			case containsNetwork(subnet.Network, include):
+				add(netinfo.Subnet{Interface: subnet.Interface, Network: include})
+			}
+		}
+	}
+	return selected
+}
+
+// Excludes reports whether an address lies in an exclude network
+func (f SubnetFilter) Excludes(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, exclude := range f.exclude {
+		if exclude.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f SubnetFilter) excludesNetwork(network *net.IPNet) bool {
+	for _, exclude := range f.exclude {
+		if containsNetwork(exclude, network) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsNetwork reports whether inner lies entirely inside outer
+func containsNetwork(outer *net.IPNet, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// sweepable reports whether a subnet is small enough to probe every address
+func sweepable(subnet netinfo.Subnet) bool {
+	ones, bits := subnet.Network.Mask.Size()
+	return subnet.IsIPv4() || bits-ones <= maxSweepHostBits
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -3,43 +3,295 @@ package scanners
 import (
 	"bufio"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/boboTheFoff/shheissee-go/internal/airodump"
+	"github.com/boboTheFoff/shheissee-go/internal/dot11"
 	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/nl80211"
+	"github.com/boboTheFoff/shheissee-go/internal/oui"
+	"github.com/boboTheFoff/shheissee-go/internal/rules"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
 )
 
+// airodumpSeconds is how long a single airodump-ng capture runs
+const airodumpSeconds = 10
+
+// nl80211ScanTimeout bounds the wait for a triggered nl80211 scan
+const nl80211ScanTimeout = 10 * time.Second
+
+// frameCaptureTime is how long DetectDeauthenticationAttacks listens for
+// management frames
+const frameCaptureTime = 10 * time.Second
+
 // WiFiScanner handles WiFi network scanning and attack detection
-type WiFiScanner struct{}
+type WiFiScanner struct {
+	runner           runner.CommandRunner
+	rules            *rules.Engine
+	vendors          *oui.Database
+	monitorInterface string
+	capture          func(iface string) (*airodump.Capture, error)
+	frames           func(iface string) ([]dot11.Frame, error)
+	scanBSS          func() ([]nl80211.BSS, error)
+	checkNl80211     func() error
+	deauth           *DeauthMonitor
+	evilTwin         *EvilTwinDetector
+}
 
 // NewWiFiScanner creates a new WiFi scanner
 func NewWiFiScanner() *WiFiScanner {
-	return &WiFiScanner{}
+	ws := &WiFiScanner{
+		runner:  runner.NewExecRunner(),
+		rules:   rules.Default(),
+		vendors: oui.Embedded(),
+		frames: func(iface string) ([]dot11.Frame, error) {
+			return dot11.Capture(iface, frameCaptureTime)
+		},
+		scanBSS: func() ([]nl80211.BSS, error) {
+			return nl80211.Scan(nl80211ScanTimeout)
+		},
+		checkNl80211: nl80211.Check,
+		deauth:       NewDeauthMonitor(10*time.Second, 30, 10),
+	}
+	ws.capture = ws.captureAirodump
+	return ws
+}
+
+// SetRunner replaces the runner used to execute external tools
+func (ws *WiFiScanner) SetRunner(r runner.CommandRunner) {
+	ws.runner = r
+}
+
+// SetRules replaces the detection rules
+func (ws *WiFiScanner) SetRules(engine *rules.Engine) {
+	ws.rules = engine
+}
+
+// SetVendors replaces the registry used to name the vendor of BSSIDs
+func (ws *WiFiScanner) SetVendors(db *oui.Database) {
+	ws.vendors = db
+}
+
+// SetMonitorInterface selects the monitor-mode interface airodump-ng
+// captures on. Without one the first interface in monitor mode is used.
+func (ws *WiFiScanner) SetMonitorInterface(iface string) {
+	ws.monitorInterface = iface
+}
+
+// SetDeauthMonitor replaces the deauthentication flood thresholds
+func (ws *WiFiScanner) SetDeauthMonitor(m *DeauthMonitor) {
+	ws.deauth = m
 }
 
-// ScanWiFiNetworks discovers nearby WiFi access points and devices
+// SetEvilTwinDetector enables evil twin detection against the trusted-AP
+// registry. Without it no network is trusted and none is checked.
+func (ws *WiFiScanner) SetEvilTwinDetector(d *EvilTwinDetector) {
+	ws.evilTwin = d
+}
+
+// Name implements Scanner
+func (ws *WiFiScanner) Name() string {
+	return "wifi"
+}
+
+// Scan implements Scanner
+func (ws *WiFiScanner) Scan() ([]interface{}, error) {
+	devices, err := ws.ScanWiFiNetworks()
+	if err != nil {
+		return nil, err
+	}
+	return toInterfaces(devices), nil
+}
+
+// Detect implements Scanner
+func (ws *WiFiScanner) Detect(devices []interface{}) []models.Attack {
+	return ws.DetectWiFiAttacks(fromInterfaces[models.WiFiDevice](devices))
+}
+
+// Health implements Scanner
+func (ws *WiFiScanner) Health() models.ScannerHealth {
+	if err := ws.checkNl80211(); err == nil {
+		return models.ScannerHealth{Name: ws.Name(), Available: true, Message: "using nl80211"}
+	}
+	return toolHealth(ws.runner, ws.Name(), "iwlist", "nmcli")
+}
+
+// ScanWiFiNetworks discovers nearby WiFi access points and devices. An
+// airodump-ng capture on a monitor-mode interface also reports beacons and
+// associated clients. Otherwise the kernel is asked over nl80211, and
+// iwlist and nmcli are the fallbacks where it is unavailable.
 func (ws *WiFiScanner) ScanWiFiNetworks() ([]models.WiFiDevice, error) {
-	devices, err := ws.scanWithIwlist()
+	devices, err := ws.scanWithAirodump()
+	if err != nil {
+		devices, err = ws.scanWithNl80211()
+	}
+	if err != nil {
+		devices, err = ws.scanWithIwlist()
+	}
 	if err != nil {
 		devices, err = ws.scanWithNmcli()
 		if err != nil {
 			return nil, fmt.Errorf("no WiFi scanning method available: %v", err)
 		}
 	}
+
+	for i := range devices {
+		devices[i].Vendor = ws.vendors.Lookup(devices[i].Address)
+		devices[i].LocallyAdministered = oui.IsLocallyAdministered(devices[i].Address)
+	}
 	return devices, nil
 }
 
+// scanWithAirodump captures with airodump-ng on a monitor-mode interface
+func (ws *WiFiScanner) scanWithAirodump() ([]models.WiFiDevice, error) {
+	if !ws.runner.Available("airodump-ng") {
+		return nil, fmt.Errorf("airodump-ng not available")
+	}
+	iface := ws.findMonitorInterface()
+	if iface == "" {
+		return nil, fmt.Errorf("no interface in monitor mode")
+	}
+
+	capture, err := ws.capture(iface)
+	if err != nil {
+		return nil, err
+	}
+	return devicesFromCapture(capture), nil
+}
+
+// findMonitorInterface returns the configured monitor interface or the
+// first interface iwconfig reports in monitor mode
+func (ws *WiFiScanner) findMonitorInterface() string {
+	if ws.monitorInterface != "" {
+		return ws.monitorInterface
+	}
+	if !ws.runner.Available("iwconfig") {
+		return ""
+	}
+	output, err := ws.runner.CombinedOutput("iwconfig")
+	if err != nil {
+		return ""
+	}
+	return parseMonitorInterface(string(output))
+}
+
+// parseMonitorInterface finds the first interface in monitor mode in
+// iwconfig output, where each interface's block starts unindented
+func parseMonitorInterface(output string) string {
+	current := ""
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			current = strings.Fields(line)[0]
+		}
+		if current != "" && strings.Contains(line, "Mode:Monitor") {
+			return current
+		}
+	}
+	return ""
+}
+
+// captureAirodump runs airodump-ng for airodumpSeconds and reads the CSV it
+// wrote. timeout stops airodump with a non-zero status, so a failure only
+// counts when no CSV was written.
+func (ws *WiFiScanner) captureAirodump(iface string) (*airodump.Capture, error) {
+	dir, err := os.MkdirTemp("", "shheissee-airodump-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	prefix := filepath.Join(dir, "scan")
+	output, runErr := ws.runner.CombinedOutput("timeout", strconv.Itoa(airodumpSeconds),
+		"airodump-ng", "--output-format", "csv", "--write-interval", "1", "-w", prefix, iface)
+
+	capture, err := airodump.ParseFile(prefix + "-01.csv")
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("airodump-ng failed: %v: %s", runErr, strings.TrimSpace(string(output)))
+		}
+		return nil, err
+	}
+	return capture, nil
+}
+
+// devicesFromCapture converts the access points of a capture, with the
+// stations associated with each
+func devicesFromCapture(capture *airodump.Capture) []models.WiFiDevice {
+	var devices []models.WiFiDevice
+	for _, ap := range capture.AccessPoints {
+		device := models.WiFiDevice{
+			Address:  ap.BSSID,
+			SSID:     ap.ESSID,
+			Security: securityFromAirodump(ap.Privacy, ap.Cipher, ap.Auth),
+			Beacons:  ap.Beacons,
+		}
+		if ap.Channel > 0 {
+			device.Channel = strconv.Itoa(ap.Channel)
+		}
+		if ap.Power < -1 {
+			device.Power = ap.Power
+			device.Signal = fmt.Sprintf("%d dBm", ap.Power)
+		}
+		for _, client := range capture.Clients(ap.BSSID) {
+			device.Clients = append(device.Clients, client.MAC)
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// scanWithNl80211 asks the kernel for the access points its wireless
+// interfaces see
+func (ws *WiFiScanner) scanWithNl80211() ([]models.WiFiDevice, error) {
+	bsses, err := ws.scanBSS()
+	if err != nil {
+		return nil, err
+	}
+	return devicesFromBSS(bsses), nil
+}
+
+// devicesFromBSS converts the BSS entries of an nl80211 scan
+func devicesFromBSS(bsses []nl80211.BSS) []models.WiFiDevice {
+	var devices []models.WiFiDevice
+	for _, bss := range bsses {
+		device := models.WiFiDevice{
+			Address:   bss.BSSID,
+			SSID:      bss.SSID(),
+			Frequency: bss.Frequency,
+			Status:    "Unknown",
+			Security:  securityFromIEs(bss),
+		}
+		if channel := bss.Channel(); channel > 0 {
+			device.Channel = strconv.Itoa(channel)
+		}
+		if bss.SignalMBm != 0 {
+			device.Power = bss.SignalMBm / 100
+			device.Signal = fmt.Sprintf("%d dBm", device.Power)
+		}
+		if !bss.LastSeen.IsZero() {
+			lastSeen := bss.LastSeen
+			device.LastSeen = &lastSeen
+		}
+		if bss.Associated {
+			device.Status = "Associated"
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
+
 // scanWithIwlist uses iwlist to scan for WiFi networks
 func (ws *WiFiScanner) scanWithIwlist() ([]models.WiFiDevice, error) {
-	if !isCommandAvailable("iwlist") {
+	if !ws.runner.Available("iwlist") {
 		return nil, fmt.Errorf("iwlist not available")
 	}
 
-	cmd := exec.Command("iwlist", "scan")
-	output, err := cmd.CombinedOutput()
+	output, err := ws.runner.CombinedOutput("iwlist", "scan")
 	if err != nil {
 		return nil, err
 	}
@@ -49,12 +301,11 @@ func (ws *WiFiScanner) scanWithIwlist() ([]models.WiFiDevice, error) {
 
 // scanWithNmcli uses nmcli as alternative
 func (ws *WiFiScanner) scanWithNmcli() ([]models.WiFiDevice, error) {
-	if !isCommandAvailable("nmcli") {
+	if !ws.runner.Available("nmcli") {
 		return nil, fmt.Errorf("nmcli not available")
 	}
 
-	cmd := exec.Command("nmcli", "device", "wifi", "list")
-	output, err := cmd.CombinedOutput()
+	output, err := ws.runner.CombinedOutput("nmcli", "-t", "-f", "SSID,BSSID,CHAN,SIGNAL,SECURITY", "device", "wifi", "list")
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +317,16 @@ func (ws *WiFiScanner) scanWithNmcli() ([]models.WiFiDevice, error) {
 func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 	var devices []models.WiFiDevice
 	var currentDevice models.WiFiDevice
+	var security iwlistSecurity
+
+	flush := func() {
+		if currentDevice.Address != "" {
+			currentDevice.Security = security.result()
+			devices = append(devices, currentDevice)
+		}
+		currentDevice = models.WiFiDevice{}
+		security = iwlistSecurity{}
+	}
 
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
@@ -73,10 +334,7 @@ func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 
 		// New cell/cell starts
 		if strings.HasPrefix(line, "Cell ") || strings.Contains(line, "Address:") {
-			if currentDevice.Address != "" {
-				devices = append(devices, currentDevice)
-			}
-			currentDevice = models.WiFiDevice{}
+			flush()
 		}
 
 		// Extract BSSID/MAC address
@@ -89,9 +347,7 @@ func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 
 		// Extract SSID
 		if strings.HasPrefix(line, "ESSID:") {
-			ssid := strings.Trim(line, "ESSID:\"")
-			ssid = strings.TrimSuffix(ssid, "\"")
-			currentDevice.SSID = ssid
+			currentDevice.SSID = strings.Trim(strings.TrimPrefix(line, "ESSID:"), "\"")
 		}
 
 		// Extract signal level
@@ -112,117 +368,122 @@ func (ws *WiFiScanner) parseIwlistOutput(output string) []models.WiFiDevice {
 				currentDevice.Channel = strings.TrimSpace(parts[1])
 			}
 		}
+
+		// Encryption key, WPA/RSN information elements, ciphers and suites
+		security.parseLine(line)
 	}
 
 	// Add the last device
-	if currentDevice.Address != "" {
-		devices = append(devices, currentDevice)
-	}
+	flush()
 
 	return devices
 }
 
-// parseNmcliOutput parses nmcli wifi list output
+// parseNmcliOutput parses the terse output of
+// "nmcli -t -f SSID,BSSID,CHAN,SIGNAL,SECURITY device wifi list", one
+// colon-separated line per access point with colons in values escaped
 func (ws *WiFiScanner) parseNmcliOutput(output string) []models.WiFiDevice {
 	var devices []models.WiFiDevice
 
 	scanner := bufio.NewScanner(strings.NewReader(output))
-	// Skip header line
-	if scanner.Scan() {
-		// Header line
-	}
-
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+		parts := splitNmcliFields(scanner.Text())
+		if len(parts) < 5 {
 			continue
 		}
 
-		// Parse nmcli format: "* SSID BSSID MODE CHAN RATE SIGNAL BARS SECURITY"
-		// This is approximate parsing - real nmcli output may vary
-		parts := strings.Fields(line)
-		if len(parts) >= 6 {
-			device := models.WiFiDevice{
-				SSID:   parts[1],
-				Address: parts[2],
-				Channel: parts[4],
-				Signal: fmt.Sprintf("%s dBm", parts[6]), // Adjust based on actual output
-				Status: "Unknown",
-			}
-			devices = append(devices, device)
+		device := models.WiFiDevice{
+			SSID:     parts[0],
+			Address:  strings.ToUpper(parts[1]),
+			Channel:  parts[2],
+			Security: securityFromNmcli(parts[4]),
+			Status:   "Unknown",
+		}
+		if device.SSID == "--" {
+			device.SSID = ""
+		}
+		// nmcli reports signal quality in percent; map it onto -100..-50 dBm
+		if percent, err := strconv.Atoi(parts[3]); err == nil {
+			device.Signal = fmt.Sprintf("%d dBm", percent/2-100)
 		}
+		devices = append(devices, device)
 	}
 
 	return devices
 }
 
+// splitNmcliFields splits a terse nmcli line on unescaped colons
+func splitNmcliFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(line[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
 // DetectWiFiAttacks analyzes WiFi networks for attack patterns
 func (ws *WiFiScanner) DetectWiFiAttacks(devices []models.WiFiDevice) []models.Attack {
-	var attacks []models.Attack
-
-	// Evil Twin Detection - Duplicate SSIDs
-	ssidCounts := make(map[string][]string)
-	for _, device := range devices {
-		if device.SSID != "" && device.SSID != "Hidden" {
-			ssidCounts[device.SSID] = append(ssidCounts[device.SSID], device.Address)
-		}
+	// Per-network checks such as rogue SSID patterns come from the rules file
+	records := make([]rules.Fields, len(devices))
+	for i, device := range devices {
+		records[i] = wifiFields(device)
 	}
+	attacks := ws.rules.Evaluate(rules.SourceWiFi, records)
 
-	for ssid, addresses := range ssidCounts {
-		if len(addresses) > 1 {
-			attacks = append(attacks, models.Attack{
-				Type:        "EVIL_TWIN",
-				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Potential evil twin attack: SSID '%s' appears %d times", ssid, len(addresses)),
-				Target:      ssid,
-				Timestamp:   time.Now(),
-			})
-		}
+	// Evil Twin Detection - trusted SSIDs from unapproved or altered access points
+	if ws.evilTwin != nil {
+		attacks = append(attacks, ws.evilTwin.Check(devices)...)
 	}
 
-	// Rogue Access Point Detection
-	roguePatterns := []string{"free", "public", "hack", "test", "evil", "wifi", "guest", "default"}
+	// Encryption checks need the security the scan reported
 	for _, device := range devices {
-		ssidLower := strings.ToLower(device.SSID)
-		for _, pattern := range roguePatterns {
-			if strings.Contains(ssidLower, pattern) {
-				attacks = append(attacks, models.Attack{
-					Type:        "ROGUE_AP",
-					Severity:    models.SeverityHigh,
-					Description: fmt.Sprintf("Potentially rogue access point detected: %s", device.SSID),
-					Target:      device.SSID,
-					Timestamp:   time.Now(),
-				})
-				break
-			}
+		security := device.Security
+		if security == nil {
+			continue
 		}
-	}
+		network := networkName(device)
 
-	// Open Network Detection
-	for _, device := range devices {
-		// In iwlist, check for "Encryption key:off"
-		// For simplicity, we'll note suspicious SSIDs that might indicate open networks
-		if device.SSID != "" && strings.Contains(strings.ToLower(device.SSID), "open") {
+		switch {
+		case security.Open():
 			attacks = append(attacks, models.Attack{
 				Type:        "OPEN_NETWORK",
 				Severity:    models.SeverityMedium,
-				Description: fmt.Sprintf("Open WiFi network detected: %s", device.SSID),
-				Target:      device.SSID,
+				Description: fmt.Sprintf("Open WiFi network detected: %s", network),
+				Target:      device.Address,
 				Timestamp:   time.Now(),
 			})
-		}
-	}
-
-	// Weak Encryption Detection
-	for _, device := range devices {
-		// Look for indicators of WEP encryption in SSID or capabilities
-		if strings.Contains(strings.ToLower(device.SSID), "wep") ||
-		   (device.Address != "" && isWEPMAC(device.Address)) {
+		case security.WEP():
 			attacks = append(attacks, models.Attack{
 				Type:        "WEAK_ENCRYPTION",
 				Severity:    models.SeverityHigh,
-				Description: fmt.Sprintf("Weak encryption (WEP) detected on network: %s", device.SSID),
-				Target:      device.SSID,
+				Description: fmt.Sprintf("Weak encryption (WEP) detected on network: %s", network),
+				Target:      device.Address,
+				Timestamp:   time.Now(),
+			})
+		case security.TKIPOnly():
+			attacks = append(attacks, models.Attack{
+				Type:        "WEAK_ENCRYPTION",
+				Severity:    models.SeverityMedium,
+				Description: fmt.Sprintf("Weak encryption (TKIP only) detected on network: %s", network),
+				Target:      device.Address,
+				Timestamp:   time.Now(),
+			})
+		case security.Transition():
+			attacks = append(attacks, models.Attack{
+				Type:        "WPA3_TRANSITION_MODE",
+				Severity:    models.SeverityLow,
+				Description: fmt.Sprintf("WPA3 network %s also accepts WPA2, allowing downgrade attacks", network),
+				Target:      device.Address,
 				Timestamp:   time.Now(),
 			})
 		}
@@ -242,14 +503,47 @@ func (ws *WiFiScanner) DetectWiFiAttacks(devices []models.WiFiDevice) []models.A
 	return attacks
 }
 
+// wifiFields exposes an access point to the detection rules
+func wifiFields(device models.WiFiDevice) rules.Fields {
+	fields := rules.Fields{
+		"address":              device.Address,
+		"ssid":                 device.SSID,
+		"vendor":               device.Vendor,
+		"locally_administered": device.LocallyAdministered,
+		"signal":               device.Signal,
+		"channel":              device.Channel,
+		"frequency":            device.Frequency,
+		"status":               device.Status,
+		"beacons":              device.Beacons,
+		"clients":              len(device.Clients),
+	}
+	// Without a reported security the fields are left out, so no rule
+	// mistakes an unknown network for an open one
+	if security := device.Security; security != nil {
+		fields["encrypted"] = security.Encrypted
+		fields["protocols"] = strings.Join(security.Protocols, " ")
+		fields["group_cipher"] = security.GroupCipher
+		fields["pairwise_ciphers"] = strings.Join(security.PairwiseCiphers, " ")
+		fields["auth_suites"] = strings.Join(security.AuthSuites, " ")
+	}
+	return fields
+}
+
+// networkName names an access point by SSID, or by BSSID if it is hidden
+func networkName(device models.WiFiDevice) string {
+	if device.SSID == "" {
+		return device.Address
+	}
+	return device.SSID
+}
+
 // checkWPSVulnerabilities checks for WPS-enabled networks
 func (ws *WiFiScanner) checkWPSVulnerabilities(devices []models.WiFiDevice) bool {
-	if !isCommandAvailable("wash") { // Requires reaver tools
+	if !ws.runner.Available("wash") { // Requires reaver tools
 		return false
 	}
 
-	cmd := exec.Command("wash", "-i", "wlan0", "-s") // This would require wireless interface
-	output, err := cmd.CombinedOutput()
+	output, err := ws.runner.CombinedOutput("wash", "-i", "wlan0", "-s") // This would require wireless interface
 	if err != nil {
 		return false
 	}
@@ -262,51 +556,30 @@ func (ws *WiFiScanner) checkWPSVulnerabilities(devices []models.WiFiDevice) bool
 	return false
 }
 
-// DetectDeauthenticationAttacks detects WiFi deauth attacks using airodump-ng
+// DetectDeauthenticationAttacks listens for management frames on the monitor
+// interface and reports deauthentication and disassociation floods
 func (ws *WiFiScanner) DetectDeauthenticationAttacks() []models.Attack {
-	var attacks []models.Attack
-
-	if !isCommandAvailable("airodump-ng") {
-		return attacks
+	iface := ws.findMonitorInterface()
+	if iface == "" {
+		return nil
 	}
 
-	// Run airodump-ng for a short period to collect data
-	cmd := exec.Command("timeout", "10", "airodump-ng", "--output-format", "csv", "-w", "/tmp/wifi_scan")
-	output, err := cmd.CombinedOutput()
+	frames, err := ws.frames(iface)
 	if err != nil {
-		return attacks
+		return nil
 	}
-
-	// Parse output for deauthentication patterns
-	outputStr := string(output)
-	deauthCount := strings.Count(outputStr, "DEAUTH")
-
-	if deauthCount > 5 { // Threshold for attack detection
-		attacks = append(attacks, models.Attack{
-			Type:        "WIFI_DEAUTH_ATTACK",
-			Severity:    models.SeverityHigh,
-			Description: fmt.Sprintf("Deauthentication attack detected: %d deauth packets observed", deauthCount),
-			Target:      "wifi_network",
-			Timestamp:   time.Now(),
-		})
-	}
-
-	// Clean up temp file
-	exec.Command("rm", "-f", "/tmp/wifi_scan-01.csv", "/tmp/wifi_scan-01.cap").Run()
-
-	return attacks
+	return ws.deauth.Observe(frames)
 }
 
 // CheckWiFiInterfaceStatus checks the status of wireless interfaces
 func (ws *WiFiScanner) CheckWiFiInterfaceStatus() []models.Attack {
 	var attacks []models.Attack
 
-	if !isCommandAvailable("iwconfig") {
+	if !ws.runner.Available("iwconfig") {
 		return attacks
 	}
 
-	cmd := exec.Command("iwconfig")
-	output, err := cmd.CombinedOutput()
+	output, err := ws.runner.CombinedOutput("iwconfig")
 	if err != nil {
 		return attacks
 	}
@@ -327,16 +600,6 @@ func (ws *WiFiScanner) CheckWiFiInterfaceStatus() []models.Attack {
 	return attacks
 }
 
-// Helper functions
-
-// isWEPMAC makes a rough guess about WEP encryption based on MAC characteristics
-// This is not reliable but provides basic detection
-func isWEPMAC(mac string) bool {
-	// Very basic heuristic - not accurate but provides some detection
-	// In a real implementation, you would need to examine the actual capabilities
-	return false // Placeholder - would need actual capability parsing
-}
-
 // MonitorWiFiAttacks continuously monitors for WiFi attacks
 func (ws *WiFiScanner) MonitorWiFiAttacks() (<-chan models.Attack, error) {
 	attackCh := make(chan models.Attack, 100)
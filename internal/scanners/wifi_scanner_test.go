@@ -0,0 +1,263 @@
+package scanners
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/airodump"
+	"github.com/boboTheFoff/shheissee-go/internal/dot11"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/nl80211"
+	"github.com/boboTheFoff/shheissee-go/internal/runner"
+)
+
+func TestParseIwlistOutput(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/iwlist_scan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		want   []models.WiFiDevice
+	}{
+		{
+			name:   "no networks",
+			output: "wlan0     No scan results\n",
+			want:   nil,
+		},
+		{
+			name:   "recorded scan",
+			output: string(recorded),
+			want: []models.WiFiDevice{
+				{Address: "3C:84:6A:12:34:56", SSID: "SmithHome", Signal: "-48 dBm", Channel: "6",
+					Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2"}, GroupCipher: "CCMP",
+						PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"PSK"}}},
+				{Address: "9A:DE:D0:11:22:33", SSID: "DIRECT-5A-HP OfficeJet", Signal: "-70 dBm", Channel: "11",
+					Security: &models.WiFiSecurity{}},
+				{Address: "F4:F2:6D:44:55:66", SSID: "", Signal: "-80 dBm", Channel: "36",
+					Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WEP"}}},
+				{Address: "00:14:6C:AB:CD:EF", SSID: "Legacy", Signal: "-75 dBm", Channel: "1",
+					Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA", "WPA2"}, GroupCipher: "TKIP",
+						PairwiseCiphers: []string{"TKIP"}, AuthSuites: []string{"PSK"}}},
+				{Address: "3C:84:6A:77:88:99", SSID: "Cafe, Bar", Signal: "-55 dBm", Channel: "44",
+					Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2", "WPA3"}, GroupCipher: "CCMP",
+						PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"PSK", "SAE"}}},
+			},
+		},
+	}
+
+	ws := NewWiFiScanner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ws.parseIwlistOutput(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIwlistOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNmcliOutput(t *testing.T) {
+	output := "SmithHome:3C\\:84\\:6A\\:12\\:34\\:56:6:100:WPA2\n" +
+		"Guest\\: Lobby:9a\\:de\\:d0\\:11\\:22\\:33:11:60:\n" +
+		":F4\\:F2\\:6D\\:44\\:55\\:66:36:40:WPA2 WPA3\n" +
+		"truncated:00\\:14\\:6C\n"
+
+	want := []models.WiFiDevice{
+		{Address: "3C:84:6A:12:34:56", SSID: "SmithHome", Signal: "-50 dBm", Channel: "6", Status: "Unknown",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2"}}},
+		{Address: "9A:DE:D0:11:22:33", SSID: "Guest: Lobby", Signal: "-70 dBm", Channel: "11", Status: "Unknown",
+			Security: &models.WiFiSecurity{}},
+		{Address: "F4:F2:6D:44:55:66", Signal: "-80 dBm", Channel: "36", Status: "Unknown",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2", "WPA3"}}},
+	}
+	if got := NewWiFiScanner().parseNmcliOutput(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNmcliOutput() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseNmcliOutputShortLines(t *testing.T) {
+	// Table output, blank and truncated lines have fewer than five fields
+	output := "SSID       BSSID  CHAN  SIGNAL\n\nSmithHome\nSmithHome:3C\\:84:6:100\n"
+	if got := NewWiFiScanner().parseNmcliOutput(output); got != nil {
+		t.Errorf("parseNmcliOutput() = %+v, want none", got)
+	}
+}
+
+func TestDetectWiFiEncryption(t *testing.T) {
+	recorded, err := os.ReadFile("testdata/iwlist_scan.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := NewWiFiScanner()
+	ws.SetRunner(runner.NewReplayRunner())
+	devices := ws.parseIwlistOutput(string(recorded))
+	devices = append(devices, models.WiFiDevice{Address: "02:00:00:00:00:01", SSID: "NoSecurityReported"})
+
+	var got []string
+	for _, attack := range ws.DetectWiFiAttacks(devices) {
+		got = append(got, attack.Type+" "+attack.Severity.String()+" "+attack.Target)
+	}
+	want := []string{
+		"OPEN_NETWORK MEDIUM 9A:DE:D0:11:22:33",
+		"WEAK_ENCRYPTION HIGH F4:F2:6D:44:55:66",
+		"WEAK_ENCRYPTION MEDIUM 00:14:6C:AB:CD:EF",
+		"WPA3_TRANSITION_MODE LOW 3C:84:6A:77:88:99",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DetectWiFiAttacks() = %v, want %v", got, want)
+	}
+}
+
+func TestScanWiFiNetworksWithAirodump(t *testing.T) {
+	r := runner.NewReplayRunner()
+	r.SetAvailable("airodump-ng", "iwconfig")
+	r.AddOutput("iwconfig", "wlan0     IEEE 802.11  ESSID:off/any\n          Mode:Managed  Access Point: Not-Associated\n\n"+
+		"wlan1mon  IEEE 802.11  Mode:Monitor  Frequency:2.437 GHz  Tx-Power=20 dBm\n")
+
+	ws := NewWiFiScanner()
+	ws.SetRunner(r)
+	ws.scanBSS = func() ([]nl80211.BSS, error) {
+		t.Error("nl80211 scanned although airodump-ng succeeded")
+		return nil, nil
+	}
+	var captured string
+	ws.capture = func(iface string) (*airodump.Capture, error) {
+		captured = iface
+		return airodump.ParseFile("testdata/airodump.csv")
+	}
+
+	devices, err := ws.ScanWiFiNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if captured != "wlan1mon" {
+		t.Errorf("captured on %q, want wlan1mon", captured)
+	}
+
+	want := []models.WiFiDevice{
+		{Address: "3C:84:6A:12:34:56", SSID: "SmithHome", Signal: "-48 dBm", Channel: "6",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2"}, PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"PSK"}}, Power: -48,
+			Beacons: 42, Clients: []string{"AA:BB:CC:00:00:01", "B8:27:EB:00:00:03"}},
+		{Address: "F4:F2:6D:44:55:66", SSID: "Cafe, Bar", Signal: "-71 dBm", Channel: "36",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2", "WPA3"}, PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"SAE", "PSK"}}, Power: -71,
+			Beacons: 17},
+		{Address: "00:14:6C:AB:CD:EF", SSID: "Legacy", Vendor: "NETGEAR", Signal: "-80 dBm", Channel: "11",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WEP"}}, Power: -80, Beacons: 5},
+		{Address: "9A:DE:D0:11:22:33", LocallyAdministered: true, Channel: "1", Security: &models.WiFiSecurity{}, Beacons: 1},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("ScanWiFiNetworks() = %+v\nwant %+v", devices, want)
+	}
+}
+
+func TestScanWiFiNetworksWithNl80211(t *testing.T) {
+	seen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	bsses := []nl80211.BSS{
+		{
+			Interface: "wlan0", BSSID: "3C:84:6A:12:34:56", Frequency: 2437, SignalMBm: -4800, Capability: 0x0411,
+			LastSeen: seen, Associated: true,
+			IEs: []byte{
+				nl80211.ElementSSID, 9, 'S', 'm', 'i', 't', 'h', 'H', 'o', 'm', 'e',
+				nl80211.ElementRSN, 24, 1, 0, 0x00, 0x0f, 0xac, 4, 1, 0, 0x00, 0x0f, 0xac, 4,
+				2, 0, 0x00, 0x0f, 0xac, 2, 0x00, 0x0f, 0xac, 8, 0x8c, 0x00,
+			},
+		},
+		{
+			Interface: "wlan0", BSSID: "00:14:6C:AB:CD:EF", Frequency: 2412, SignalMBm: -7500, Capability: 0x0011,
+			LastSeen: seen,
+			IEs: []byte{
+				nl80211.ElementSSID, 6, 'L', 'e', 'g', 'a', 'c', 'y',
+				nl80211.ElementVendor, 22, 0x00, 0x50, 0xf2, 1, 1, 0, 0x00, 0x50, 0xf2, 2, 1, 0, 0x00, 0x50, 0xf2, 2,
+				1, 0, 0x00, 0x50, 0xf2, 2,
+			},
+		},
+		{
+			Interface: "wlan0", BSSID: "F4:F2:6D:44:55:66", Frequency: 5180, SignalMBm: -8000, Capability: 0x0011,
+			LastSeen: seen, IEs: []byte{nl80211.ElementSSID, 0},
+		},
+		{
+			Interface: "wlan0", BSSID: "9A:DE:D0:11:22:33", Frequency: 2462, Capability: 0x0001,
+			IEs: []byte{nl80211.ElementSSID, 5, 'G', 'u', 'e', 's', 't'},
+		},
+	}
+
+	ws := NewWiFiScanner()
+	ws.SetRunner(runner.NewReplayRunner())
+	ws.scanBSS = func() ([]nl80211.BSS, error) { return bsses, nil }
+
+	devices, err := ws.ScanWiFiNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.WiFiDevice{
+		{Address: "3C:84:6A:12:34:56", SSID: "SmithHome", Signal: "-48 dBm", Channel: "6", Frequency: 2437, Status: "Associated",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2", "WPA3"}, GroupCipher: "CCMP",
+				PairwiseCiphers: []string{"CCMP"}, AuthSuites: []string{"PSK", "SAE"}}, Power: -48, LastSeen: &seen},
+		{Address: "00:14:6C:AB:CD:EF", SSID: "Legacy", Vendor: "NETGEAR", Signal: "-75 dBm", Channel: "1", Frequency: 2412, Status: "Unknown",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA"}, GroupCipher: "TKIP",
+				PairwiseCiphers: []string{"TKIP"}, AuthSuites: []string{"PSK"}}, Power: -75, LastSeen: &seen},
+		{Address: "F4:F2:6D:44:55:66", Signal: "-80 dBm", Channel: "36", Frequency: 5180, Status: "Unknown",
+			Security: &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WEP"}}, Power: -80, LastSeen: &seen},
+		{Address: "9A:DE:D0:11:22:33", SSID: "Guest", LocallyAdministered: true, Channel: "11", Frequency: 2462, Status: "Unknown",
+			Security: &models.WiFiSecurity{}},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("ScanWiFiNetworks() = %+v\nwant %+v", devices, want)
+	}
+}
+
+func TestScanWiFiNetworksNl80211Fallback(t *testing.T) {
+	r := runner.NewReplayRunner()
+	r.SetAvailable("iwlist")
+	if err := r.AddFile("iwlist scan", "testdata/iwlist_scan.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := NewWiFiScanner()
+	ws.SetRunner(r)
+	ws.scanBSS = func() ([]nl80211.BSS, error) { return nil, nl80211.ErrUnsupported }
+	ws.checkNl80211 = func() error { return nl80211.ErrUnsupported }
+
+	devices, err := ws.ScanWiFiNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 5 || devices[0].SSID != "SmithHome" {
+		t.Errorf("ScanWiFiNetworks() = %+v, want the iwlist results", devices)
+	}
+	if health := ws.Health(); !health.Available || health.Message != "using iwlist" {
+		t.Errorf("Health() = %+v", health)
+	}
+
+	ws.scanBSS = func() ([]nl80211.BSS, error) { return nil, errors.New("no wireless station interfaces") }
+	ws.SetRunner(runner.NewReplayRunner())
+	if _, err := ws.ScanWiFiNetworks(); err == nil {
+		t.Error("ScanWiFiNetworks() succeeded without any backend")
+	}
+}
+
+func TestDetectDeauthenticationAttacks(t *testing.T) {
+	ws := NewWiFiScanner()
+	ws.SetRunner(runner.NewReplayRunner())
+	ws.SetMonitorInterface("wlan0mon")
+	ws.frames = func(iface string) ([]dot11.Frame, error) {
+		if iface != "wlan0mon" {
+			t.Errorf("captured on %q", iface)
+		}
+		return dot11.ReadPcapFile("testdata/deauth_flood.pcap")
+	}
+
+	attacks := ws.DetectDeauthenticationAttacks()
+	if len(attacks) != 2 {
+		t.Fatalf("attacks = %+v, want a client and an access point flood", attacks)
+	}
+	if attacks[0].Target != "AA:BB:CC:00:00:01" || attacks[1].Target != "3C:84:6A:12:34:56" {
+		t.Errorf("targets = %s, %s", attacks[0].Target, attacks[1].Target)
+	}
+}
@@ -0,0 +1,330 @@
+package scanners
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/nl80211"
+)
+
+// protocolOrder is the order protocols are listed in, oldest first
+var protocolOrder = []string{"WEP", "WPA", "WPA2", "WPA3"}
+
+// iwlistSecurity collects the security lines of one iwlist cell
+type iwlistSecurity struct {
+	security *models.WiFiSecurity
+	block    string // protocol of the IE block being read: "WPA" or "WPA2"
+}
+
+// parseLine reads an "Encryption key", "IE:" or cipher/suite line
+func (s *iwlistSecurity) parseLine(line string) {
+	switch {
+	case strings.HasPrefix(line, "Encryption key:"):
+		s.get().Encrypted = strings.TrimPrefix(line, "Encryption key:") == "on"
+	case strings.HasPrefix(line, "IE:"):
+		ie := strings.TrimSpace(strings.TrimPrefix(line, "IE:"))
+		switch {
+		case strings.HasPrefix(ie, "IEEE 802.11i/WPA2"):
+			s.block = "WPA2"
+		case strings.HasPrefix(ie, "WPA Version"):
+			s.block = "WPA"
+		default:
+			s.block = "" // vendor and unknown elements
+			return
+		}
+		security := s.get()
+		security.Encrypted = true
+		security.Protocols = appendUnique(security.Protocols, s.block)
+	case s.block == "":
+		return
+	case strings.HasPrefix(line, "Group Cipher"):
+		// The RSN element's group cipher wins over the older WPA element's
+		if cipher := iwlistValue(line); s.get().GroupCipher == "" || s.block == "WPA2" {
+			s.get().GroupCipher = cipherName(cipher)
+		}
+	case strings.HasPrefix(line, "Pairwise Ciphers"):
+		for _, cipher := range strings.Fields(iwlistValue(line)) {
+			s.get().PairwiseCiphers = appendUnique(s.get().PairwiseCiphers, cipherName(cipher))
+		}
+	case strings.HasPrefix(line, "Authentication Suites"):
+		// wireless-tools predates WPA3 and prints its suites as
+		// "unknown (8)", so the value is split on the suite numbers
+		value := strings.ReplaceAll(iwlistValue(line), "unknown (", "unknown(")
+		for _, suite := range strings.Fields(value) {
+			s.get().AuthSuites = appendUnique(s.get().AuthSuites, authSuiteName(suite))
+		}
+	}
+}
+
+// result returns the cell's security, or nil if iwlist reported none
+func (s *iwlistSecurity) result() *models.WiFiSecurity {
+	if s.security != nil {
+		finishSecurity(s.security)
+	}
+	return s.security
+}
+
+func (s *iwlistSecurity) get() *models.WiFiSecurity {
+	if s.security == nil {
+		s.security = &models.WiFiSecurity{}
+	}
+	return s.security
+}
+
+// iwlistValue returns the part of "Pairwise Ciphers (2) : CCMP TKIP" after the colon
+func iwlistValue(line string) string {
+	if i := strings.Index(line, ":"); i >= 0 {
+		return strings.TrimSpace(line[i+1:])
+	}
+	return ""
+}
+
+// securityFromAirodump converts airodump-ng's privacy, cipher and
+// authentication columns, e.g. "WPA3 WPA2", "CCMP TKIP" and "SAE PSK"
+func securityFromAirodump(privacy, cipher, auth string) *models.WiFiSecurity {
+	if privacy == "" {
+		return nil
+	}
+	security := &models.WiFiSecurity{}
+	for _, protocol := range strings.Fields(privacy) {
+		switch protocol {
+		case "OPN":
+		case "OWE":
+			security.Protocols = appendUnique(security.Protocols, "WPA3")
+			security.AuthSuites = appendUnique(security.AuthSuites, "OWE")
+		default:
+			security.Protocols = appendUnique(security.Protocols, protocol)
+		}
+	}
+	security.Encrypted = len(security.Protocols) > 0
+
+	for _, c := range strings.Fields(cipher) {
+		if !strings.HasPrefix(c, "WEP") {
+			security.PairwiseCiphers = appendUnique(security.PairwiseCiphers, cipherName(c))
+		}
+	}
+	for _, suite := range strings.Fields(auth) {
+		if suite != "OPN" && suite != "SKA" { // WEP open and shared key authentication
+			security.AuthSuites = appendUnique(security.AuthSuites, authSuiteName(suite))
+		}
+	}
+
+	finishSecurity(security)
+	return security
+}
+
+// securityFromNmcli converts nmcli's SECURITY column, e.g. "WPA1 WPA2",
+// "WPA2 WPA3", "WPA2 802.1X", "WEP", or "" and "--" for open networks.
+// nmcli does not report ciphers.
+func securityFromNmcli(value string) *models.WiFiSecurity {
+	security := &models.WiFiSecurity{}
+	for _, flag := range strings.Fields(value) {
+		switch flag {
+		case "--":
+		case "WPA1":
+			security.Protocols = appendUnique(security.Protocols, "WPA")
+		case "WPA2", "WPA3", "WEP":
+			security.Protocols = appendUnique(security.Protocols, flag)
+		case "OWE":
+			security.Protocols = appendUnique(security.Protocols, "WPA3")
+			security.AuthSuites = appendUnique(security.AuthSuites, "OWE")
+		case "802.1X":
+			security.AuthSuites = appendUnique(security.AuthSuites, "802.1X")
+		}
+	}
+	security.Encrypted = len(security.Protocols) > 0
+
+	finishSecurity(security)
+	return security
+}
+
+// Organizationally unique identifiers of the WPA and RSN suite selectors
+var (
+	wpaOUI = [3]byte{0x00, 0x50, 0xf2}
+	rsnOUI = [3]byte{0x00, 0x0f, 0xac}
+)
+
+// securityFromIEs reads the privacy bit and the WPA and RSN information
+// elements of an access point's beacon or probe response
+func securityFromIEs(bss nl80211.BSS) *models.WiFiSecurity {
+	security := &models.WiFiSecurity{Encrypted: bss.Privacy()}
+	// The WPA element's body starts with its OUI and type, which the RSN
+	// element lacks. RSN is read last so its group cipher wins.
+	if wpa := bss.VendorElement(wpaOUI, 1); wpa != nil {
+		parseSuites(security, "WPA", wpaOUI, wpa)
+	}
+	if rsn := bss.Element(nl80211.ElementRSN); rsn != nil {
+		parseSuites(security, "WPA2", rsnOUI, rsn)
+	}
+
+	finishSecurity(security)
+	return security
+}
+
+// parseSuites reads the version, group cipher, pairwise ciphers and AKM
+// suites of a WPA or RSN element. A truncated element keeps what was read.
+func parseSuites(security *models.WiFiSecurity, protocol string, oui [3]byte, body []byte) {
+	security.Encrypted = true
+	security.Protocols = appendUnique(security.Protocols, protocol)
+	if len(body) < 6 {
+		return
+	}
+	if cipher := suiteCipher(oui, body[2:6]); cipher != "" {
+		security.GroupCipher = cipher
+	}
+	body = body[6:]
+
+	suites := func() [][]byte {
+		if len(body) < 2 {
+			return nil
+		}
+		count := int(binary.LittleEndian.Uint16(body))
+		body = body[2:]
+		var list [][]byte
+		for i := 0; i < count && len(body) >= 4; i++ {
+			list = append(list, body[:4])
+			body = body[4:]
+		}
+		return list
+	}
+	for _, suite := range suites() {
+		if cipher := suiteCipher(oui, suite); cipher != "" {
+			security.PairwiseCiphers = appendUnique(security.PairwiseCiphers, cipher)
+		}
+	}
+	for _, suite := range suites() {
+		if akm := suiteAKM(oui, suite); akm != "" {
+			security.AuthSuites = appendUnique(security.AuthSuites, akm)
+		}
+	}
+}
+
+// suiteCipher names a cipher suite selector, or returns "" for vendor suites
+func suiteCipher(oui [3]byte, suite []byte) string {
+	if [3]byte(suite[:3]) != oui {
+		return ""
+	}
+	switch suite[3] {
+	case 1:
+		return "WEP-40"
+	case 2:
+		return "TKIP"
+	case 4:
+		return "CCMP"
+	case 5:
+		return "WEP-104"
+	case 8:
+		return "GCMP"
+	case 9:
+		return "GCMP-256"
+	case 10:
+		return "CCMP-256"
+	default:
+		return ""
+	}
+}
+
+// suiteAKM names an authentication and key management suite selector in
+// the terms authSuiteName uses
+func suiteAKM(oui [3]byte, suite []byte) string {
+	if [3]byte(suite[:3]) != oui {
+		return ""
+	}
+	switch suite[3] {
+	case 1, 3, 5, 11:
+		return "802.1X"
+	case 2, 4, 6:
+		return "PSK"
+	case 8, 9, 24, 25:
+		return "SAE"
+	case 12, 13:
+		return "802.1X-SHA256-192"
+	case 18:
+		return "OWE"
+	default:
+		return ""
+	}
+}
+
+// finishSecurity derives what the tools leave implicit: encryption without
+// a WPA element is WEP, and an RSN element with SAE or OWE is WPA3, which
+// also offers WPA2 only if a WPA2 suite remains
+func finishSecurity(security *models.WiFiSecurity) {
+	if security.Encrypted && len(security.Protocols) == 0 {
+		security.Protocols = []string{"WEP"}
+	}
+
+	if security.HasProtocol("WPA2") {
+		wpa3, wpa2 := false, false
+		for _, suite := range security.AuthSuites {
+			switch suite {
+			case "SAE", "OWE", "802.1X-SHA256-192":
+				wpa3 = true
+			default:
+				wpa2 = true
+			}
+		}
+		if wpa3 {
+			security.Protocols = appendUnique(security.Protocols, "WPA3")
+			if !wpa2 {
+				security.Protocols = removeString(security.Protocols, "WPA2")
+			}
+		}
+	}
+
+	var ordered []string
+	for _, protocol := range protocolOrder {
+		if security.HasProtocol(protocol) {
+			ordered = append(ordered, protocol)
+		}
+	}
+	security.Protocols = ordered
+}
+
+// cipherName writes a cipher the way the scanners report it
+func cipherName(cipher string) string {
+	switch c := strings.ToUpper(cipher); c {
+	case "GCMP256":
+		return "GCMP-256"
+	case "CCMP256":
+		return "CCMP-256"
+	default:
+		return c
+	}
+}
+
+// authSuiteName maps the suite names of iwlist and airodump-ng to one set.
+// iwlist prints suites it does not know by number: 8 is SAE, 18 is OWE and
+// 12 is the WPA3-Enterprise 192-bit suite.
+func authSuiteName(suite string) string {
+	switch strings.ToUpper(suite) {
+	case "802.1X", "MGT":
+		return "802.1X"
+	case "UNKNOWN(8)", "SAE":
+		return "SAE"
+	case "UNKNOWN(18)", "OWE":
+		return "OWE"
+	case "UNKNOWN(12)":
+		return "802.1X-SHA256-192"
+	default:
+		return strings.ToUpper(suite)
+	}
+}
+
+func appendUnique(list []string, value string) []string {
+	if containsString(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+func removeString(list []string, value string) []string {
+	var kept []string
+	for _, item := range list {
+		if item != value {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
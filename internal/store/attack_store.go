@@ -0,0 +1,315 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// minCompactEntries is the number of stale lines tolerated before the file is rewritten
+const minCompactEntries = 100
+
+// Retention limits how many attacks the store keeps
+type Retention struct {
+	MaxAge     time.Duration // attacks older than this are dropped (0 keeps all)
+	MaxEntries int           // only the newest MaxEntries attacks are kept (0 keeps all)
+}
+
+// Query selects attacks from the store. Zero-valued fields match everything.
+type Query struct {
+	Since       time.Time
+	Until       time.Time
+	Types       []string
+	MinSeverity models.Severity
+	Target      string
+	Limit       int // return only the most recent Limit matches
+}
+
+// AttackStore is an append-only, file-backed attack log. Attacks are stored as
+// one JSON object per line and kept in memory for querying. Other processes
+// appending to the same file are picked up on the next read.
+type AttackStore struct {
+	path        string
+	retention   Retention
+	attacks     []models.Attack
+	file        os.FileInfo // identity of the file loaded so far
+	offset      int64       // bytes of the file already loaded
+	fileEntries int         // lines currently in the file, including pruned ones
+	mu          sync.RWMutex
+}
+
+// OpenAttackStore opens (or creates) the attack store at path
+func OpenAttackStore(path string, retention Retention) (*AttackStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &AttackStore{
+		path:      path,
+		retention: retention,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.prune(time.Now())
+
+	if err := s.compactIfNeeded(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Add appends an attack to the store
+func (s *AttackStore) Add(attack models.Attack) error {
+	data, err := json.Marshal(attack)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	// Read back the new line along with anything other processes appended
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.prune(time.Now())
+
+	return s.compactIfNeeded()
+}
+
+// Query returns the attacks matching q in chronological order
+func (s *AttackStore) Query(q Query) []models.Attack {
+	s.refresh()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []models.Attack
+	for _, attack := range s.attacks {
+		if q.matches(attack) {
+			matches = append(matches, attack)
+		}
+	}
+
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[len(matches)-q.Limit:]
+	}
+	return matches
+}
+
+// Recent returns the most recent attacks, oldest first
+func (s *AttackStore) Recent(limit int) []models.Attack {
+	return s.Query(Query{Limit: limit})
+}
+
+// Count returns the number of attacks currently retained
+func (s *AttackStore) Count() int {
+	s.refresh()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.attacks)
+}
+
+// Close does nothing: Add opens and closes the file for every attack
+func (s *AttackStore) Close() error {
+	return nil
+}
+
+// matches reports whether attack satisfies the query
+func (q Query) matches(attack models.Attack) bool {
+	if !q.Since.IsZero() && attack.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && attack.Timestamp.After(q.Until) {
+		return false
+	}
+	if attack.Severity < q.MinSeverity {
+		return false
+	}
+	if q.Target != "" && !strings.EqualFold(attack.Target, q.Target) {
+		return false
+	}
+	if len(q.Types) > 0 {
+		for _, attackType := range q.Types {
+			if strings.EqualFold(attack.Type, attackType) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// refresh loads attacks appended by other processes
+func (s *AttackStore) refresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err == nil {
+		s.prune(time.Now())
+	}
+}
+
+// load reads any part of the file not loaded yet. If the file was replaced or
+// shrank it was compacted by another process and is reloaded from the start.
+func (s *AttackStore) load() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.attacks = nil
+			s.file = nil
+			s.offset = 0
+			s.fileEntries = 0
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if s.file == nil || !os.SameFile(s.file, info) || info.Size() < s.offset {
+		s.attacks = nil
+		s.offset = 0
+		s.fileEntries = 0
+	}
+	s.file = info
+	if info.Size() == s.offset {
+		return nil
+	}
+
+	if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Leave a partially written trailing line for the next read
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		s.offset += int64(len(line))
+		s.fileEntries++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var attack models.Attack
+		if err := json.Unmarshal(line, &attack); err != nil {
+			// Skip corrupt entries rather than losing the whole history
+			continue
+		}
+		s.attacks = append(s.attacks, attack)
+	}
+
+	return nil
+}
+
+// prune drops attacks outside the retention limits
+func (s *AttackStore) prune(now time.Time) {
+	start := 0
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+		for start < len(s.attacks) && s.attacks[start].Timestamp.Before(cutoff) {
+			start++
+		}
+	}
+	if s.retention.MaxEntries > 0 && len(s.attacks)-start > s.retention.MaxEntries {
+		start = len(s.attacks) - s.retention.MaxEntries
+	}
+	if start > 0 {
+		s.attacks = append([]models.Attack(nil), s.attacks[start:]...)
+	}
+}
+
+// compactIfNeeded rewrites the file once pruned lines dominate it
+func (s *AttackStore) compactIfNeeded() error {
+	stale := s.fileEntries - len(s.attacks)
+	if stale < minCompactEntries || stale < len(s.attacks) {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact rewrites the file with only the retained attacks
+func (s *AttackStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	var size int64
+	for _, attack := range s.attacks {
+		data, err := json.Marshal(attack)
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+		n, _ := writer.Write(append(data, '\n'))
+		size += int64(n)
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace attack store: %v", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.file = info
+	s.offset = size
+	s.fileEntries = len(s.attacks)
+	return nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestAttackStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attacks.jsonl")
+
+	s, err := OpenAttackStore(path, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, attackType := range []string{"UNKNOWN_DEVICE", "ROGUE_AP", "UNKNOWN_DEVICE"} {
+		attack := models.Attack{
+			Type:      attackType,
+			Severity:  models.SeverityHigh,
+			Target:    "192.168.1.50",
+			Timestamp: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := s.Add(attack); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	reopened, err := OpenAttackStore(path, Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Count(); got != 3 {
+		t.Fatalf("Count() after reopen = %d, want 3", got)
+	}
+	if got := reopened.Recent(1); len(got) != 1 || !got[0].Timestamp.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("Recent(1) = %+v, want the newest attack", got)
+	}
+}
+
+func TestAttackStoreQuery(t *testing.T) {
+	s, err := OpenAttackStore(filepath.Join(t.TempDir(), "attacks.jsonl"), Retention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	attacks := []models.Attack{
+		{Type: "UNKNOWN_DEVICE", Severity: models.SeverityHigh, Target: "192.168.1.50", Timestamp: base},
+		{Type: "SUSPICIOUS_PORT", Severity: models.SeverityMedium, Target: "192.168.1.50", Timestamp: base.Add(time.Hour)},
+		{Type: "OPEN_NETWORK", Severity: models.SeverityLow, Target: "CoffeeShop", Timestamp: base.Add(2 * time.Hour)},
+		{Type: "UNKNOWN_DEVICE", Severity: models.SeverityHigh, Target: "192.168.1.77", Timestamp: base.Add(3 * time.Hour)},
+	}
+	for _, attack := range attacks {
+		if err := s.Add(attack); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  int
+	}{
+		{"everything", Query{}, 4},
+		{"limit", Query{Limit: 2}, 2},
+		{"time range", Query{Since: base.Add(30 * time.Minute), Until: base.Add(150 * time.Minute)}, 2},
+		{"type", Query{Types: []string{"unknown_device"}}, 2},
+		{"multiple types", Query{Types: []string{"OPEN_NETWORK", "SUSPICIOUS_PORT"}}, 2},
+		{"minimum severity", Query{MinSeverity: models.SeverityMedium}, 3},
+		{"target", Query{Target: "192.168.1.50"}, 2},
+		{"combined", Query{Types: []string{"UNKNOWN_DEVICE"}, Target: "192.168.1.77"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Query(tt.query); len(got) != tt.want {
+				t.Errorf("Query(%+v) returned %d attacks, want %d", tt.query, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAttackStoreRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attacks.jsonl")
+	s, err := OpenAttackStore(path, Retention{MaxAge: 24 * time.Hour, MaxEntries: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(models.Attack{Type: "OLD", Timestamp: time.Now().Add(-48 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Count(); got != 0 {
+		t.Fatalf("expired attack retained, Count() = %d", got)
+	}
+
+	for i := 0; i < 400; i++ {
+		if err := s.Add(models.Attack{Type: "NEW", Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.Count(); got != 150 {
+		t.Fatalf("Count() = %d, want 150", got)
+	}
+
+	// Compaction must leave the file consistent with the in-memory view
+	reopened, err := OpenAttackStore(path, Retention{MaxAge: 24 * time.Hour, MaxEntries: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Count(); got != 150 {
+		t.Errorf("Count() after reopen = %d, want 150", got)
+	}
+	if s.fileEntries >= 400 {
+		t.Errorf("file was never compacted: %d entries on disk", s.fileEntries)
+	}
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// BlockStore persists the blocker's block list as a single JSON document.
+// Every update re-reads the file first so that blocks added or removed by
+// other processes (for example the block/unblock CLI commands) are kept.
+type BlockStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenBlockStore opens (or creates) the block store at path
+func OpenBlockStore(path string) (*BlockStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &BlockStore{path: path}
+
+	// Fail early on a corrupt file rather than on the first block
+	if _, err := s.Load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close is a no-op, since Update writes the file before returning
+func (s *BlockStore) Close() error {
+	return nil
+}
+
+// Load returns the stored block list
+func (s *BlockStore) Load() (models.BlockedItems, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Update applies fn to the stored block list and writes the result back
+func (s *BlockStore) Update(fn func(items *models.BlockedItems)) (models.BlockedItems, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.read()
+	if err != nil {
+		return items, err
+	}
+
+	fn(&items)
+
+	return items, s.write(items)
+}
+
+func (s *BlockStore) read() (models.BlockedItems, error) {
+	var items models.BlockedItems
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return newBlockedItems(), err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return newBlockedItems(), fmt.Errorf("failed to parse block store %s: %v", s.path, err)
+		}
+	}
+
+	if items.BlockedIPs == nil {
+		items.BlockedIPs = make(map[string]models.BlockRecord)
+	}
+	if items.BlockedMACs == nil {
+		items.BlockedMACs = make(map[string]models.BlockRecord)
+	}
+	if items.BlockedBTAddrs == nil {
+		items.BlockedBTAddrs = make(map[string]models.BlockRecord)
+	}
+	return items, nil
+}
+
+// write replaces the file atomically so a crash never leaves a partial list
+func (s *BlockStore) write(items models.BlockedItems) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace block store: %v", err)
+	}
+	return nil
+}
+
+func newBlockedItems() models.BlockedItems {
+	return models.BlockedItems{
+		BlockedIPs:     make(map[string]models.BlockRecord),
+		BlockedMACs:    make(map[string]models.BlockRecord),
+		BlockedBTAddrs: make(map[string]models.BlockRecord),
+	}
+}
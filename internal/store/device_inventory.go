@@ -0,0 +1,341 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// maxDeviceChanges bounds the change history kept for each device
+const maxDeviceChanges = 200
+
+// DeviceQuery selects inventory devices. Zero-valued fields match everything.
+type DeviceQuery struct {
+	Kind   string
+	Status string
+	// Match selects devices by key, IP or MAC address, or by a part of the
+	// hostname, vendor, Bluetooth name or SSID, ignoring case
+	Match string
+}
+
+// inventoryDocument is the file format of the device inventory
+type inventoryDocument struct {
+	Devices map[string]*models.InventoryDevice `json:"devices"`
+}
+
+// DeviceInventory persists every device the scanners have found, with its
+// identities, open ports and changes, as a single JSON document. Like the
+// block store it re-reads the file before every update.
+type DeviceInventory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenDeviceInventory opens (or creates) the device inventory at path
+func OpenDeviceInventory(path string) (*DeviceInventory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &DeviceInventory{path: path}
+
+	// Fail early on a corrupt file rather than after the first scan
+	if _, err := s.read(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close is a no-op; Observe rewrites the inventory on every scan
+func (s *DeviceInventory) Close() error {
+	return nil
+}
+
+// Query returns the devices matching q ordered by kind and first appearance
+func (s *DeviceInventory) Query(q DeviceQuery) ([]models.InventoryDevice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.InventoryDevice
+	for _, device := range doc.Devices {
+		if q.matches(device) {
+			matches = append(matches, *device)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Kind != matches[j].Kind {
+			return matches[i].Kind < matches[j].Kind
+		}
+		if !matches[i].FirstSeen.Equal(matches[j].FirstSeen) {
+			return matches[i].FirstSeen.Before(matches[j].FirstSeen)
+		}
+		return matches[i].Key < matches[j].Key
+	})
+	return matches, nil
+}
+
+// Observe records the devices of one kind found by a complete scan at now.
+// Sightings carry identities and open ports but no key or times. Devices of
+// that kind that were online and are missing from the scan go offline.
+func (s *DeviceInventory) Observe(kind string, seen []models.InventoryDevice, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	seenKeys := make(map[string]bool)
+	for _, sighting := range seen {
+		sighting.MAC = strings.ToUpper(sighting.MAC)
+		key := resolveDeviceKey(doc.Devices, kind, sighting)
+		if key == "" {
+			continue
+		}
+		seenKeys[key] = true
+
+		device, ok := doc.Devices[key]
+		if !ok {
+			device = &models.InventoryDevice{
+				Key:       key,
+				Kind:      kind,
+				IP:        sighting.IP,
+				MAC:       sighting.MAC,
+				Hostname:  sighting.Hostname,
+				Vendor:    sighting.Vendor,
+				BTName:    sighting.BTName,
+				SSID:      sighting.SSID,
+				Status:    models.DeviceOnline,
+				FirstSeen: now,
+			}
+			recordChange(device, models.DeviceChange{Time: now, Field: "status", To: models.DeviceOnline})
+			doc.Devices[key] = device
+		} else {
+			updateIdentity(device, sighting, now)
+			if device.Status != models.DeviceOnline {
+				recordChange(device, models.DeviceChange{Time: now, Field: "status", From: device.Status, To: models.DeviceOnline})
+				device.Status = models.DeviceOnline
+			}
+		}
+		device.LastSeen = now
+		device.LocallyAdministered = sighting.LocallyAdministered
+
+		// Only network scans probe ports, and they report every open one
+		if kind == models.DeviceKindNetwork {
+			updatePorts(device, sighting.Ports, now, ok)
+		}
+	}
+
+	for key, device := range doc.Devices {
+		if device.Kind == kind && device.Status == models.DeviceOnline && !seenKeys[key] {
+			recordChange(device, models.DeviceChange{Time: now, Field: "status", From: models.DeviceOnline, To: models.DeviceOffline})
+			device.Status = models.DeviceOffline
+		}
+	}
+
+	return s.write(doc)
+}
+
+// resolveDeviceKey finds the inventory entry a sighting belongs to. Devices
+// are keyed by MAC address where one is known and by IP address otherwise.
+func resolveDeviceKey(devices map[string]*models.InventoryDevice, kind string, sighting models.InventoryDevice) string {
+	if sighting.MAC == "" {
+		if sighting.IP == "" {
+			return ""
+		}
+		// A device seen without its MAC (for example by an unprivileged
+		// nmap run) is the one last seen with that IP address
+		var match *models.InventoryDevice
+		for _, device := range devices {
+			if device.Kind == kind && device.IP == sighting.IP && (match == nil || device.LastSeen.After(match.LastSeen)) {
+				match = device
+			}
+		}
+		if match != nil {
+			return match.Key
+		}
+		return kind + "/" + sighting.IP
+	}
+
+	key := kind + "/" + sighting.MAC
+	if _, ok := devices[key]; ok || sighting.IP == "" {
+		return key
+	}
+
+	// Move an entry recorded before its MAC address was known
+	ipKey := kind + "/" + sighting.IP
+	if device, ok := devices[ipKey]; ok && device.MAC == "" {
+		delete(devices, ipKey)
+		device.Key = key
+		devices[key] = device
+	}
+	return key
+}
+
+// updateIdentity records the identities that changed since the last
+// sighting. An empty value means the scan did not learn it and is ignored.
+func updateIdentity(device *models.InventoryDevice, sighting models.InventoryDevice, now time.Time) {
+	fields := []struct {
+		name    string
+		current *string
+		value   string
+	}{
+		{"ip", &device.IP, sighting.IP},
+		{"mac", &device.MAC, sighting.MAC},
+		{"hostname", &device.Hostname, sighting.Hostname},
+		{"vendor", &device.Vendor, sighting.Vendor},
+		{"bt_name", &device.BTName, sighting.BTName},
+		{"ssid", &device.SSID, sighting.SSID},
+	}
+	for _, field := range fields {
+		if field.value == "" || field.value == *field.current {
+			continue
+		}
+		recordChange(device, models.DeviceChange{Time: now, Field: field.name, From: *field.current, To: field.value})
+		*field.current = field.value
+	}
+}
+
+// updatePorts merges the open ports of a scan into the port history. Ports
+// already open when the device was first seen are not reported as changes.
+func updatePorts(device *models.InventoryDevice, open []models.InventoryPort, now time.Time, known bool) {
+	isOpen := make(map[string]bool)
+	for _, port := range open {
+		isOpen[portName(port)] = true
+
+		var existing *models.InventoryPort
+		for i := range device.Ports {
+			if device.Ports[i].Number == port.Number && device.Ports[i].Protocol == port.Protocol {
+				existing = &device.Ports[i]
+				break
+			}
+		}
+
+		switch {
+		case existing == nil:
+			if known {
+				recordChange(device, models.DeviceChange{Time: now, Field: portName(port), To: "open"})
+			}
+			device.Ports = append(device.Ports, models.InventoryPort{
+				Number:    port.Number,
+				Protocol:  port.Protocol,
+				FirstSeen: now,
+			})
+			existing = &device.Ports[len(device.Ports)-1]
+		case !existing.Open:
+			recordChange(device, models.DeviceChange{Time: now, Field: portName(port), From: "closed", To: "open"})
+		}
+
+		existing.Open = true
+		existing.LastSeen = now
+		if port.Service != "" {
+			existing.Service = port.Service
+		}
+		if port.Version != "" {
+			existing.Version = port.Version
+		}
+	}
+
+	for i := range device.Ports {
+		if device.Ports[i].Open && !isOpen[portName(device.Ports[i])] {
+			recordChange(device, models.DeviceChange{Time: now, Field: portName(device.Ports[i]), From: "open", To: "closed"})
+			device.Ports[i].Open = false
+		}
+	}
+
+	sort.Slice(device.Ports, func(i, j int) bool {
+		if device.Ports[i].Protocol != device.Ports[j].Protocol {
+			return device.Ports[i].Protocol < device.Ports[j].Protocol
+		}
+		return device.Ports[i].Number < device.Ports[j].Number
+	})
+}
+
+// portName names a port in the change history, e.g. "port 22/tcp"
+func portName(port models.InventoryPort) string {
+	return fmt.Sprintf("port %d/%s", port.Number, port.Protocol)
+}
+
+// recordChange appends a change, dropping the oldest beyond maxDeviceChanges
+func recordChange(device *models.InventoryDevice, change models.DeviceChange) {
+	device.Changes = append(device.Changes, change)
+	if len(device.Changes) > maxDeviceChanges {
+		device.Changes = device.Changes[len(device.Changes)-maxDeviceChanges:]
+	}
+}
+
+func (q DeviceQuery) matches(device *models.InventoryDevice) bool {
+	if q.Kind != "" && !strings.EqualFold(q.Kind, device.Kind) {
+		return false
+	}
+	if q.Status != "" && !strings.EqualFold(q.Status, device.Status) {
+		return false
+	}
+	if q.Match == "" {
+		return true
+	}
+
+	for _, address := range []string{device.Key, device.IP, device.MAC} {
+		if address != "" && strings.EqualFold(address, q.Match) {
+			return true
+		}
+	}
+	match := strings.ToLower(q.Match)
+	for _, name := range []string{device.Hostname, device.Vendor, device.BTName, device.SSID} {
+		if name != "" && strings.Contains(strings.ToLower(name), match) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DeviceInventory) read() (inventoryDocument, error) {
+	doc := inventoryDocument{}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return doc, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return doc, fmt.Errorf("failed to parse device inventory %s: %v", s.path, err)
+		}
+	}
+
+	if doc.Devices == nil {
+		doc.Devices = make(map[string]*models.InventoryDevice)
+	}
+	return doc, nil
+}
+
+// write replaces the file atomically so a crash never leaves a partial inventory
+func (s *DeviceInventory) write(doc inventoryDocument) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace device inventory: %v", err)
+	}
+	return nil
+}
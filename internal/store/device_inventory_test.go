@@ -0,0 +1,115 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestDeviceInventoryRecordsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device_inventory.json")
+	s, err := OpenDeviceInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ssh := models.InventoryPort{Number: 22, Protocol: "tcp", Service: "ssh"}
+	web := models.InventoryPort{Number: 80, Protocol: "tcp", Service: "http"}
+	scans := [][]models.InventoryDevice{
+		// Seen before its MAC address is known
+		{{IP: "192.168.1.20", Ports: []models.InventoryPort{ssh}}},
+		{{IP: "192.168.1.20", MAC: "aa:bb:cc:dd:ee:20", Hostname: "nas.lan", Ports: []models.InventoryPort{ssh, web}}},
+		{},
+		{{IP: "192.168.1.21", MAC: "AA:BB:CC:DD:EE:20", Ports: []models.InventoryPort{web}}},
+	}
+	for i, seen := range scans {
+		if err := s.Observe(models.DeviceKindNetwork, seen, base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other kinds do not take network devices offline
+	if err := s.Observe(models.DeviceKindBluetooth, []models.InventoryDevice{{MAC: "11:22:33:44:55:66", BTName: "Headset"}}, base); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenDeviceInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := reopened.Query(DeviceQuery{Match: "nas"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Query() = %+v, want the NAS", devices)
+	}
+
+	device := devices[0]
+	if device.Key != "network/AA:BB:CC:DD:EE:20" || device.IP != "192.168.1.21" || device.Status != models.DeviceOnline ||
+		!device.FirstSeen.Equal(base) || !device.LastSeen.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("device = %+v", device)
+	}
+
+	var changes []string
+	for _, change := range device.Changes {
+		changes = append(changes, change.Field+": "+change.From+" -> "+change.To)
+	}
+	want := []string{
+		"status:  -> online",
+		"mac:  -> AA:BB:CC:DD:EE:20",
+		"hostname:  -> nas.lan",
+		"port 80/tcp:  -> open",
+		"status: online -> offline",
+		"ip: 192.168.1.20 -> 192.168.1.21",
+		"status: offline -> online",
+		"port 22/tcp: open -> closed",
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %q, want %q", changes, want)
+	}
+
+	if len(device.Ports) != 2 || device.Ports[0].Number != 22 || device.Ports[0].Open ||
+		!device.Ports[0].LastSeen.Equal(base.Add(time.Minute)) || !device.Ports[1].Open {
+		t.Errorf("ports = %+v", device.Ports)
+	}
+}
+
+func TestDeviceInventoryQuery(t *testing.T) {
+	s, err := OpenDeviceInventory(filepath.Join(t.TempDir(), "device_inventory.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	s.Observe(models.DeviceKindWiFi, []models.InventoryDevice{{MAC: "00:11:22:33:44:55", SSID: "HomeNet"}}, now)
+	s.Observe(models.DeviceKindNetwork, []models.InventoryDevice{{IP: "192.168.1.1"}, {IP: "192.168.1.10"}}, now)
+	s.Observe(models.DeviceKindNetwork, []models.InventoryDevice{{IP: "192.168.1.1"}}, now.Add(time.Minute))
+
+	tests := []struct {
+		query DeviceQuery
+		want  []string
+	}{
+		{DeviceQuery{}, []string{"network/192.168.1.1", "network/192.168.1.10", "wifi/00:11:22:33:44:55"}},
+		{DeviceQuery{Kind: "wifi"}, []string{"wifi/00:11:22:33:44:55"}},
+		{DeviceQuery{Status: models.DeviceOffline}, []string{"network/192.168.1.10"}},
+		{DeviceQuery{Match: "192.168.1.1"}, []string{"network/192.168.1.1"}},
+		{DeviceQuery{Match: "homenet"}, []string{"wifi/00:11:22:33:44:55"}},
+	}
+	for _, tt := range tests {
+		devices, err := s.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, device := range devices {
+			keys = append(keys, device.Key)
+		}
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.query, keys, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// SimulationLog is an append-only, file-backed log of the actions the blocker
+// would have taken in dry-run mode, stored as one JSON object per line
+type SimulationLog struct {
+	path       string
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// OpenSimulationLog opens (or creates) the simulation log at path, keeping at
+// most maxEntries actions (0 keeps all)
+func OpenSimulationLog(path string, maxEntries int) (*SimulationLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &SimulationLog{
+		path:       path,
+		maxEntries: maxEntries,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	actions, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	if maxEntries > 0 && len(actions) > maxEntries {
+		if err := l.rewrite(actions[len(actions)-maxEntries:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// Close does nothing, as the log is only open while an action is added
+func (l *SimulationLog) Close() error {
+	return nil
+}
+
+// Add appends an action to the log
+func (l *SimulationLog) Add(action models.SimulatedAction) error {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Recent returns the most recent actions, oldest first (limit 0 returns all)
+func (l *SimulationLog) Recent(limit int) ([]models.SimulatedAction, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	actions, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(actions) > limit {
+		actions = actions[len(actions)-limit:]
+	}
+	return actions, nil
+}
+
+func (l *SimulationLog) read() ([]models.SimulatedAction, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var actions []models.SimulatedAction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action models.SimulatedAction
+		if err := json.Unmarshal(line, &action); err != nil {
+			// Skip corrupt or partially written entries
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions, scanner.Err()
+}
+
+func (l *SimulationLog) rewrite(actions []models.SimulatedAction) error {
+	var buf bytes.Buffer
+	for _, action := range actions {
+		data, err := json.Marshal(action)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, l.path)
+}
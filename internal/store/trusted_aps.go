@@ -0,0 +1,228 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+// trustedAPsDocument is the file format of the trusted-AP registry
+type trustedAPsDocument struct {
+	Networks map[string]*models.TrustedNetwork `json:"networks"` // by SSID
+}
+
+// TrustedAPStore persists the access points the operator trusts, grouped by
+// SSID, as a single JSON document. Like the block store it re-reads the file
+// for every call, so a running monitor picks up the trust-ap CLI command.
+type TrustedAPStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenTrustedAPStore opens (or creates) the trusted-AP registry at path
+func OpenTrustedAPStore(path string) (*TrustedAPStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &TrustedAPStore{path: path}
+
+	// Fail early on a corrupt file rather than on the first WiFi scan
+	if _, err := s.read(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close is a no-op because the store keeps no file open between calls
+func (s *TrustedAPStore) Close() error {
+	return nil
+}
+
+// Networks returns the trusted networks ordered by SSID
+func (s *TrustedAPStore) Networks() ([]models.TrustedNetwork, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]models.TrustedNetwork, 0, len(doc.Networks))
+	for _, network := range doc.Networks {
+		networks = append(networks, *network)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].SSID < networks[j].SSID
+	})
+	return networks, nil
+}
+
+// Trust approves access points as they were scanned: their BSSID, channel
+// and signal, and, with includeOUI, every BSSID of the same vendor prefix.
+// A network's accepted security is the weakest of its trusted access
+// points. Access points with a hidden SSID are skipped. It returns the
+// networks that changed.
+func (s *TrustedAPStore) Trust(aps []models.WiFiDevice, includeOUI bool, now time.Time) ([]models.TrustedNetwork, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	for _, ap := range aps {
+		if ap.SSID == "" || ap.Address == "" {
+			continue
+		}
+		bssid := strings.ToUpper(ap.Address)
+
+		network, ok := doc.Networks[ap.SSID]
+		if !ok {
+			network = &models.TrustedNetwork{SSID: ap.SSID}
+			doc.Networks[ap.SSID] = network
+		}
+		network.TrustedAt = now
+		network.BSSIDs = appendMissing(network.BSSIDs, bssid)
+		if includeOUI && len(bssid) >= 8 {
+			network.OUIs = appendMissing(network.OUIs, bssid[:8])
+		}
+		if ap.Channel != "" {
+			network.Channels = appendMissing(network.Channels, ap.Channel)
+		}
+		if dbm, ok := ap.SignalDBm(); ok {
+			if network.Signals == nil {
+				network.Signals = make(map[string]int)
+			}
+			network.Signals[bssid] = dbm
+		}
+		if ap.Security != nil && (network.Security == nil || ap.Security.Weaker(*network.Security)) {
+			security := *ap.Security
+			network.Security = &security
+		}
+		changed[ap.SSID] = true
+	}
+
+	var networks []models.TrustedNetwork
+	for ssid := range changed {
+		networks = append(networks, *doc.Networks[ssid])
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].SSID < networks[j].SSID
+	})
+	return networks, s.write(doc)
+}
+
+// Untrust removes the network with the SSID match or, if match is a BSSID,
+// that access point from its network. A network left without approved
+// BSSIDs or vendor prefixes is removed. It returns how many networks changed.
+func (s *TrustedAPStore) Untrust(match string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, ok := doc.Networks[match]; ok {
+		delete(doc.Networks, match)
+		return 1, s.write(doc)
+	}
+
+	bssid := strings.ToUpper(match)
+	changed := 0
+	for ssid, network := range doc.Networks {
+		kept := network.BSSIDs[:0]
+		for _, approved := range network.BSSIDs {
+			if approved != bssid {
+				kept = append(kept, approved)
+			}
+		}
+		if len(kept) == len(network.BSSIDs) {
+			continue
+		}
+		network.BSSIDs = kept
+		delete(network.Signals, bssid)
+		if len(network.BSSIDs) == 0 && len(network.OUIs) == 0 {
+			delete(doc.Networks, ssid)
+		}
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, s.write(doc)
+}
+
+// appendMissing appends value unless list already holds it
+func appendMissing(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+func (s *TrustedAPStore) read() (trustedAPsDocument, error) {
+	doc := trustedAPsDocument{}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return doc, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return doc, fmt.Errorf("failed to parse trusted access points %s: %v", s.path, err)
+		}
+	}
+
+	if doc.Networks == nil {
+		doc.Networks = make(map[string]*models.TrustedNetwork)
+	}
+	for ssid, network := range doc.Networks {
+		// Entries written by hand may use lower case or omit the SSID
+		network.SSID = ssid
+		for i := range network.BSSIDs {
+			network.BSSIDs[i] = strings.ToUpper(network.BSSIDs[i])
+		}
+		for i := range network.OUIs {
+			network.OUIs[i] = strings.ToUpper(network.OUIs[i])
+		}
+		for bssid, dbm := range network.Signals {
+			delete(network.Signals, bssid)
+			network.Signals[strings.ToUpper(bssid)] = dbm
+		}
+	}
+	return doc, nil
+}
+
+// write replaces the file atomically so a crash never leaves a partial registry
+func (s *TrustedAPStore) write(doc trustedAPsDocument) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace trusted access points: %v", err)
+	}
+	return nil
+}
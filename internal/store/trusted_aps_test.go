@@ -0,0 +1,68 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+)
+
+func TestTrustedAPStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trusted_aps.json")
+	s, err := OpenTrustedAPStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	wpa3 := &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA3"}, AuthSuites: []string{"SAE"}}
+	transition := &models.WiFiSecurity{Encrypted: true, Protocols: []string{"WPA2", "WPA3"}, AuthSuites: []string{"PSK", "SAE"}}
+	aps := []models.WiFiDevice{
+		{Address: "3c:84:6a:12:34:56", SSID: "SmithHome", Signal: "-48 dBm", Channel: "6", Security: wpa3},
+		{Address: "3C:84:6A:12:34:57", SSID: "SmithHome", Power: -60, Channel: "11", Security: transition},
+		{Address: "9A:DE:D0:11:22:33", Channel: "1"}, // hidden SSIDs cannot be trusted
+	}
+	if _, err := s.Trust(aps, true, now); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenTrustedAPStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	networks, err := reopened.Networks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.TrustedNetwork{{
+		SSID:      "SmithHome",
+		BSSIDs:    []string{"3C:84:6A:12:34:56", "3C:84:6A:12:34:57"},
+		OUIs:      []string{"3C:84:6A"},
+		Channels:  []string{"6", "11"},
+		Security:  transition, // the weaker of the two
+		Signals:   map[string]int{"3C:84:6A:12:34:56": -48, "3C:84:6A:12:34:57": -60},
+		TrustedAt: now,
+	}}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("Networks() = %+v\nwant %+v", networks, want)
+	}
+
+	// Removing a BSSID keeps the network; removing the SSID drops it
+	if changed, err := s.Untrust("3c:84:6a:12:34:57"); err != nil || changed != 1 {
+		t.Fatalf("Untrust(BSSID) = %d, %v", changed, err)
+	}
+	if networks, _ := s.Networks(); len(networks) != 1 || len(networks[0].BSSIDs) != 1 || len(networks[0].Signals) != 1 {
+		t.Errorf("after untrusting a BSSID: %+v", networks)
+	}
+	if changed, err := s.Untrust("SmithHome"); err != nil || changed != 1 {
+		t.Fatalf("Untrust(SSID) = %d, %v", changed, err)
+	}
+	if changed, err := s.Untrust("SmithHome"); err != nil || changed != 0 {
+		t.Errorf("Untrust(unknown) = %d, %v", changed, err)
+	}
+	if networks, _ := s.Networks(); len(networks) != 0 {
+		t.Errorf("Networks() = %+v, want none", networks)
+	}
+}
@@ -2,36 +2,71 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gorilla/mux"
-	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/detector"
 	"github.com/boboTheFoff/shheissee-go/internal/logging"
+	"github.com/boboTheFoff/shheissee-go/internal/models"
+	"github.com/boboTheFoff/shheissee-go/internal/store"
+	"github.com/gorilla/mux"
 )
 
+// maxRequestBody bounds the JSON body of an API request
+const maxRequestBody = 1 << 20
+
+// Controller is the part of the attack detector driven by the web API.
+// detector.AttackDetector implements it.
+type Controller interface {
+	BlockIP(ip string, reason string, opts detector.BlockOptions) error
+	UnblockIP(ip string) error
+	BlockMAC(mac string, reason string, opts detector.BlockOptions) error
+	UnblockMAC(mac string) error
+	BlockBluetoothDevice(btAddr string, reason string, opts detector.BlockOptions) error
+	UnblockBluetoothDevice(btAddr string) error
+	DeauthWiFiClient(clientMAC string, apMAC string, reason string) error
+	SetAutoBlock(enabled bool)
+	SetDryRun(enabled bool)
+	IsDryRun() bool
+	GetSimulatedActions(limit int) ([]models.SimulatedAction, error)
+	GetBlockedItems() models.BlockedItems
+	GetAttackCount() int
+	QueryAttacks(query store.Query) []models.Attack
+	QueryDevices(query store.DeviceQuery) ([]models.InventoryDevice, error)
+	TrustedNetworks() ([]models.TrustedNetwork, error)
+	TrustAccessPoints(match string, includeOUI bool) ([]models.TrustedNetwork, error)
+	UntrustAccessPoint(match string) error
+}
+
 // WebServer handles web interface for attack monitoring
 type WebServer struct {
-	port            int
-	router          *mux.Router
-	detector        interface{} // Will be AttackDetector, avoiding circular import
-	logger          *logging.Logger
-	templateDir     string
-	attackLog       []models.Attack
+	host        string
+	port        int
+	router      *mux.Router
+	detector    Controller
+	logger      *logging.Logger
+	templateDir string
 }
 
 // TemplateData holds data for HTML templates
 type TemplateData struct {
-	Title           string
-	Timestamp       string
-	TotalHigh        int
-	TotalMedium      int
-	TotalLow         int
-	TotalAttacks     int
-	RecentAttacks    []models.Attack
+	Title         string
+	Timestamp     string
+	TotalHigh     int
+	TotalMedium   int
+	TotalLow      int
+	TotalAttacks  int
+	RecentAttacks []models.Attack
+	Devices       []models.InventoryDevice
 }
 
 // NewWebServer creates a new web server instance
@@ -41,7 +76,6 @@ func NewWebServer(port int, templateDir string, logger *logging.Logger) *WebServ
 		router:      mux.NewRouter(),
 		logger:      logger,
 		templateDir: templateDir,
-		attackLog:   []models.Attack{},
 	}
 
 	ws.setupRoutes()
@@ -49,19 +83,27 @@ func NewWebServer(port int, templateDir string, logger *logging.Logger) *WebServ
 }
 
 // SetDetector sets the attack detector instance
-func (ws *WebServer) SetDetector(detector interface{}) {
-	ws.detector = detector
+func (ws *WebServer) SetDetector(controller Controller) {
+	ws.detector = controller
+}
+
+// SetHost sets the address the server listens on. It defaults to every
+// interface, which LAN clients such as the Android app need; "127.0.0.1"
+// keeps the unauthenticated API to the local host.
+func (ws *WebServer) SetHost(host string) {
+	ws.host = host
 }
 
 // Start starts the web server
 func (ws *WebServer) Start() error {
-	addr := fmt.Sprintf(":%d", ws.port)
+	addr := net.JoinHostPort(ws.host, strconv.Itoa(ws.port))
 	ws.logger.LogInfo(fmt.Sprintf("Starting web server on %s", addr))
 	return http.ListenAndServe(addr, ws.router)
 }
 
 // setupRoutes configures all HTTP routes
 func (ws *WebServer) setupRoutes() {
+	ws.router.Use(requireJSONBody)
 	ws.router.HandleFunc("/", ws.handleHome)
 	ws.router.HandleFunc("/intrusion-detection", ws.handleIntrusionLog)
 	ws.router.HandleFunc("/warnings", ws.handleWarnings)
@@ -79,6 +121,12 @@ func (ws *WebServer) setupRoutes() {
 	ws.router.HandleFunc("/api/unblock/bt", ws.handleAPIUnblockBT).Methods("POST")
 	ws.router.HandleFunc("/api/deauth/wifi", ws.handleAPIDeauthWiFi).Methods("POST")
 	ws.router.HandleFunc("/api/autoblock", ws.handleAPISetAutoBlock).Methods("POST")
+	ws.router.HandleFunc("/api/dryrun", ws.handleAPISetDryRun).Methods("POST")
+	ws.router.HandleFunc("/api/simulated", ws.handleAPISimulated).Methods("GET")
+	ws.router.HandleFunc("/api/devices", ws.handleAPIDevices).Methods("GET")
+	ws.router.HandleFunc("/api/wifi/trusted", ws.handleAPITrustedAPs).Methods("GET")
+	ws.router.HandleFunc("/api/wifi/trust", ws.handleAPITrustAP).Methods("POST")
+	ws.router.HandleFunc("/api/wifi/untrust", ws.handleAPIUntrustAP).Methods("POST")
 
 	// Serve static files
 	ws.router.PathPrefix("/static/").Handler(
@@ -107,69 +155,123 @@ func (ws *WebServer) handleWarnings(w http.ResponseWriter, r *http.Request) {
 // APIAttack represents attack data for JSON API
 type APIAttack struct {
 	Type        string `json:"type"`
+	Severity    string `json:"severity"`
 	Description string `json:"description"`
+	Target      string `json:"target"`
 	Timestamp   string `json:"timestamp"`
 }
 
-// handleAPIAttacks provides JSON API for attack data
+// handleAPIAttacks provides JSON API for attack data. Supported filters are
+// limit, since and until (RFC 3339), type (repeatable or comma separated),
+// severity (minimum: low, medium or high) and target.
 func (ws *WebServer) handleAPIAttacks(w http.ResponseWriter, r *http.Request) {
-	limit := 50 // Default limit
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsed, err := strconv.Atoi(limitStr); err == nil {
-			limit = parsed
-		}
+	query, err := parseAttackQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Get recent attacks (last 'limit' entries)
-	start := len(ws.attackLog) - limit
-	if start < 0 {
-		start = 0
+	var attacks []models.Attack
+	if ws.detector != nil {
+		attacks = ws.detector.QueryAttacks(query)
 	}
-	recentAttacks := ws.attackLog[start:]
 
 	// Convert attacks to API format
-	apiAttacks := make([]APIAttack, len(recentAttacks))
-	for i, attack := range recentAttacks {
+	apiAttacks := make([]APIAttack, len(attacks))
+	for i, attack := range attacks {
 		apiAttacks[i] = APIAttack{
 			Type:        attack.Type,
+			Severity:    attack.Severity.String(),
 			Description: attack.Description,
+			Target:      attack.Target,
 			Timestamp:   attack.Timestamp.Format(time.RFC3339),
 		}
 	}
 
-	response := map[string]interface{}{
+	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"attacks": apiAttacks,
 		"count":   len(apiAttacks),
+	})
+}
+
+// parseAttackQuery builds an attack store query from URL parameters
+func parseAttackQuery(r *http.Request) (store.Query, error) {
+	params := r.URL.Query()
+	query := store.Query{Limit: 50} // Default limit
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return query, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		query.Limit = limit
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	for name, dest := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := params.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s %q: expected RFC 3339 timestamp", name, value)
+			}
+			*dest = parsed
+		}
+	}
 
-	json.NewEncoder(w).Encode(response)
+	for _, value := range params["type"] {
+		for _, attackType := range strings.Split(value, ",") {
+			if attackType = strings.TrimSpace(attackType); attackType != "" {
+				query.Types = append(query.Types, attackType)
+			}
+		}
+	}
+
+	if value := params.Get("severity"); value != "" {
+		severity, err := models.ParseSeverity(value)
+		if err != nil {
+			return query, err
+		}
+		query.MinSeverity = severity
+	}
+
+	query.Target = strings.TrimSpace(params.Get("target"))
+	return query, nil
 }
 
 // handleAPIStatus provides system status JSON
 func (ws *WebServer) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
-	_ = map[string]interface{}{
-		"status":      "active",
-		"total_attacks": len(ws.attackLog),
-		"timestamp":   time.Now().Format(time.RFC3339),
+	totalAttacks := 0
+	dryRun := false
+	if ws.detector != nil {
+		totalAttacks = ws.detector.GetAttackCount()
+		dryRun = ws.detector.IsDryRun()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	fmt.Fprintf(w, `{"status": "active", "total_attacks": %d, "timestamp": "%s"}`,
-		len(ws.attackLog), time.Now().Format(time.RFC3339))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "active",
+		"total_attacks": totalAttacks,
+		"dry_run":       dryRun,
+		"timestamp":     time.Now().Format(time.RFC3339),
+	})
 }
 
 // prepareTemplateData prepares common template data
 func (ws *WebServer) prepareTemplateData(title string) TemplateData {
+	var attacks []models.Attack
+	var devices []models.InventoryDevice
+	if ws.detector != nil {
+		attacks = ws.detector.QueryAttacks(store.Query{})
+		var err error
+		if devices, err = ws.detector.QueryDevices(store.DeviceQuery{}); err != nil {
+			ws.logger.LogError("Failed to read device inventory", err)
+		}
+	}
+
 	// Count attacks by severity
 	high := 0
 	medium := 0
 	low := 0
 
-	for _, attack := range ws.attackLog {
+	for _, attack := range attacks {
 		switch attack.Severity {
 		case models.SeverityHigh:
 			high++
@@ -181,20 +283,21 @@ func (ws *WebServer) prepareTemplateData(title string) TemplateData {
 	}
 
 	// Get recent attacks (last 50)
-	start := len(ws.attackLog) - 50
+	start := len(attacks) - 50
 	if start < 0 {
 		start = 0
 	}
-	recentAttacks := ws.attackLog[start:]
+	recentAttacks := attacks[start:]
 
 	return TemplateData{
-		Title:        title,
-		Timestamp:    time.Now().Format("2006-01-02 15:04:05"),
-		TotalHigh:    high,
-		TotalMedium:  medium,
-		TotalLow:     low,
-		TotalAttacks: len(ws.attackLog),
+		Title:         title,
+		Timestamp:     time.Now().Format("2006-01-02 15:04:05"),
+		TotalHigh:     high,
+		TotalMedium:   medium,
+		TotalLow:      low,
+		TotalAttacks:  len(attacks),
 		RecentAttacks: recentAttacks,
+		Devices:       devices,
 	}
 }
 
@@ -214,26 +317,6 @@ func (ws *WebServer) renderTemplate(w http.ResponseWriter, tmpl string, data Tem
 	}
 }
 
-// UpdateAttacks updates the attack log (called by detector)
-func (ws *WebServer) UpdateAttacks(attacks []models.Attack) {
-	ws.attackLog = attacks
-
-	// Keep only recent attacks to prevent memory issues
-	maxAttacks := 1000
-	if len(ws.attackLog) > maxAttacks {
-		ws.attackLog = ws.attackLog[len(ws.attackLog)-maxAttacks:]
-	}
-}
-
-// GetRecentAttacks returns recent attacks for other components
-func (ws *WebServer) GetRecentAttacks(limit int) []models.Attack {
-	start := len(ws.attackLog) - limit
-	if start < 0 {
-		start = 0
-	}
-	return ws.attackLog[start:]
-}
-
 // handleBlocking serves the blocking management page
 func (ws *WebServer) handleBlocking(w http.ResponseWriter, r *http.Request) {
 	data := ws.prepareTemplateData("Active Blocking Management")
@@ -242,128 +325,538 @@ func (ws *WebServer) handleBlocking(w http.ResponseWriter, r *http.Request) {
 
 // handleAPIBlocked returns currently blocked items
 func (ws *WebServer) handleAPIBlocked(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if !ws.requireController(w) {
+		return
+	}
 
-	// This would get blocked items from detector in a complete implementation
-	fmt.Fprintf(w, `{"blocked_ips": {}, "blocked_macs": {}, "blocked_bt_addrs": {}}`)
+	writeJSON(w, http.StatusOK, ws.detector.GetBlockedItems())
 }
 
 // handleAPIBlockIP blocks an IP address
 func (ws *WebServer) handleAPIBlockIP(w http.ResponseWriter, r *http.Request) {
-	ip := r.FormValue("ip")
-	reason := r.FormValue("reason")
-	if reason == "" {
-		reason = "Manual block via web interface"
+	if !ws.requireController(w) {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ip := strings.TrimSpace(r.FormValue("ip"))
+	if net.ParseIP(ip) == nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid IP address %q", ip))
+		return
+	}
+	reason := formValueDefault(r, "reason", "Manual block via web interface")
+	opts, err := parseBlockOptions(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := ws.detector.BlockIP(ip, reason, opts); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to block IP %s", ip), err)
+		return
+	}
 
-	// This would call detector.BlockIP in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "IP %s blocked", "ip": "%s"}`, ip, ip)
-	ip = ip // use the variable
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("IP %s blocked", ip),
+		"ip":      ip,
+		"ttl":     opts.TTL.String(),
+	})
 }
 
 // handleAPIUnblockIP unblocks an IP address
 func (ws *WebServer) handleAPIUnblockIP(w http.ResponseWriter, r *http.Request) {
-	ip := r.FormValue("ip")
+	if !ws.requireController(w) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	ip := strings.TrimSpace(r.FormValue("ip"))
+	if net.ParseIP(ip) == nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid IP address %q", ip))
+		return
+	}
+
+	if err := ws.detector.UnblockIP(ip); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to unblock IP %s", ip), err)
+		return
+	}
 
-	// This would call detector.UnblockIP in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "IP %s unblocked", "ip": "%s"}`, ip, ip)
-	ip = ip // use the variable
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("IP %s unblocked", ip),
+		"ip":      ip,
+	})
 }
 
 // handleAPIBlockMAC blocks a MAC address
 func (ws *WebServer) handleAPIBlockMAC(w http.ResponseWriter, r *http.Request) {
-	mac := r.FormValue("mac")
-	reason := r.FormValue("reason")
-	if reason == "" {
-		reason = "Manual block via web interface"
+	if !ws.requireController(w) {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	mac, err := parseMACParam(r, "mac")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reason := formValueDefault(r, "reason", "Manual block via web interface")
+	opts, err := parseBlockOptions(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// This would call detector.BlockMAC in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "MAC %s blocked", "mac": "%s"}`, mac, mac)
-	mac = mac // use the variable
+	if err := ws.detector.BlockMAC(mac, reason, opts); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to block MAC %s", mac), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("MAC %s blocked", mac),
+		"mac":     mac,
+		"ttl":     opts.TTL.String(),
+	})
 }
 
 // handleAPIUnblockMAC unblocks a MAC address
 func (ws *WebServer) handleAPIUnblockMAC(w http.ResponseWriter, r *http.Request) {
-	mac := r.FormValue("mac")
+	if !ws.requireController(w) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	mac, err := parseMACParam(r, "mac")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// This would call detector.UnblockMAC in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "MAC %s unblocked", "mac": "%s"}`, mac, mac)
-	mac = mac // use the variable
+	if err := ws.detector.UnblockMAC(mac); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to unblock MAC %s", mac), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("MAC %s unblocked", mac),
+		"mac":     mac,
+	})
 }
 
 // handleAPIBlockBT blocks a Bluetooth device
 func (ws *WebServer) handleAPIBlockBT(w http.ResponseWriter, r *http.Request) {
-	btAddr := r.FormValue("bt_addr")
-	reason := r.FormValue("reason")
-	if reason == "" {
-		reason = "Manual block via web interface"
+	if !ws.requireController(w) {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	btAddr, err := parseMACParam(r, "bt_addr")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reason := formValueDefault(r, "reason", "Manual block via web interface")
+	opts, err := parseBlockOptions(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// This would call detector.BlockBluetoothDevice in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "Bluetooth device %s blocked", "bt_addr": "%s"}`, btAddr, btAddr)
-	btAddr = btAddr // use the variable
+	if err := ws.detector.BlockBluetoothDevice(btAddr, reason, opts); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to block Bluetooth device %s", btAddr), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Bluetooth device %s blocked", btAddr),
+		"bt_addr": btAddr,
+		"ttl":     opts.TTL.String(),
+	})
 }
 
 // handleAPIUnblockBT unblocks a Bluetooth device
 func (ws *WebServer) handleAPIUnblockBT(w http.ResponseWriter, r *http.Request) {
-	btAddr := r.FormValue("bt_addr")
+	if !ws.requireController(w) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	btAddr, err := parseMACParam(r, "bt_addr")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := ws.detector.UnblockBluetoothDevice(btAddr); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to unblock Bluetooth device %s", btAddr), err)
+		return
+	}
 
-	// This would call detector.UnblockBluetoothDevice in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "Bluetooth device %s unblocked", "bt_addr": "%s"}`, btAddr, btAddr)
-	btAddr = btAddr // use the variable
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Bluetooth device %s unblocked", btAddr),
+		"bt_addr": btAddr,
+	})
 }
 
 // handleAPIDeauthWiFi deauthenticates a WiFi client
 func (ws *WebServer) handleAPIDeauthWiFi(w http.ResponseWriter, r *http.Request) {
-	clientMAC := r.FormValue("client_mac")
-	apMAC := r.FormValue("ap_mac")
-	reason := r.FormValue("reason")
-	if reason == "" {
-		reason = "Manual deauth via web interface"
+	if !ws.requireController(w) {
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	clientMAC, err := parseMACParam(r, "client_mac")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	apMAC, err := parseMACParam(r, "ap_mac")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reason := formValueDefault(r, "reason", "Manual deauth via web interface")
+
+	if err := ws.detector.DeauthWiFiClient(clientMAC, apMAC, reason); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to deauthenticate WiFi client %s", clientMAC), err)
+		return
+	}
 
-	// This would call detector.DeauthWiFiClient in a complete implementation
-	fmt.Fprintf(w, `{"success": true, "message": "WiFi client %s deauthenticated from AP %s", "client_mac": "%s", "ap_mac": "%s"}`, clientMAC, apMAC, clientMAC, apMAC)
-	clientMAC = clientMAC // use the variable
-	apMAC = apMAC // use the variable
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"message":    fmt.Sprintf("WiFi client %s deauthenticated from AP %s", clientMAC, apMAC),
+		"client_mac": clientMAC,
+		"ap_mac":     apMAC,
+	})
 }
 
 // handleAPISetAutoBlock enables/disables auto-blocking
 func (ws *WebServer) handleAPISetAutoBlock(w http.ResponseWriter, r *http.Request) {
-	enabledStr := r.FormValue("enabled")
-	enabled := enabledStr == "true"
+	if !ws.requireController(w) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid value for enabled: %q", r.FormValue("enabled")))
+		return
+	}
+
+	ws.detector.SetAutoBlock(enabled)
+
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Auto-blocking %s", status),
+		"enabled": enabled,
+	})
+}
+
+// handleAPISetDryRun enables or disables blocker dry-run mode
+func (ws *WebServer) handleAPISetDryRun(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid value for enabled: %q", r.FormValue("enabled")))
+		return
+	}
+
+	ws.detector.SetDryRun(enabled)
 
-	// This would call detector.SetAutoBlock in a complete implementation
 	status := "disabled"
 	if enabled {
 		status = "enabled"
 	}
-	fmt.Fprintf(w, `{"success": true, "message": "Auto-blocking %s", "enabled": %t}`, status, enabled)
-	enabled = enabled // use the variable
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Dry-run %s", status),
+		"enabled": enabled,
+	})
+}
+
+// handleAPISimulated returns the actions simulated in dry-run mode
+func (ws *WebServer) handleAPISimulated(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", limitStr))
+			return
+		}
+		limit = parsed
+	}
+
+	actions, err := ws.detector.GetSimulatedActions(limit)
+	if err != nil {
+		ws.writeControllerError(w, "Failed to read simulated actions", err)
+		return
+	}
+	if actions == nil {
+		actions = []models.SimulatedAction{}
+	}
+
+	writeJSON(w, http.StatusOK, actions)
+}
+
+// handleAPIDevices lists the device inventory, optionally filtered by kind,
+// status and a device address or name (q)
+func (ws *WebServer) handleAPIDevices(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	params := r.URL.Query()
+	devices, err := ws.detector.QueryDevices(store.DeviceQuery{
+		Kind:   params.Get("kind"),
+		Status: params.Get("status"),
+		Match:  params.Get("q"),
+	})
+	if err != nil {
+		ws.writeControllerError(w, "Failed to read device inventory", err)
+		return
+	}
+	if devices == nil {
+		devices = []models.InventoryDevice{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"devices": devices,
+		"count":   len(devices),
+	})
+}
+
+// handleAPITrustedAPs lists the networks trusted against evil twins
+func (ws *WebServer) handleAPITrustedAPs(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	networks, err := ws.detector.TrustedNetworks()
+	if err != nil {
+		ws.writeControllerError(w, "Failed to read trusted access points", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"networks": networks,
+		"count":    len(networks),
+	})
+}
+
+// handleAPITrustAP trusts the access points currently seen with the SSID or
+// BSSID ap, and with oui=true their vendor prefixes
+func (ws *WebServer) handleAPITrustAP(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	ap := r.FormValue("ap")
+	if ap == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing ap (SSID or BSSID)")
+		return
+	}
+	includeOUI := false
+	if value := strings.TrimSpace(r.FormValue("oui")); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid value for oui: %q", value))
+			return
+		}
+		includeOUI = parsed
+	}
+
+	networks, err := ws.detector.TrustAccessPoints(ap, includeOUI)
+	if err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to trust access point %s", ap), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("Access points of %s trusted", ap),
+		"networks": networks,
+	})
+}
+
+// handleAPIUntrustAP removes a trusted network by SSID, or an access point by BSSID
+func (ws *WebServer) handleAPIUntrustAP(w http.ResponseWriter, r *http.Request) {
+	if !ws.requireController(w) {
+		return
+	}
+
+	ap := r.FormValue("ap")
+	if ap == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing ap (SSID or BSSID)")
+		return
+	}
+
+	if err := ws.detector.UntrustAccessPoint(ap); err != nil {
+		ws.writeControllerError(w, fmt.Sprintf("Failed to untrust access point %s", ap), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("%s is no longer trusted", ap),
+	})
+}
+
+// API helpers
+
+// requireController reports whether a detector is attached, answering 503 if not
+func (ws *WebServer) requireController(w http.ResponseWriter) bool {
+	if ws.detector == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "attack detector not available")
+		return false
+	}
+	return true
+}
+
+// writeControllerError logs a failed detector call and maps it to an HTTP status
+func (ws *WebServer) writeControllerError(w http.ResponseWriter, message string, err error) {
+	ws.logger.LogError(message, err)
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, detector.ErrInvalidTarget):
+		status = http.StatusBadRequest
+	case errors.Is(err, detector.ErrProtectedTarget):
+		status = http.StatusForbidden
+	case errors.Is(err, detector.ErrAlreadyBlocked), errors.Is(err, detector.ErrNotBlocked):
+		status = http.StatusConflict
+	case errors.Is(err, detector.ErrUnknownAccessPoint):
+		status = http.StatusNotFound
+	case errors.Is(err, detector.ErrBlockerUnavailable), errors.Is(err, detector.ErrNoBlockingTool):
+		status = http.StatusServiceUnavailable
+	}
+
+	writeAPIError(w, status, err.Error())
+}
+
+// writeJSON writes payload as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// requireJSONBody only lets requests that change state through with a JSON
+// object body, whose members become the request's form values. A browser
+// cannot send such a request cross-site without a CORS preflight, which the
+// server never grants, so other web pages cannot drive the API.
+func requireJSONBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			writeAPIError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+			return
+		}
+		form, err := decodeJSONForm(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		r.PostForm = form
+		r.Form = make(url.Values)
+		for name, values := range r.URL.Query() {
+			r.Form[name] = values
+		}
+		for name, values := range form {
+			r.Form[name] = values
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decodeJSONForm reads a flat JSON object such as {"ip": "192.168.1.100",
+// "override": true} into form values. An empty body is an empty object.
+func decodeJSONForm(body io.Reader) (url.Values, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON body: %v", err)
+	}
+
+	form := make(url.Values)
+	for name, value := range members {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			form.Set(name, v)
+		case bool:
+			form.Set(name, strconv.FormatBool(v))
+		case json.Number:
+			form.Set(name, v.String())
+		default:
+			return nil, fmt.Errorf("invalid JSON body: %s must be a string, number or boolean", name)
+		}
+	}
+	return form, nil
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
+
+// formValueDefault returns the named form value or def when it is empty
+func formValueDefault(r *http.Request, name string, def string) string {
+	if value := strings.TrimSpace(r.FormValue(name)); value != "" {
+		return value
+	}
+	return def
+}
+
+// parseBlockOptions reads the optional block duration, such as "30m" or
+// "24h" (missing means permanent), and the override flag for protected targets
+func parseBlockOptions(r *http.Request) (detector.BlockOptions, error) {
+	var opts detector.BlockOptions
+
+	if value := strings.TrimSpace(r.FormValue("ttl")); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return opts, fmt.Errorf("invalid ttl %q", value)
+		}
+		opts.TTL = ttl
+	}
+
+	if value := strings.TrimSpace(r.FormValue("override")); value != "" {
+		override, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid value for override: %q", value)
+		}
+		opts.Override = override
+	}
+
+	return opts, nil
+}
+
+// parseMACParam reads a MAC or Bluetooth address form value and normalizes it
+func parseMACParam(r *http.Request, name string) (string, error) {
+	value := strings.TrimSpace(r.FormValue(name))
+	hw, err := net.ParseMAC(value)
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("invalid %s %q", name, value)
+	}
+	return strings.ToUpper(hw.String()), nil
 }
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boboTheFoff/shheissee-go/internal/detector"
+	"github.com/boboTheFoff/shheissee-go/internal/logging"
+)
+
+func TestRequireJSONBody(t *testing.T) {
+	var got url.Values
+	handler := requireJSONBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = url.Values{"ip": {r.FormValue("ip")}, "override": {r.FormValue("override")}, "ttl": {r.FormValue("ttl")}}
+		writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "form post", method: "POST", contentType: "application/x-www-form-urlencoded", body: "ip=10.0.0.5", want: http.StatusUnsupportedMediaType},
+		{name: "text post", method: "POST", contentType: "text/plain", body: `{"ip": "10.0.0.5"}`, want: http.StatusUnsupportedMediaType},
+		{name: "nested value", method: "POST", contentType: "application/json", body: `{"ip": ["10.0.0.5"]}`, want: http.StatusBadRequest},
+		{name: "malformed", method: "POST", contentType: "application/json", body: `{"ip": `, want: http.StatusBadRequest},
+		{name: "json post", method: "POST", contentType: "application/json; charset=utf-8", body: `{"ip": "10.0.0.5", "override": true, "ttl": null}`, want: http.StatusOK},
+		{name: "get", method: "GET", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(tt.method, "/api/block/ip", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", origin)
+			}
+			if tt.want != http.StatusOK {
+				if got != nil {
+					t.Error("handler ran for a rejected request")
+				}
+				return
+			}
+			if tt.method == "POST" && (got.Get("ip") != "10.0.0.5" || got.Get("override") != "true" || got.Get("ttl") != "") {
+				t.Errorf("form = %v", got)
+			}
+		})
+	}
+}
+
+// fakeController records the calls of the blocking endpoints and fails them with err
+type fakeController struct {
+	Controller
+	calls []string
+	err   error
+}
+
+func (f *fakeController) record(call string) error {
+	f.calls = append(f.calls, call)
+	return f.err
+}
+
+func (f *fakeController) BlockIP(ip string, reason string, opts detector.BlockOptions) error {
+	return f.record(fmt.Sprintf("block ip %s %s ttl=%s override=%v", ip, reason, opts.TTL, opts.Override))
+}
+
+func (f *fakeController) UnblockIP(ip string) error {
+	return f.record("unblock ip " + ip)
+}
+
+func (f *fakeController) BlockMAC(mac string, reason string, opts detector.BlockOptions) error {
+	return f.record(fmt.Sprintf("block mac %s %s ttl=%s override=%v", mac, reason, opts.TTL, opts.Override))
+}
+
+func (f *fakeController) UnblockMAC(mac string) error {
+	return f.record("unblock mac " + mac)
+}
+
+func (f *fakeController) DeauthWiFiClient(clientMAC string, apMAC string, reason string) error {
+	return f.record(fmt.Sprintf("deauth %s %s %s", clientMAC, apMAC, reason))
+}
+
+func newTestServer(t *testing.T, controller Controller) *WebServer {
+	logger, err := logging.NewLogger(filepath.Join(t.TempDir(), "web.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.Close() })
+
+	ws := NewWebServer(0, t.TempDir(), logger)
+	ws.SetDetector(controller)
+	return ws
+}
+
+func postJSON(ws *WebServer, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	ws.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBlockingEndpoints(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+		call string
+	}{
+		{name: "block ip", path: "/api/block/ip", body: `{"ip": "192.168.1.100", "ttl": "30m", "override": true}`, want: http.StatusOK,
+			call: "block ip 192.168.1.100 Manual block via web interface ttl=30m0s override=true"},
+		{name: "block invalid ip", path: "/api/block/ip", body: `{"ip": "192.168.1"}`, want: http.StatusBadRequest},
+		{name: "block ip bad ttl", path: "/api/block/ip", body: `{"ip": "192.168.1.100", "ttl": "soon"}`, want: http.StatusBadRequest},
+		{name: "unblock ip", path: "/api/unblock/ip", body: `{"ip": "2001:db8::1"}`, want: http.StatusOK, call: "unblock ip 2001:db8::1"},
+		{name: "block mac", path: "/api/block/mac", body: `{"mac": "aa:bb:cc:dd:ee:ff", "reason": "spoofing"}`, want: http.StatusOK,
+			call: "block mac AA:BB:CC:DD:EE:FF spoofing ttl=0s override=false"},
+		{name: "unblock mac", path: "/api/unblock/mac", body: `{"mac": "AA-BB-CC-DD-EE-FF"}`, want: http.StatusOK, call: "unblock mac AA:BB:CC:DD:EE:FF"},
+		{name: "unblock invalid mac", path: "/api/unblock/mac", body: `{"mac": "AA:BB"}`, want: http.StatusBadRequest},
+		{name: "deauth", path: "/api/deauth/wifi", body: `{"client_mac": "aa:bb:cc:dd:ee:ff", "ap_mac": "00:11:22:33:44:55"}`, want: http.StatusOK,
+			call: "deauth AA:BB:CC:DD:EE:FF 00:11:22:33:44:55 Manual deauth via web interface"},
+		{name: "deauth without ap", path: "/api/deauth/wifi", body: `{"client_mac": "aa:bb:cc:dd:ee:ff"}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &fakeController{}
+			rec := postJSON(newTestServer(t, controller), tt.path, tt.body)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			var want []string
+			if tt.call != "" {
+				want = []string{tt.call}
+			}
+			if !reflect.DeepEqual(controller.calls, want) {
+				t.Errorf("calls = %q, want %q", controller.calls, want)
+			}
+		})
+	}
+}
+
+func TestControllerErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: fmt.Errorf("%w: \"x\" is not a valid IP address", detector.ErrInvalidTarget), want: http.StatusBadRequest},
+		{err: fmt.Errorf("IP 192.168.1.1 is %w", detector.ErrAlreadyBlocked), want: http.StatusConflict},
+		{err: fmt.Errorf("IP 192.168.1.1 is %w", detector.ErrNotBlocked), want: http.StatusConflict},
+		{err: fmt.Errorf("%w: default gateway", detector.ErrProtectedTarget), want: http.StatusForbidden},
+		{err: detector.ErrNoBlockingTool, want: http.StatusServiceUnavailable},
+		{err: errors.New("exit status 1"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			ws := newTestServer(t, &fakeController{err: tt.err})
+			rec := postJSON(ws, "/api/block/ip", `{"ip": "192.168.1.1"}`)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body["success"] != false || body["error"] != tt.err.Error() {
+				t.Errorf("body = %v", body)
+			}
+		})
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/boboTheFoff/shheissee-go/internal/airodump"
+	"github.com/boboTheFoff/shheissee-go/internal/netinfo"
 )
 
 // runCommandOrSudo attempts to run a command first as regular user, then with sudo if it fails with permission errors
@@ -65,14 +68,20 @@ type WifiAP struct {
 	PWR     int    `json:"pwr"`
 	Beacons int    `json:"beacons"`
 	ENC     string `json:"enc"`
+	Cipher  string `json:"cipher,omitempty"`
+	Auth    string `json:"auth,omitempty"`
+	Channel int    `json:"channel,omitempty"`
+	Clients int    `json:"clients"`
 	ESSID   string `json:"essid"`
 }
 
 type WifiClient struct {
-	MAC     string `json:"mac"`
-	APMAC   string `json:"apmac"`
-	PWR     int    `json:"pwr"`
-	Lost    int    `json:"lost"`
+	MAC     string   `json:"mac"`
+	APMAC   string   `json:"apmac"`
+	PWR     int      `json:"pwr"`
+	Lost    int      `json:"lost"`
+	Packets int      `json:"packets"`
+	Probes  []string `json:"probes,omitempty"`
 }
 
 type BluetoothDevice struct {
@@ -185,10 +194,78 @@ func MonitorWifi() {
 	ServiceStatuses["wifi"].LogEntries = append(ServiceStatuses["wifi"].LogEntries, fmt.Sprintf("[%s] Using WiFi interface: %s", time.Now().Format("15:04:05"), monitorInterface))
 	StatusMu.Unlock()
 
-	// Skip airodump-ng for now and go directly to fallback WiFi scanning
 	log.Printf("Using WiFi interface for scanning: %s", monitorInterface)
-	fallbackScanWifi()
-	return
+	capture, err := captureAirodump(monitorInterface)
+	if err != nil {
+		log.Printf("airodump-ng capture failed: %v", err)
+		logToFile(fmt.Sprintf("airodump-ng capture failed: %v", err), "ERROR")
+		fallbackScanWifi()
+		return
+	}
+
+	var aps []WifiAP
+	for _, ap := range capture.AccessPoints {
+		aps = append(aps, WifiAP{
+			BSSID:   ap.BSSID,
+			PWR:     ap.Power,
+			Beacons: ap.Beacons,
+			ENC:     ap.Privacy,
+			Cipher:  ap.Cipher,
+			Auth:    ap.Auth,
+			Channel: ap.Channel,
+			Clients: len(capture.Clients(ap.BSSID)),
+			ESSID:   ap.ESSID,
+		})
+	}
+	clients := []WifiClient{}
+	for _, station := range capture.Stations {
+		clients = append(clients, WifiClient{
+			MAC:     station.MAC,
+			APMAC:   station.BSSID,
+			PWR:     station.Power,
+			Packets: station.Packets,
+			Probes:  station.Probes,
+		})
+	}
+
+	WifiMu.Lock()
+	WifiAPs = aps
+	WifiClients = clients
+	WifiMu.Unlock()
+
+	StatusMu.Lock()
+	ServiceStatuses["wifi"].LastUpdate = time.Now().Format("2006-01-02 15:04:05")
+	ServiceStatuses["wifi"].LogEntries = append(ServiceStatuses["wifi"].LogEntries, fmt.Sprintf("[%s] airodump-ng found %d access points and %d clients", time.Now().Format("15:04:05"), len(aps), len(clients)))
+	if len(ServiceStatuses["wifi"].LogEntries) > 10 {
+		ServiceStatuses["wifi"].LogEntries = ServiceStatuses["wifi"].LogEntries[len(ServiceStatuses["wifi"].LogEntries)-10:]
+	}
+	StatusMu.Unlock()
+
+	log.Printf("airodump-ng scan completed: found %d access points and %d clients", len(aps), len(clients))
+}
+
+// captureAirodump runs airodump-ng on a monitor-mode interface for a few
+// seconds and reads the CSV it writes. timeout ends the capture with a
+// non-zero status, so the run only failed if no CSV was written.
+func captureAirodump(iface string) (*airodump.Capture, error) {
+	dir, err := os.MkdirTemp("", "shheissee-airodump-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	prefix := filepath.Join(dir, "scan")
+	cmd := exec.Command("timeout", "10", "airodump-ng", "--output-format", "csv", "--write-interval", "1", "-w", prefix, iface)
+	_, output, runErr := runCommandOrSudo(cmd, nil)
+
+	capture, err := airodump.ParseFile(prefix + "-01.csv")
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("%v: %s", runErr, strings.TrimSpace(string(output)))
+		}
+		return nil, err
+	}
+	return capture, nil
 }
 
 func MonitorNetwork() {
@@ -1015,35 +1092,50 @@ func DetectBluetoothAttacks(devices []BluetoothDevice) []Attack {
 	return attacks
 }
 
-// DetectNetworkAttacks analyzes network for intrusion patterns
+// DetectNetworkAttacks analyzes the host's local IPv4 subnets for intrusion patterns
 func DetectNetworkAttacks() []Attack {
 	var attacks []Attack
 
 	// Check for suspicious open ports using nmap if available
-	if _, err := exec.LookPath("nmap"); err == nil {
-		cmd := exec.Command("nmap", "-p", "21,23,3389,445", "--open", "192.168.1.0/24")
+	if _, err := exec.LookPath("nmap"); err != nil {
+		return attacks
+	}
+
+	subnets, err := netinfo.LocalSubnets()
+	if err != nil {
+		log.Printf("Failed to list local subnets: %v", err)
+		return attacks
+	}
+
+	// Check for dangerous ports
+	dangerousPorts := map[string]string{
+		"21/tcp":    "FTP",
+		"23/tcp":    "Telnet",
+		"3389/tcp":  "RDP",
+		"445/tcp":   "SMB",
+	}
+
+	for _, subnet := range subnets {
+		if !subnet.IsIPv4() {
+			continue
+		}
+
+		cmd := exec.Command("nmap", "-p", "21,23,3389,445", "--open", subnet.Network.String())
 		output, err := cmd.CombinedOutput()
-		if err == nil {
-			outputStr := string(output)
-
-			// Check for dangerous ports
-			dangerousPorts := map[string]string{
-				"21/tcp":    "FTP",
-				"23/tcp":    "Telnet",
-				"3389/tcp":  "RDP",
-				"445/tcp":   "SMB",
-			}
+		if err != nil {
+			continue
+		}
+		outputStr := string(output)
 
-			for port, service := range dangerousPorts {
-				if strings.Contains(outputStr, port) && strings.Contains(outputStr, "open") {
-					attacks = append(attacks, Attack{
-						Type:        "SUSPICIOUS_PORT",
-						Severity:    "Medium",
-						Description: fmt.Sprintf("Suspicious open port detected: %s (%s)", port, service),
-						Target:      "network",
-						Timestamp:   time.Now(),
-					})
-				}
+		for port, service := range dangerousPorts {
+			if strings.Contains(outputStr, port) && strings.Contains(outputStr, "open") {
+				attacks = append(attacks, Attack{
+					Type:        "SUSPICIOUS_PORT",
+					Severity:    "Medium",
+					Description: fmt.Sprintf("Suspicious open port detected: %s (%s) on %s", port, service, subnet.Network),
+					Target:      subnet.Network.String(),
+					Timestamp:   time.Now(),
+				})
 			}
 		}
 	}